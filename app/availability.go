@@ -0,0 +1,116 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+)
+
+// Slot states of the availability grid
+const (
+	SlotFree      = "free"
+	SlotBooked    = "booked"
+	SlotCheckedIn = "checked_in"
+)
+
+// Slot sizes of the availability grid
+const (
+	DefaultSlotSize = 30 * time.Minute
+	minSlotSize     = 5 * time.Minute
+)
+
+var ErrInvalidSlotSize = errors.New("slot_minutes must divide a day and be at least 5")
+
+type (
+	// AvailabilityGrid is the state of every seat for every slot of a day, Slots are
+	// the start times of the slots and every seat has one state per slot
+	AvailabilityGrid struct {
+		Date        string             `json:"date"`
+		SlotMinutes int                `json:"slot_minutes"`
+		Slots       []string           `json:"slots"`
+		Seats       []SeatAvailability `json:"seats"`
+	}
+
+	SeatAvailability struct {
+		SeatID uint     `json:"seat_id"`
+		Number string   `json:"number"`
+		Zone   string   `json:"zone"`
+		Slots  []string `json:"slots"`
+	}
+)
+
+// BuildAvailabilityGrid compute the grid of the day starting at date in the office time
+// zone, with one query for the seats and one for their bookings. A slot is booked when
+// an active booking overlaps it and checked_in when that booking is checked in
+func BuildAvailabilityGrid(s Storage, date time.Time, slotSize time.Duration, filter SeatFilter) (*AvailabilityGrid, error) {
+	if slotSize < minSlotSize || (24*time.Hour)%slotSize != 0 {
+		return nil, ErrInvalidSlotSize
+	}
+
+	y, m, d := date.In(dateutil.LocVN).Date()
+	var (
+		start = time.Date(y, m, d, 0, 0, 0, 0, dateutil.LocVN)
+		end   = start.AddDate(0, 0, 1)
+		slots = int(end.Sub(start) / slotSize)
+	)
+
+	seats, err := s.FindSeats(filter)
+	if err != nil {
+		return nil, err
+	}
+	seatIDs := make([]uint, len(seats))
+	for i, seat := range seats {
+		seatIDs[i] = seat.ID
+	}
+	bookings, err := s.FindActiveBookingsBySeatIDs(seatIDs, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	grid := &AvailabilityGrid{
+		Date:        dateutil.ToFormat(start, dateutil.FormatYYYYMMDDDash),
+		SlotMinutes: int(slotSize / time.Minute),
+		Slots:       make([]string, slots),
+		Seats:       make([]SeatAvailability, len(seats)),
+	}
+	for i := range grid.Slots {
+		grid.Slots[i] = start.Add(time.Duration(i) * slotSize).Format("15:04")
+	}
+	seatIndex := map[uint]int{}
+	for i, seat := range seats {
+		seatIndex[seat.ID] = i
+		grid.Seats[i] = SeatAvailability{
+			SeatID: seat.ID,
+			Number: seat.Number,
+			Zone:   seatZonePath(seat),
+			Slots:  make([]string, slots),
+		}
+		for j := range grid.Seats[i].Slots {
+			grid.Seats[i].Slots[j] = SlotFree
+		}
+	}
+
+	for _, booking := range bookings {
+		state := SlotBooked
+		if booking.CheckedIn || booking.Status == BookingStatusCheckedIn {
+			state = SlotCheckedIn
+		}
+		// the slots overlapping [StartTime, EndTime), cut to the day
+		first := int(booking.StartTime.Sub(start) / slotSize)
+		if booking.StartTime.Before(start) {
+			first = 0
+		}
+		last := int((booking.EndTime.Sub(start) + slotSize - 1) / slotSize)
+		if last > slots {
+			last = slots
+		}
+		row := grid.Seats[seatIndex[booking.SeatID]].Slots
+		for j := first; j < last; j++ {
+			if row[j] != SlotCheckedIn {
+				row[j] = state
+			}
+		}
+	}
+	return grid, nil
+}
@@ -0,0 +1,76 @@
+//go:build embeddedpg
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Run the storage tests against an in-process PostgreSQL too with
+//
+//	go test -tags embeddedpg ./app/
+//
+// the PostgreSQL binaries are downloaded on the first run.
+const (
+	embeddedPostgresPort = 54329
+	embeddedPostgresDSN  = "host=localhost port=54329 user=postgres password=postgres sslmode=disable dbname=%s"
+)
+
+var testDatabaseSeq atomic.Int64
+
+func init() {
+	testBackends = append(testBackends, testBackend{name: DriverPostgres, open: openPostgresTestDatabase})
+}
+
+func TestMain(m *testing.M) {
+	runtime, err := os.MkdirTemp("", "embedded-postgres")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(runtime)
+
+	pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(embeddedPostgresPort).
+		RuntimePath(runtime).
+		StartParameters(map[string]string{"max_connections": "200"}))
+	if err := pg.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	if err := pg.Stop(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	os.Exit(code)
+}
+
+// openPostgresTestDatabase create a database dropped at the end of the test
+func openPostgresTestDatabase(t *testing.T) DatabaseConfig {
+	admin, err := gorm.Open(postgres.Open(fmt.Sprintf(embeddedPostgresDSN, "postgres")), &gorm.Config{})
+	require.NoError(t, err)
+	adminDB, err := admin.DB()
+	require.NoError(t, err)
+
+	name := fmt.Sprintf("test_%d", testDatabaseSeq.Add(1))
+	require.NoError(t, admin.Exec("CREATE DATABASE "+name).Error)
+	t.Cleanup(func() {
+		_ = admin.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)").Error
+		_ = adminDB.Close()
+	})
+
+	return DatabaseConfig{
+		Driver:       DriverPostgres,
+		DSN:          fmt.Sprintf(embeddedPostgresDSN, name),
+		MaxOpenConns: 20,
+	}
+}
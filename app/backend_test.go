@@ -0,0 +1,57 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"code-challenge-backend/migrations"
+	"code-challenge-backend/pkg/migrate"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testBackend open an empty database for a single test
+type testBackend struct {
+	name string
+	open func(t *testing.T) DatabaseConfig
+}
+
+// testBackends are the databases every storage test runs against, more are
+// registered by the files behind build tags
+var testBackends = []testBackend{
+	{name: DriverSQLite, open: openSQLiteTestDatabase},
+}
+
+func openSQLiteTestDatabase(t *testing.T) DatabaseConfig {
+	return DatabaseConfig{
+		Driver: DriverSQLite,
+		DSN:    filepath.Join(t.TempDir(), "test.db") + "?_txlock=immediate",
+	}
+}
+
+// forEachBackend run fn as a sub test against a fresh migrated database of every test backend
+func forEachBackend(t *testing.T, fn func(t *testing.T, ds *DataStorage)) {
+	for _, backend := range testBackends {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			ds := NewDataStorage(backend.open(t))
+			t.Cleanup(func() { _ = ds.Close() })
+
+			migrator, err := migrate.New(ds.mysqlDB, migrations.All())
+			require.NoError(t, err)
+			_, err = migrator.Up()
+			require.NoError(t, err)
+			fn(t, ds)
+		})
+	}
+}
+
+// forEachStorage run fn as a sub test against an empty MemoryStorage and a fresh database of every test backend
+func forEachStorage(t *testing.T, fn func(t *testing.T, s Storage)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, NewMemoryStorage())
+	})
+	forEachBackend(t, func(t *testing.T, ds *DataStorage) {
+		fn(t, ds)
+	})
+}
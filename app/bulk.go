@@ -0,0 +1,188 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+)
+
+// bookingExportHeader are the columns of a booking export
+var bookingExportHeader = []string{
+	"booking_id", "date", "start_time", "end_time", "user_id", "user_email", "user_name",
+	"seat_number", "status", "checked_in", "checked_in_at", "checked_out_at",
+}
+
+type (
+	// RowError is the validation error of a spreadsheet row, rows are numbered like in
+	// the spreadsheet so the header is row 1
+	RowError struct {
+		Row   int    `json:"row"`
+		Error string `json:"error"`
+	}
+
+	// SeatImportResult summarize an import, nothing is written when Errors is not empty
+	SeatImportResult struct {
+		Created int        `json:"created"`
+		Updated int        `json:"updated"`
+		Changes []string   `json:"changes"`
+		Errors  []RowError `json:"errors,omitempty"`
+	}
+)
+
+// ImportSeats upsert the seats of a spreadsheet with the columns of fixtures/seats.csv,
+// seats are matched by number and their zone must exist. Every row is validated before
+// anything is written and the rows are written in one transaction, with dryRun nothing
+// is written at all
+func ImportSeats(s Storage, r io.Reader, format string, dryRun bool) (*SeatImportResult, error) {
+	rows, err := readSpreadsheet(r, format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSpreadsheet, err)
+	}
+
+	result := &SeatImportResult{Changes: []string{}}
+	if len(rows) == 0 {
+		rows = [][]string{{}}
+	}
+	columns := sheetColumns(rows[0])
+	if _, ok := columns["number"]; !ok {
+		result.Errors = append(result.Errors, RowError{Row: 1, Error: "header must have a number column"})
+		return result, nil
+	}
+
+	sd, err := newSeeder(s, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		seats   []SeatFixture
+		numbers = map[string]int{}
+	)
+	for i, record := range rows[1:] {
+		rowNumber := i + 2
+		row := sheetRow{columns: columns, record: record}
+		if row.empty() {
+			continue
+		}
+
+		seat, err := parseSeatRow(row)
+		if err == nil {
+			err = seat.validate()
+		}
+		if err == nil && seat.Zone != "" {
+			if _, ok := sd.zones[seat.zonePath()]; !ok {
+				err = fmt.Errorf("zone %s not found", seat.zonePath())
+			}
+		}
+		if err == nil {
+			if first, ok := numbers[seat.Number]; ok {
+				err = fmt.Errorf("seat %s is already in row %d", seat.Number, first)
+			}
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, RowError{Row: rowNumber, Error: err.Error()})
+			continue
+		}
+		numbers[seat.Number] = rowNumber
+		seats = append(seats, seat)
+	}
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	err = s.WithTransaction(func(s Storage) error {
+		sd.s = s
+		return sd.seedSeats(seats)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range sd.changes {
+		if change.Action == SeedActionCreate {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+		result.Changes = append(result.Changes, change.String())
+	}
+	return result, nil
+}
+
+// ParseBookingExportFilter parse the filter of a booking export, from and to are
+// dates formatted as dateutil.FormatYYYYMMDDDash and to is inclusive, empty values
+// are not filtered
+func ParseBookingExportFilter(from, to, userID, seatNumber string) (BookingExportFilter, error) {
+	filter := BookingExportFilter{SeatNumber: seatNumber}
+	if from != "" {
+		date, err := parseDateParam("from", from)
+		if err != nil {
+			return filter, err
+		}
+		filter.From = &date
+	}
+	if to != "" {
+		date, err := parseDateParam("to", to)
+		if err != nil {
+			return filter, err
+		}
+		end := date.AddDate(0, 0, 1)
+		filter.To = &end
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, fmt.Errorf("from date is after to date")
+	}
+	if userID != "" {
+		id, err := strconv.ParseUint(userID, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid user id %q", userID)
+		}
+		uid := uint(id)
+		filter.UserID = &uid
+	}
+	return filter, nil
+}
+
+// ExportBookings write the bookings matching the filter to w as they are read, times
+// are written in the local time of the office
+func ExportBookings(bookings BookingRepository, w io.Writer, format string, filter BookingExportFilter) error {
+	sheet, err := newSpreadsheetWriter(w, format)
+	if err != nil {
+		return err
+	}
+	if err := sheet.WriteRow(bookingExportHeader); err != nil {
+		return err
+	}
+
+	err = bookings.ExportBookings(filter, func(row BookingExportRow) error {
+		start, end := row.StartTime.In(dateutil.LocVN), row.EndTime.In(dateutil.LocVN)
+		return sheet.WriteRow([]string{
+			strconv.Itoa(row.ID),
+			dateutil.ToFormat(start, dateutil.FormatYYYYMMDDDash),
+			dateutil.ToFormat(start, dateutil.FormatYYYYMMDDHHMMDash),
+			dateutil.ToFormat(end, dateutil.FormatYYYYMMDDHHMMDash),
+			strconv.FormatUint(uint64(row.UserID), 10),
+			escapeSpreadsheetCell(row.UserEmail),
+			escapeSpreadsheetCell(row.UserName),
+			escapeSpreadsheetCell(row.SeatNumber),
+			row.Status,
+			strconv.FormatBool(row.CheckedIn),
+			formatOptionalTime(row.CheckedInAt),
+			formatOptionalTime(row.CheckedOutAt),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return sheet.Close()
+}
+
+// formatOptionalTime format t in the local time of the office, nil is empty
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return dateutil.ToFormat(t.In(dateutil.LocVN), dateutil.FormatYYYYMMDDHHMMDash)
+}
@@ -0,0 +1,446 @@
+package app
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedTestLocations create the HQ/Ground/North zone used by the import tests
+func seedTestLocations(t *testing.T, s Storage) {
+	t.Helper()
+	_, err := Seed(s, Fixtures{Buildings: []BuildingFixture{{
+		Name:   "HQ",
+		Floors: []FloorFixture{{Name: "Ground", Level: 0, Zones: []string{"North"}}},
+	}}}, false)
+	require.NoError(t, err)
+}
+
+func TestImportSeats(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		seedTestLocations(t, s)
+		content := "number,building,floor,zone,type,dual_monitor\nA1,HQ,Ground,North,booth,true\nA2,,,,,\n"
+
+		result, err := ImportSeats(s, strings.NewReader(content), SpreadsheetCSV, true)
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Created)
+		seats, err := s.ListSeats(true)
+		require.NoError(t, err)
+		assert.Empty(t, seats, "dry run must not write")
+
+		result, err = ImportSeats(s, strings.NewReader(content), SpreadsheetCSV, false)
+		require.NoError(t, err)
+		assert.Equal(t, &SeatImportResult{
+			Created: 2,
+			Changes: []string{"+ seat A1 zone=HQ/Ground/North, type=booth", "+ seat A2 zone=-, type=desk"},
+		}, result)
+
+		result, err = ImportSeats(s, strings.NewReader(content), SpreadsheetCSV, false)
+		require.NoError(t, err)
+		assert.Empty(t, result.Changes, "importing again must not change anything")
+
+		result, err = ImportSeats(s, strings.NewReader("number,type\nA2,booth\n"), SpreadsheetCSV, false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Updated)
+		seat, err := s.GetSeatByNumber("A2")
+		require.NoError(t, err)
+		assert.Equal(t, SeatTypeBooth, seat.Type)
+	})
+}
+
+func TestImportSeats_RowErrors(t *testing.T) {
+	type args struct {
+		content string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []RowError
+	}{
+		{
+			name: "missing number column",
+			args: args{content: "seat,type\nA1,desk\n"},
+			want: []RowError{{Row: 1, Error: "header must have a number column"}},
+		},
+		{
+			name: "every invalid row is reported",
+			args: args{content: "number,building,floor,zone,type,dual_monitor\n" +
+				"A1,HQ,Ground,North,sofa,\n" +
+				"A2,HQ,Ground,South,,\n" +
+				"A3,,,,,maybe\n" +
+				",,,,,\n" +
+				"A4,,,,,\n" +
+				"A4,,,,,\n"},
+			want: []RowError{
+				{Row: 2, Error: `seat A1: invalid type "sofa"`},
+				{Row: 3, Error: "zone HQ/Ground/South not found"},
+				{Row: 4, Error: `invalid dual_monitor "maybe"`},
+				{Row: 7, Error: "seat A4 is already in row 6"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStorage()
+			seedTestLocations(t, s)
+
+			result, err := ImportSeats(s, strings.NewReader(tt.args.content), SpreadsheetCSV, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result.Errors)
+			seats, err := s.ListSeats(true)
+			require.NoError(t, err)
+			assert.Empty(t, seats, "invalid rows must not write anything")
+		})
+	}
+}
+
+func TestImportSeats_XLSX(t *testing.T) {
+	var buf bytes.Buffer
+	sheet, err := newSpreadsheetWriter(&buf, SpreadsheetXLSX)
+	require.NoError(t, err)
+	require.NoError(t, sheet.WriteRow([]string{"number", "building", "floor", "zone", "near_window"}))
+	require.NoError(t, sheet.WriteRow([]string{"A1", "HQ", "Ground", "North", "true"}))
+	require.NoError(t, sheet.Close())
+
+	s := NewMemoryStorage()
+	seedTestLocations(t, s)
+	result, err := ImportSeats(s, &buf, SpreadsheetXLSX, false)
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	seat, err := s.GetSeatByNumber("A1")
+	require.NoError(t, err)
+	assert.True(t, seat.NearWindow)
+
+	_, err = ImportSeats(s, strings.NewReader("not a xlsx file"), SpreadsheetXLSX, false)
+	assert.ErrorIs(t, err, ErrInvalidSpreadsheet)
+}
+
+func TestReadSpreadsheet_MaxRows(t *testing.T) {
+	for _, format := range []string{SpreadsheetCSV, SpreadsheetXLSX} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			write := func(rows int, trailingBlank bool) *bytes.Buffer {
+				var buf bytes.Buffer
+				sheet, err := newSpreadsheetWriter(&buf, format)
+				require.NoError(t, err)
+				for i := 0; i < rows; i++ {
+					require.NoError(t, sheet.WriteRow([]string{"A1"}))
+				}
+				if trailingBlank {
+					require.NoError(t, sheet.WriteRow(nil))
+				}
+				require.NoError(t, sheet.Close())
+				return &buf
+			}
+
+			// the blank rows at the end are not counted
+			rows, err := readSpreadsheet(write(maxSpreadsheetRows, true), format)
+			require.NoError(t, err)
+			assert.Len(t, rows, maxSpreadsheetRows)
+
+			_, err = readSpreadsheet(write(maxSpreadsheetRows+1, false), format)
+			assert.ErrorIs(t, err, errTooManyRows)
+		})
+	}
+}
+
+func TestImportSeats_WriteError(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		seedTestLocations(t, s)
+		_, err := ImportSeats(s, strings.NewReader("number,type\nA1,booth\n"), SpreadsheetCSV, false)
+		require.NoError(t, err)
+
+		content := "number,type\nA1,desk\nA2,desk\nA3,desk\n"
+		result, err := ImportSeats(failingSeatStorage{Storage: s, failNumber: "A3"}, strings.NewReader(content), SpreadsheetCSV, false)
+		require.Error(t, err)
+		assert.Nil(t, result)
+
+		seats, err := s.ListSeats(true)
+		require.NoError(t, err)
+		require.Len(t, seats, 1, "a failed import must not write anything")
+		assert.Equal(t, SeatTypeBooth, seats[0].Type)
+	})
+}
+
+func TestParseBookingExportFilter(t *testing.T) {
+	var (
+		from   = time.Date(2026, 10, 1, 0, 0, 0, 0, dateutil.LocVN)
+		to     = time.Date(2026, 11, 1, 0, 0, 0, 0, dateutil.LocVN)
+		userID = uint(7)
+	)
+	type args struct {
+		from, to, userID, seatNumber string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    BookingExportFilter
+		wantErr bool
+	}{
+		{
+			name: "no filter",
+			args: args{},
+			want: BookingExportFilter{},
+		},
+		{
+			name: "to date is inclusive",
+			args: args{from: "2026-10-01", to: "2026-10-31", userID: "7", seatNumber: "A1"},
+			want: BookingExportFilter{From: &from, To: &to, UserID: &userID, SeatNumber: "A1"},
+		},
+		{
+			name:    "invalid date",
+			args:    args{from: "01/10/2026"},
+			wantErr: true,
+		},
+		{
+			name:    "from after to",
+			args:    args{from: "2026-10-02", to: "2026-10-01"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid user id",
+			args:    args{userID: "me"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBookingExportFilter(tt.args.from, tt.args.to, tt.args.userID, tt.args.seatNumber)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExportBookings(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		jane := &User{Name: "Jane", Email: "jane@example.com", Role: RoleEmployee}
+		john := &User{Name: "John", Email: "john@example.com", Role: RoleEmployee}
+		require.NoError(t, s.Create(jane))
+		require.NoError(t, s.Create(john))
+		a1, a2 := &Seat{Number: "A1"}, &Seat{Number: "A2"}
+		require.NoError(t, s.CreateSeat(a1))
+		require.NoError(t, s.CreateSeat(a2))
+
+		day := time.Date(2026, 10, 20, 9, 0, 0, 0, dateutil.LocVN)
+		for _, booking := range []*Booking{
+			{UserID: jane.ID, SeatID: a1.ID, StartTime: day.AddDate(0, 0, 1).UTC(), EndTime: day.AddDate(0, 0, 1).Add(8 * time.Hour).UTC()},
+			{UserID: jane.ID, SeatID: a1.ID, StartTime: day.UTC(), EndTime: day.Add(8 * time.Hour).UTC()},
+			{UserID: john.ID, SeatID: a2.ID, StartTime: day.UTC(), EndTime: day.Add(4 * time.Hour).UTC()},
+		} {
+			require.NoError(t, s.CreateBookingIfAvailable(booking))
+		}
+
+		type args struct {
+			from, to, userID, seatNumber string
+		}
+		tests := []struct {
+			name string
+			args args
+			want []string
+		}{
+			{
+				name: "every booking ordered by start time",
+				args: args{},
+				want: []string{"A1 2026-10-20 09:00 jane@example.com", "A2 2026-10-20 09:00 john@example.com", "A1 2026-10-21 09:00 jane@example.com"},
+			},
+			{
+				name: "date range",
+				args: args{from: "2026-10-21", to: "2026-10-21"},
+				want: []string{"A1 2026-10-21 09:00 jane@example.com"},
+			},
+			{
+				name: "user",
+				args: args{userID: "2"},
+				want: []string{"A2 2026-10-20 09:00 john@example.com"},
+			},
+			{
+				name: "seat",
+				args: args{seatNumber: "A1"},
+				want: []string{"A1 2026-10-20 09:00 jane@example.com", "A1 2026-10-21 09:00 jane@example.com"},
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				filter, err := ParseBookingExportFilter(tt.args.from, tt.args.to, tt.args.userID, tt.args.seatNumber)
+				require.NoError(t, err)
+				var buf bytes.Buffer
+				require.NoError(t, ExportBookings(s, &buf, SpreadsheetCSV, filter))
+
+				rows, err := csv.NewReader(&buf).ReadAll()
+				require.NoError(t, err)
+				require.NotEmpty(t, rows)
+				assert.Equal(t, bookingExportHeader, rows[0])
+				got := []string{}
+				for _, row := range rows[1:] {
+					assert.Equal(t, row[2][:10], row[1], "date column is the start date")
+					got = append(got, strings.Join([]string{row[7], row[2], row[5]}, " "))
+				}
+				assert.Equal(t, tt.want, got)
+			})
+		}
+	})
+}
+
+func TestExportBookings_FormulaInjection(t *testing.T) {
+	s := NewMemoryStorage()
+	user := &User{Name: `=HYPERLINK("http://example.com","Jane")`, Email: "jane@example.com", Role: RoleEmployee}
+	require.NoError(t, s.Create(user))
+	seat := &Seat{Number: "@A1"}
+	require.NoError(t, s.CreateSeat(seat))
+	start := time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC)
+	require.NoError(t, s.CreateBookingIfAvailable(&Booking{UserID: user.ID, SeatID: seat.ID, StartTime: start, EndTime: start.Add(time.Hour)}))
+
+	var buf bytes.Buffer
+	require.NoError(t, ExportBookings(s, &buf, SpreadsheetCSV, BookingExportFilter{}))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "jane@example.com", rows[1][5])
+	assert.Equal(t, `'=HYPERLINK("http://example.com","Jane")`, rows[1][6])
+	assert.Equal(t, "'@A1", rows[1][7])
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "Jane", want: "Jane"},
+		{value: "=1+1", want: "'=1+1"},
+		{value: "+1", want: "'+1"},
+		{value: "-1", want: "'-1"},
+		{value: "@SUM(A1)", want: "'@SUM(A1)"},
+		{value: "\t=1", want: "'\t=1"},
+		{value: "\r=1", want: "'\r=1"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, escapeSpreadsheetCell(tt.value), tt.value)
+	}
+}
+
+// slowExportStorage read the export rows with a delay
+type slowExportStorage struct {
+	Storage
+	delay time.Duration
+}
+
+func (s slowExportStorage) ExportBookings(filter BookingExportFilter, fn func(row BookingExportRow) error) error {
+	return s.Storage.ExportBookings(filter, func(row BookingExportRow) error {
+		time.Sleep(s.delay)
+		return fn(row)
+	})
+}
+
+func TestExportBookings_OutlastWriteTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := NewMemoryStorage()
+	booking := createTestBooking(t, s)
+	for i := 1; i < 3; i++ {
+		next := &Booking{UserID: booking.UserID, SeatID: booking.SeatID, StartTime: booking.StartTime.AddDate(0, 0, i), EndTime: booking.EndTime.AddDate(0, 0, i)}
+		require.NoError(t, s.CreateBookingIfAvailable(next))
+	}
+	r := gin.New()
+	r.GET("/admin/bookings/export", newTestHandler(slowExportStorage{Storage: s, delay: 100 * time.Millisecond}).ExportBookings)
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.WriteTimeout = 200 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/admin/bookings/export")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	rows, err := csv.NewReader(resp.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, rows, 4)
+}
+
+func TestBulkHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := NewMemoryStorage()
+	seedTestLocations(t, s)
+	h := newTestHandler(s)
+	r := gin.New()
+	r.POST("/admin/seats/import", h.ImportSeats)
+	r.GET("/admin/bookings/export", h.ExportBookings)
+
+	upload := func(name, content, query string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, err := mw.CreateFormFile("file", name)
+		require.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/seats/import"+query, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := upload("seats.csv", "number,zone\nA1,North\n", "")
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	var invalid struct {
+		Error  string     `json:"error"`
+		Errors []RowError `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &invalid))
+	assert.Equal(t, "Invalid rows", invalid.Error)
+	assert.Len(t, invalid.Errors, 1)
+
+	w = upload("seats.json", "{}", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = upload("seats.csv", "number\nA1\n", "?dry_run=true")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	_, err := s.GetSeatByNumber("A1")
+	assert.Error(t, err, "dry run must not write")
+
+	w = upload("seats.csv", "number\nA1\n", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result SeatImportResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 1, result.Created)
+
+	// the file is over the limit, then the whole request
+	for _, size := range []int{maxImportSize + 1, 2 * maxImportSize} {
+		w = upload("seats.csv", "number\n"+strings.Repeat("A", size), "")
+		require.Equal(t, http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+		assert.Equal(t, "File too large", decodeError(t, w))
+	}
+
+	w = upload("seats.csv", "number\n"+strings.Repeat("A1\n", maxSpreadsheetRows), "")
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	assert.Contains(t, decodeError(t, w), "more than 10000 rows")
+
+	w = serveJSON(r, http.MethodGet, "/admin/bookings/export?format=xlsx", "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, spreadsheetContentTypes[SpreadsheetXLSX], w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), ".xlsx")
+	rows, err := readSpreadsheet(w.Body, SpreadsheetXLSX)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{bookingExportHeader}, rows)
+
+	w = serveJSON(r, http.MethodGet, "/admin/bookings/export?from=yesterday", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+}
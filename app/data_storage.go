@@ -1,11 +1,39 @@
 package app
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
 	"strings"
 	"time"
 
-	"gorm.io/driver/sqlite"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxTransactionAttempts = 10
+	transactionRetryDelay  = 5 * time.Millisecond
+	// releaseHistoryChunk is how many history rows ReleaseBooking inserts per statement
+	releaseHistoryChunk = 100
+)
+
+// releasePendingCondition select the bookings the release sweep looks at, the values are
+// written as literals to repeat the WHERE clause of the partial idx_bookings_release index
+var releasePendingCondition = fmt.Sprintf("b.checked_in = false AND b.status IN ('%s')",
+	strings.Join(activeBookingStatuses, "', '"))
+
+var (
+	ErrUserAlreadyBooked  = errors.New("user already has a booking")
+	ErrSeatAlreadyBooked  = errors.New("seat already booked on that duration")
+	ErrNoOccurrenceBooked = errors.New("no occurrence booked")
+	// ErrBookingNotActive is returned by the status writes when the booking changed status
+	// since it was read, e.g. it was released or cancelled meanwhile
+	ErrBookingNotActive = errors.New("booking is no longer active")
 )
 
 type (
@@ -14,8 +42,8 @@ type (
 	}
 )
 
-func NewDataStorage(dns string) *DataStorage {
-	db, err := gorm.Open(sqlite.Open(dns), &gorm.Config{})
+func NewDataStorage(config DatabaseConfig) *DataStorage {
+	db, err := OpenDatabase(config)
 	if err != nil {
 		panic(err)
 	}
@@ -24,16 +52,13 @@ func NewDataStorage(dns string) *DataStorage {
 	}
 }
 
-// Upsert user
-func (ds *DataStorage) Upsert(user *User) error {
-	err := ds.mysqlDB.Save(user).Error
+// Close close the underlying database connections
+func (ds *DataStorage) Close() error {
+	sqlDB, err := ds.mysqlDB.DB()
 	if err != nil {
-		if strings.EqualFold("UNIQUE constraint failed: users.email", err.Error()) {
-			return nil
-		}
 		return err
 	}
-	return nil
+	return sqlDB.Close()
 }
 
 func (ds *DataStorage) QueryBooking(bookingId uint) (*Booking, error) {
@@ -46,45 +71,426 @@ func (ds *DataStorage) QueryBooking(bookingId uint) (*Booking, error) {
 }
 
 func (ds *DataStorage) ReseverBooking(booking *Booking) error {
-	err := ds.mysqlDB.Exec("UPDATE bookings SET checked_in = true WHERE id = ?", booking.ID).Error
+	return ds.Transaction(func(ds *DataStorage) error {
+		now := time.Now().UTC()
+		result := ds.mysqlDB.Exec("UPDATE bookings SET checked_in = true, status = ?, checked_in_at = ? WHERE id = ? AND checked_in = ? AND status IN ?",
+			BookingStatusCheckedIn, now, booking.ID, false, activeBookingStatuses)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBookingNotActive
+		}
+		booking.CheckedIn = true
+		booking.Status = BookingStatusCheckedIn
+		booking.CheckedInAt = &now
+		return ds.addBookingHistory(booking)
+	})
+}
+
+// check the booking out, it ends at now and no longer holds its seat
+func (ds *DataStorage) CheckOutBooking(booking *Booking, now time.Time) error {
+	now = now.UTC()
+	return ds.Transaction(func(ds *DataStorage) error {
+		result := ds.mysqlDB.Exec("UPDATE bookings SET status = ?, end_time = ?, checked_out_at = ? WHERE id = ? AND status = ?",
+			BookingStatusCheckedOut, now, now, booking.ID, BookingStatusCheckedIn)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBookingNotActive
+		}
+		booking.Status = BookingStatusCheckedOut
+		booking.EndTime = now
+		booking.CheckedOutAt = &now
+		return ds.addBookingHistory(booking)
+	})
+}
+
+// release the bookings which have not been checked in once the grace period of their
+// seat's policy has passed, it returns the released bookings. The candidates are
+// released batch by batch, each batch in a transaction, and the bookings of the batches
+// committed before a failure are returned with its error
+func (ds *DataStorage) ReleaseBooking(config ReleaseConfig, now time.Time) ([]Booking, error) {
+	var (
+		released []Booking
+		after    *Booking
+	)
+	for {
+		batch, last, err := ds.releaseBatch(config, now, after)
+		released = append(released, batch...)
+		if err != nil || last == nil {
+			return released, err
+		}
+		after = last
+	}
+}
+
+// releaseBatch release the no-shows among the next config.BatchSize candidates after the
+// cursor, with one insert of their history, one update and one delete. It returns the
+// last candidate as the cursor of the next batch, nil when this batch was the last one.
+// Candidates still within a longer grace period are skipped by the cursor so that they
+// do not hold the batch up
+func (ds *DataStorage) releaseBatch(config ReleaseConfig, now time.Time, after *Booking) ([]Booking, *Booking, error) {
+	var (
+		released []Booking
+		last     *Booking
+	)
+	err := ds.RetryTransaction(func(ds *DataStorage) error {
+		released, last = nil, nil
+
+		// the candidates past the shortest grace period, in the order of the
+		// idx_bookings_release index
+		query := ds.mysqlDB.Table("bookings b").
+			Select("b.*, s.zone_id, s.type AS seat_type").
+			Joins("JOIN seats s ON s.id = b.seat_id").
+			Where(releasePendingCondition).
+			Where("b.start_time < ?", now.Add(-config.minGracePeriod()).UTC())
+		if after != nil {
+			query = query.Where("(b.start_time > ? OR (b.start_time = ? AND b.id > ?))",
+				after.StartTime.UTC(), after.StartTime.UTC(), after.ID)
+		}
+		var candidates []releaseCandidate
+		if err := query.Order("b.start_time, b.id").Limit(config.BatchSize).Scan(&candidates).Error; err != nil {
+			return err
+		}
+		if len(candidates) == config.BatchSize {
+			cursor := candidates[len(candidates)-1].Booking
+			last = &cursor
+		}
+
+		var (
+			markIDs, deleteIDs []int
+			history            []BookingStatusHistory
+		)
+		for i := range candidates {
+			policy := config.PolicyFor(candidates[i].ZoneID, candidates[i].SeatType)
+			if !candidates[i].StartTime.Before(now.Add(-policy.GracePeriod)) {
+				continue
+			}
+			booking := candidates[i].Booking
+			booking.Status = BookingStatusReleased
+			if policy.Action == ReleaseActionDelete {
+				deleteIDs = append(deleteIDs, booking.ID)
+			} else {
+				markIDs = append(markIDs, booking.ID)
+			}
+			history = append(history, BookingStatusHistory{
+				BookingID: booking.ID,
+				Status:    booking.Status,
+				SeatID:    booking.SeatID,
+				StartTime: booking.StartTime,
+				EndTime:   booking.EndTime,
+			})
+			released = append(released, booking)
+		}
+		if len(released) == 0 {
+			return nil
+		}
+
+		// chunked to stay within the bound variables a statement may have
+		if err := ds.mysqlDB.CreateInBatches(&history, releaseHistoryChunk).Error; err != nil {
+			return err
+		}
+		if len(markIDs) > 0 {
+			err := ds.mysqlDB.Model(&Booking{}).Where("id IN ?", markIDs).Update("status", BookingStatusReleased).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(deleteIDs) > 0 {
+			return ds.mysqlDB.Exec("DELETE FROM bookings WHERE id IN ?", deleteIDs).Error
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+	return released, last, nil
+}
+
+// Create user
+func (ds *DataStorage) Create(user *User) error {
+	return ds.mysqlDB.Create(user).Error
+}
+
+// increase the failed login counter, the account is locked until lockUntil when it reaches maxAttempts.
+// The counter is increased in a single statement so that parallel failures are all counted, locked_until
+// is set first as MySQL evaluates each assignment with the columns already assigned
+func (ds *DataStorage) RecordFailedLogin(userID uint, maxAttempts int, lockUntil time.Time) error {
+	result := ds.mysqlDB.Exec(`UPDATE users SET
+		locked_until = CASE WHEN failed_login_attempts + 1 >= ? THEN ? ELSE locked_until END,
+		failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= ? THEN 0 ELSE failed_login_attempts + 1 END
+		WHERE id = ?`, maxAttempts, lockUntil.UTC(), maxAttempts, userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
 	}
 	return nil
 }
 
-func (ds *DataStorage) ReleaseBooking() error {
-	// Find bookings that have not been checked in and are more than 10 minutes past the start time
-	var bookings []Booking
-	err := ds.mysqlDB.Raw(`
-        SELECT id
-        FROM bookings
-        WHERE checked_in = false
-        AND start_time < ?
-    `, time.Now().Add(-10*time.Minute)).Scan(&bookings).Error
+// clear the failed login counter and lock after a successful login
+func (ds *DataStorage) ResetFailedLogins(userID uint) error {
+	return ds.mysqlDB.Model(&User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	}).Error
+}
+
+// insert the booking with its first status history entry
+func (ds *DataStorage) CreateBooking(booking *Booking) error {
+	return ds.Transaction(func(ds *DataStorage) error {
+		booking.Status = BookingStatusBooked
+		if err := ds.mysqlDB.Create(booking).Error; err != nil {
+			return err
+		}
+		return ds.addBookingHistory(booking)
+	})
+}
+
+// insert the booking when neither the user nor the seat has an overlapping booking,
+// the check and the insert run in a single transaction so concurrent requests cannot
+// both pass the check
+func (ds *DataStorage) CreateBookingIfAvailable(booking *Booking) error {
+	return ds.RetryTransaction(func(ds *DataStorage) error {
+		booking.ID = 0
+		if err := ds.checkBookingConflicts(booking, time.Now()); err != nil {
+			return err
+		}
+		return ds.CreateBooking(booking)
+	})
+}
+
+// update the booking when its new seat and time range do not overlap another booking
+func (ds *DataStorage) UpdateBookingIfAvailable(booking *Booking) error {
+	return ds.RetryTransaction(func(ds *DataStorage) error {
+		// the booking may have been released or checked in since it was read
+		var movable int64
+		err := ds.mysqlDB.Model(&Booking{}).
+			Where("id = ? AND checked_in = ? AND status IN ?", booking.ID, false, activeBookingStatuses).
+			Count(&movable).Error
+		if err != nil {
+			return err
+		}
+		if movable == 0 {
+			return ErrBookingNotActive
+		}
+		if err := ds.checkBookingConflicts(booking, time.Now()); err != nil {
+			return err
+		}
+		return ds.UpdateBooking(booking)
+	})
+}
+
+// insert the series with every booking which does not overlap another booking, conflicts[i]
+// is the conflict of bookings[i] or nil when it was booked. ErrNoOccurrenceBooked is
+// returned, and nothing is inserted, when every booking conflicts
+func (ds *DataStorage) CreateBookingSeriesIfAvailable(series *BookingSeries, bookings []Booking) ([]error, error) {
+	var conflicts []error
+	err := ds.RetryTransaction(func(ds *DataStorage) error {
+		conflicts, series.ID = make([]error, len(bookings)), 0
+		if err := ds.CreateBookingSeries(series); err != nil {
+			return err
+		}
+
+		booked := 0
+		for i := range bookings {
+			booking := &bookings[i]
+			booking.ID, booking.SeriesID = 0, &series.ID
+			if err := ds.checkBookingConflicts(booking, time.Now()); err != nil {
+				if !errors.Is(err, ErrUserAlreadyBooked) && !errors.Is(err, ErrSeatAlreadyBooked) {
+					return err
+				}
+				conflicts[i] = err
+				continue
+			}
+			if err := ds.CreateBooking(booking); err != nil {
+				return err
+			}
+			booked++
+		}
+
+		if booked == 0 {
+			return ErrNoOccurrenceBooked
+		}
+		return nil
+	})
+	return conflicts, err
+}
+
+// checkBookingConflicts return ErrUserAlreadyBooked or ErrSeatAlreadyBooked when another
+// active booking, or a waitlist offer of another user unexpired at now, overlaps the
+// booking. It must run inside a transaction to be race free
+func (ds *DataStorage) checkBookingConflicts(booking *Booking, now time.Time) error {
+	userBookings, err := ds.FindOverlapBookingsByUserID(booking.UserID, booking.StartTime, booking.EndTime)
+	if err != nil {
+		return err
+	}
+	if len(excludeBooking(userBookings, booking.ID)) > 0 {
+		return ErrUserAlreadyBooked
+	}
+
+	overlapBookings, err := ds.FindOverlapBookingsBySeatID(booking.SeatID, booking.StartTime, booking.EndTime)
+	if err != nil {
+		return err
+	}
+	if len(excludeBooking(overlapBookings, booking.ID)) > 0 {
+		return ErrSeatAlreadyBooked
+	}
+
+	offers, err := ds.countWaitlistOffers(booking.StartTime, booking.EndTime, now,
+		"offered_seat_id = ? AND user_id <> ?", booking.SeatID, booking.UserID)
 	if err != nil {
 		return err
 	}
+	if offers > 0 {
+		return ErrSeatAlreadyBooked
+	}
+	return nil
+}
+
+// countWaitlistOffers count the offers matching query which overlap [startTime, endTime)
+// and have not expired at now
+func (ds *DataStorage) countWaitlistOffers(startTime, endTime, now time.Time, query string, args ...interface{}) (int64, error) {
+	var count int64
+	err := ds.mysqlDB.Model(&WaitlistEntry{}).
+		Where("status = ? AND offer_expires_at > ? AND start_time < ? AND end_time > ?",
+			WaitlistStatusOffered, now.UTC(), endTime.UTC(), startTime.UTC()).
+		Where(query, args...).
+		Count(&count).Error
+	return count, err
+}
 
-	// Release each booking
+// excludeBooking return bookings without the booking identified by id
+func excludeBooking(bookings []Booking, id int) []Booking {
+	result := make([]Booking, 0, len(bookings))
 	for _, booking := range bookings {
-		// Update the booking status
-		err = ds.mysqlDB.Exec("DELETE from bookings WHERE id = ?", booking.ID).Error
+		if booking.ID != id {
+			result = append(result, booking)
+		}
+	}
+	return result
+}
+
+// save the new seat and time range of the booking and mark it as modified
+func (ds *DataStorage) UpdateBooking(booking *Booking) error {
+	return ds.Transaction(func(ds *DataStorage) error {
+		booking.Status = BookingStatusModified
+		err := ds.mysqlDB.Model(&Booking{}).Where("id = ?", booking.ID).Updates(map[string]interface{}{
+			"seat_id":    booking.SeatID,
+			"start_time": booking.StartTime.UTC(),
+			"end_time":   booking.EndTime.UTC(),
+			"status":     booking.Status,
+		}).Error
 		if err != nil {
 			return err
 		}
+		return ds.addBookingHistory(booking)
+	})
+}
+
+// change the status of the active booking and record it in the history
+func (ds *DataStorage) UpdateBookingStatus(booking *Booking, status string) error {
+	return ds.Transaction(func(ds *DataStorage) error {
+		result := ds.mysqlDB.Model(&Booking{}).
+			Where("id = ? AND status IN ?", booking.ID, activeBookingStatuses).
+			Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBookingNotActive
+		}
+		booking.Status = status
+		return ds.addBookingHistory(booking)
+	})
+}
+
+func (ds *DataStorage) addBookingHistory(booking *Booking) error {
+	return ds.mysqlDB.Create(&BookingStatusHistory{
+		BookingID: booking.ID,
+		Status:    booking.Status,
+		SeatID:    booking.SeatID,
+		StartTime: booking.StartTime,
+		EndTime:   booking.EndTime,
+	}).Error
+}
+
+// get the first active booking of the user on the seat overlapping [fromTime, toTime)
+func (ds *DataStorage) FindUserSeatBooking(userID, seatID uint, fromTime, toTime time.Time) (*Booking, error) {
+	var booking Booking
+	err := ds.mysqlDB.
+		Where("user_id = ? AND seat_id = ? AND status IN ? AND start_time < ? AND end_time > ?",
+			userID, seatID, activeBookingStatuses, toTime.UTC(), fromTime.UTC()).
+		Order("start_time, id").
+		First(&booking).Error
+	if err != nil {
+		return nil, err
 	}
+	return &booking, nil
+}
 
-	return nil
+// list the active bookings of the seats overlapping [fromTime, toTime), ordered by start time
+func (ds *DataStorage) FindActiveBookingsBySeatIDs(seatIDs []uint, fromTime, toTime time.Time) ([]Booking, error) {
+	var bookings []Booking
+	if len(seatIDs) == 0 {
+		return bookings, nil
+	}
+	err := ds.mysqlDB.
+		Where("seat_id IN ? AND status IN ? AND start_time < ? AND end_time > ?",
+			seatIDs, activeBookingStatuses, toTime.UTC(), fromTime.UTC()).
+		Order("start_time, id").
+		Find(&bookings).Error
+	if err != nil {
+		return nil, err
+	}
+	return bookings, nil
 }
 
-// Create user
-func (ds *DataStorage) Create(user *User) error {
-	return ds.mysqlDB.Create(user).Error
+// list the status history of the booking, oldest first
+func (ds *DataStorage) FindBookingHistory(bookingID int) ([]BookingStatusHistory, error) {
+	var history []BookingStatusHistory
+	err := ds.mysqlDB.Where("booking_id = ?", bookingID).Order("id").Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
 }
 
-func (ds *DataStorage) CreateBooking(booking *Booking) error {
-	return ds.mysqlDB.Create(booking).Error
+func (ds *DataStorage) CreateBookingSeries(series *BookingSeries) error {
+	return ds.mysqlDB.Create(series).Error
+}
+
+func (ds *DataStorage) GetBookingSeriesByID(id uint) (*BookingSeries, error) {
+	var series BookingSeries
+	err := ds.mysqlDB.Where("id = ?", id).First(&series).Error
+	if err != nil {
+		return nil, err
+	}
+	return &series, err
+}
+
+// cancel the active bookings of the series which have not started yet, it returns the cancelled bookings
+func (ds *DataStorage) CancelUpcomingBookingsBySeriesID(seriesID uint, now time.Time) ([]Booking, error) {
+	var cancelled []Booking
+	err := ds.Transaction(func(ds *DataStorage) error {
+		var bookings []Booking
+		err := ds.mysqlDB.Where("series_id = ? AND status IN ? AND start_time > ?", seriesID, activeBookingStatuses, now.UTC()).
+			Order("start_time").Find(&bookings).Error
+		if err != nil {
+			return err
+		}
+		for i := range bookings {
+			if err := ds.UpdateBookingStatus(&bookings[i], BookingStatusCancelled); err != nil {
+				return err
+			}
+		}
+		cancelled = bookings
+		return nil
+	})
+	return cancelled, err
 }
 
 // get user by email
@@ -97,6 +503,16 @@ func (ds *DataStorage) GetUserByEmail(email string) (*User, error) {
 	return &user, err
 }
 
+// get user by id
+func (ds *DataStorage) GetUserByID(id uint) (*User, error) {
+	var user User
+	err := ds.mysqlDB.Where("id = ?", id).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, err
+}
+
 func (ds *DataStorage) GetSeatByNumber(number string) (*Seat, error) {
 	var seat Seat
 	err := ds.mysqlDB.Where("number = ?", number).First(&seat).Error
@@ -106,6 +522,124 @@ func (ds *DataStorage) GetSeatByNumber(number string) (*Seat, error) {
 	return &seat, err
 }
 
+// get seat by id, soft-deleted seats are included when unscoped is true
+func (ds *DataStorage) GetSeatByID(id uint, unscoped bool) (*Seat, error) {
+	var seat Seat
+	db := ds.mysqlDB
+	if unscoped {
+		db = db.Unscoped()
+	}
+	err := db.Where("id = ?", id).First(&seat).Error
+	if err != nil {
+		return nil, err
+	}
+	return &seat, err
+}
+
+// list all seats, soft-deleted seats are included when unscoped is true
+func (ds *DataStorage) ListSeats(unscoped bool) ([]Seat, error) {
+	var seats []Seat
+	db := ds.mysqlDB
+	if unscoped {
+		db = db.Unscoped()
+	}
+	err := db.Preload("Zone.Floor.Building").Order("number").Find(&seats).Error
+	if err != nil {
+		return nil, err
+	}
+	return seats, nil
+}
+
+func (ds *DataStorage) CreateSeat(seat *Seat) error {
+	return ds.mysqlDB.Create(seat).Error
+}
+
+func (ds *DataStorage) UpdateSeat(seat *Seat) error {
+	return ds.mysqlDB.Save(seat).Error
+}
+
+// soft delete seat, its bookings are kept
+func (ds *DataStorage) DeleteSeat(id uint) error {
+	return ds.mysqlDB.Delete(&Seat{}, id).Error
+}
+
+// restore a soft-deleted seat
+func (ds *DataStorage) RestoreSeat(id uint) error {
+	return ds.mysqlDB.Unscoped().Model(&Seat{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
+
+// list buildings with their floors and zones
+func (ds *DataStorage) ListLocations() ([]Building, error) {
+	var buildings []Building
+	err := ds.mysqlDB.
+		Preload("Floors", func(db *gorm.DB) *gorm.DB { return db.Order("level") }).
+		Preload("Floors.Zones", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+		Order("name").
+		Find(&buildings).Error
+	if err != nil {
+		return nil, err
+	}
+	return buildings, nil
+}
+
+func (ds *DataStorage) GetBuildingByID(id uint) (*Building, error) {
+	var building Building
+	err := ds.mysqlDB.Where("id = ?", id).First(&building).Error
+	if err != nil {
+		return nil, err
+	}
+	return &building, err
+}
+
+func (ds *DataStorage) GetFloorByID(id uint) (*Floor, error) {
+	var floor Floor
+	err := ds.mysqlDB.Where("id = ?", id).First(&floor).Error
+	if err != nil {
+		return nil, err
+	}
+	return &floor, err
+}
+
+func (ds *DataStorage) GetZoneByID(id uint) (*Zone, error) {
+	var zone Zone
+	err := ds.mysqlDB.Where("id = ?", id).First(&zone).Error
+	if err != nil {
+		return nil, err
+	}
+	return &zone, err
+}
+
+func (ds *DataStorage) CreateBuilding(building *Building) error {
+	return ds.mysqlDB.Create(building).Error
+}
+
+func (ds *DataStorage) CreateFloor(floor *Floor) error {
+	return ds.mysqlDB.Create(floor).Error
+}
+
+func (ds *DataStorage) UpdateFloor(floor *Floor) error {
+	return ds.mysqlDB.Model(&Floor{}).Where("id = ?", floor.ID).Updates(map[string]interface{}{
+		"name":  floor.Name,
+		"level": floor.Level,
+	}).Error
+}
+
+func (ds *DataStorage) CreateZone(zone *Zone) error {
+	return ds.mysqlDB.Create(zone).Error
+}
+
+// save the name and role of the user
+func (ds *DataStorage) UpdateUser(user *User) error {
+	return ds.mysqlDB.Model(&User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"name": user.Name,
+		"role": user.Role,
+	}).Error
+}
+
+func (ds *DataStorage) UpdateUserRole(userID uint, role string) error {
+	return ds.mysqlDB.Model(&User{}).Where("id = ?", userID).Update("role", role).Error
+}
+
 func (ds *DataStorage) Booking(id uint) (*User, error) {
 	var user User
 	err := ds.mysqlDB.Where("id = ?", id).First(&user).Error
@@ -113,32 +647,78 @@ func (ds *DataStorage) Booking(id uint) (*User, error) {
 }
 
 // Find seats
-func (ds *DataStorage) FindAvailableSeats(fromTime, toTime time.Time) ([]Seat, error) {
+func (ds *DataStorage) FindAvailableSeats(fromTime, toTime time.Time, filter SeatFilter) ([]Seat, error) {
+	fromTime, toTime = fromTime.UTC(), toTime.UTC()
 	var seats []Seat
-	err := ds.mysqlDB.Raw(`
-        SELECT s.*
-        FROM seats s
-        WHERE NOT EXISTS (
+	query := ds.seatQuery().
+		Where(`NOT EXISTS (
             SELECT 1
             FROM bookings b
-            WHERE b.seat_id = s.id
+            WHERE b.seat_id = seats.id
+            AND b.status IN ?
             AND (
                 (b.start_time < ? AND b.end_time > ?)
                 OR (b.start_time < ? AND b.end_time > ?)
                 OR (b.start_time >= ? AND b.end_time <= ?)
             )
-        )
-    `, toTime, fromTime, toTime, fromTime, fromTime, toTime).Scan(&seats).Error
+        )`, activeBookingStatuses, toTime, fromTime, toTime, fromTime, fromTime, toTime)
+	err := applySeatFilter(query, filter).Order("seats.number").Find(&seats).Error
 	if err != nil {
 		return nil, err
 	}
 	return seats, nil
 }
 
-// Find bookings that start_time and end_time of request is overlap with start_time and end_time of bookings
+// list the seats matching the filter with their location, ordered by number
+func (ds *DataStorage) FindSeats(filter SeatFilter) ([]Seat, error) {
+	var seats []Seat
+	err := applySeatFilter(ds.seatQuery(), filter).Order("seats.number").Find(&seats).Error
+	if err != nil {
+		return nil, err
+	}
+	return seats, nil
+}
+
+// seatQuery select the seats with their location, joined with zones and floors for applySeatFilter
+func (ds *DataStorage) seatQuery() *gorm.DB {
+	return ds.mysqlDB.Model(&Seat{}).
+		Select("seats.*").
+		Preload("Zone.Floor.Building").
+		Joins("LEFT JOIN zones ON zones.id = seats.zone_id").
+		Joins("LEFT JOIN floors ON floors.id = zones.floor_id")
+}
+
+// applySeatFilter add the filter conditions, the query must join zones and floors
+func applySeatFilter(query *gorm.DB, filter SeatFilter) *gorm.DB {
+	if filter.BuildingID != nil {
+		query = query.Where("floors.building_id = ?", *filter.BuildingID)
+	}
+	if filter.FloorID != nil {
+		query = query.Where("zones.floor_id = ?", *filter.FloorID)
+	}
+	if filter.ZoneID != nil {
+		query = query.Where("seats.zone_id = ?", *filter.ZoneID)
+	}
+	if filter.Type != "" {
+		query = query.Where("seats.type = ?", filter.Type)
+	}
+	if filter.DualMonitor != nil {
+		query = query.Where("seats.dual_monitor = ?", *filter.DualMonitor)
+	}
+	if filter.NearWindow != nil {
+		query = query.Where("seats.near_window = ?", *filter.NearWindow)
+	}
+	if filter.Accessible != nil {
+		query = query.Where("seats.accessible = ?", *filter.Accessible)
+	}
+	return query
+}
+
+// Find bookings that start_time and end_time of request is overlap with start_time and end_time of bookings,
+// time ranges are half open so that back to back bookings do not overlap
 func (ds *DataStorage) FindOverlapBookingsBySeatID(seatID uint, startTime, endTime time.Time) ([]Booking, error) {
 	var bookings []Booking
-	err := ds.mysqlDB.Where("seat_id = ? AND start_time <= ? AND end_time >= ?", seatID, endTime, startTime).Find(&bookings).Error
+	err := ds.mysqlDB.Where("seat_id = ? AND status IN ? AND start_time < ? AND end_time > ?", seatID, activeBookingStatuses, endTime.UTC(), startTime.UTC()).Find(&bookings).Error
 	if err != nil {
 		return nil, err
 	}
@@ -148,21 +728,486 @@ func (ds *DataStorage) FindOverlapBookingsBySeatID(seatID uint, startTime, endTi
 // find bookings that start_time and end_time of request is overlap with start_time and end_time this user's bookings
 func (ds *DataStorage) FindOverlapBookingsByUserID(userID uint, startTime, endTime time.Time) ([]Booking, error) {
 	var bookings []Booking
-	err := ds.mysqlDB.Where("user_id = ? AND start_time <= ? AND end_time >= ?", userID, endTime, startTime).Find(&bookings).Error
+	err := ds.mysqlDB.Where("user_id = ? AND status IN ? AND start_time < ? AND end_time > ?", userID, activeBookingStatuses, endTime.UTC(), startTime.UTC()).Find(&bookings).Error
 	if err != nil {
 		return nil, err
 	}
 	return bookings, err
 }
 
-// find bookings by user_id
-func (ds *DataStorage) FindBookingsByUserID(userID uint) ([]Booking, error) {
-	var bookings []Booking
-	err := ds.mysqlDB.Where("user_id = ?", userID).Find(&bookings).Error
+// count bookings of the seat which have not ended yet
+func (ds *DataStorage) CountUpcomingBookingsBySeatID(seatID uint, now time.Time) (int64, error) {
+	var count int64
+	err := ds.mysqlDB.Model(&Booking{}).Where("seat_id = ? AND status IN ? AND end_time > ?", seatID, activeBookingStatuses, now.UTC()).Count(&count).Error
+	return count, err
+}
+
+// find a page of bookings by user_id with their seat and location, filter is one of
+// the BookingFilter values and after is the cursor of the previous page
+func (ds *DataStorage) FindBookingsByUserID(userID uint, filter string, after *bookingCursor, limit int, now time.Time) ([]BookingView, error) {
+	now = now.UTC()
+	query := ds.mysqlDB.Table("bookings").
+		Select(`bookings.id, bookings.series_id, bookings.seat_id, seats.number AS seat_number,
+            zones.id AS zone_id, zones.name AS zone_name, floors.id AS floor_id, floors.name AS floor_name,
+            buildings.id AS building_id, buildings.name AS building_name,
+            bookings.start_time, bookings.end_time, bookings.checked_in, bookings.status,
+            bookings.checked_in_at, bookings.checked_out_at`).
+		Joins("JOIN seats ON seats.id = bookings.seat_id").
+		Joins("LEFT JOIN zones ON zones.id = seats.zone_id").
+		Joins("LEFT JOIN floors ON floors.id = zones.floor_id").
+		Joins("LEFT JOIN buildings ON buildings.id = floors.building_id").
+		Where("bookings.user_id = ?", userID)
+
+	ascending := false
+	switch filter {
+	case BookingFilterUpcoming:
+		ascending = true
+		query = query.Where("bookings.end_time > ? AND bookings.status IN ?", now, activeBookingStatuses)
+	case BookingFilterPast:
+		query = query.Where("bookings.end_time <= ?", now)
+	case BookingFilterCheckedIn:
+		query = query.Where("bookings.checked_in = ?", true)
+	}
+
+	if after != nil {
+		after.StartTime = after.StartTime.UTC()
+	}
+	if ascending {
+		if after != nil {
+			query = query.Where("bookings.start_time > ? OR (bookings.start_time = ? AND bookings.id > ?)", after.StartTime, after.StartTime, after.ID)
+		}
+		query = query.Order("bookings.start_time ASC, bookings.id ASC")
+	} else {
+		if after != nil {
+			query = query.Where("bookings.start_time < ? OR (bookings.start_time = ? AND bookings.id < ?)", after.StartTime, after.StartTime, after.ID)
+		}
+		query = query.Order("bookings.start_time DESC, bookings.id DESC")
+	}
+
+	var views []BookingView
+	err := query.Limit(limit).Scan(&views).Error
 	if err != nil {
 		return nil, err
 	}
-	return bookings, err
+	return views, nil
+}
+
+// call fn with every booking matching the filter ordered by start time, the rows are
+// read one by one so exports of any size do not have to fit in memory
+func (ds *DataStorage) ExportBookings(filter BookingExportFilter, fn func(row BookingExportRow) error) error {
+	query := ds.mysqlDB.Table("bookings").
+		Select(`bookings.id, bookings.user_id, users.email AS user_email, users.name AS user_name,
+            bookings.seat_id, seats.number AS seat_number, bookings.start_time, bookings.end_time,
+            bookings.status, bookings.checked_in, bookings.checked_in_at, bookings.checked_out_at`).
+		Joins("JOIN users ON users.id = bookings.user_id").
+		Joins("JOIN seats ON seats.id = bookings.seat_id")
+	if filter.From != nil {
+		query = query.Where("bookings.start_time >= ?", filter.From.UTC())
+	}
+	if filter.To != nil {
+		query = query.Where("bookings.start_time < ?", filter.To.UTC())
+	}
+	if filter.UserID != nil {
+		query = query.Where("bookings.user_id = ?", *filter.UserID)
+	}
+	if filter.SeatNumber != "" {
+		query = query.Where("seats.number = ?", filter.SeatNumber)
+	}
+
+	rows, err := query.Order("bookings.start_time, bookings.id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row BookingExportRow
+		if err := ds.mysqlDB.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// call fn with every booking starting in [from, to). The no-show bookings deleted by
+// ReleaseBooking are read from their released history so they are reported too
+func (ds *DataStorage) ReportBookings(from, to time.Time, fn func(row ReportBooking) error) error {
+	queries := []*gorm.DB{
+		ds.mysqlDB.Model(&Booking{}).
+			Select("id, seat_id, start_time, end_time, status, checked_in, checked_in_at, checked_out_at").
+			Where("start_time >= ? AND start_time < ?", from.UTC(), to.UTC()),
+		ds.mysqlDB.Model(&BookingStatusHistory{}).
+			Select("booking_id AS id, seat_id, start_time, end_time, status").
+			Where("status = ? AND start_time >= ? AND start_time < ?", BookingStatusReleased, from.UTC(), to.UTC()).
+			Where("NOT EXISTS (SELECT 1 FROM bookings WHERE bookings.id = booking_status_histories.booking_id)"),
+	}
+	for _, query := range queries {
+		rows, err := query.Rows()
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var row ReportBooking
+			if err := ds.mysqlDB.ScanRows(rows, &row); err != nil {
+				rows.Close()
+				return err
+			}
+			if err := fn(row); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ds *DataStorage) CreateWaitlistEntry(entry *WaitlistEntry) error {
+	entry.Status = WaitlistStatusWaiting
+	return ds.mysqlDB.Create(entry).Error
+}
+
+func (ds *DataStorage) GetWaitlistEntryByID(id uint) (*WaitlistEntry, error) {
+	var entry WaitlistEntry
+	if err := ds.mysqlDB.Where("id = ?", id).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// find the waiting and offered entries of the user which have not ended at now
+func (ds *DataStorage) FindWaitlistEntriesByUserID(userID uint, now time.Time) ([]WaitlistEntry, error) {
+	var entries []WaitlistEntry
+	err := ds.mysqlDB.
+		Where("user_id = ? AND status IN ? AND end_time > ?",
+			userID, []string{WaitlistStatusWaiting, WaitlistStatusOffered}, now.UTC()).
+		Order("id").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (ds *DataStorage) UpdateWaitlistEntryStatus(entry *WaitlistEntry, status string) error {
+	entry.Status = status
+	return ds.mysqlDB.Model(&WaitlistEntry{}).Where("id = ?", entry.ID).Update("status", status).Error
+}
+
+// offer the seat, free in [fromTime, toTime], to the waiting entries for it or its zone in
+// line order. An entry is offered the seat when neither the seat nor the user has another
+// booking or offer over its time range, the offers expire at expiresAt
+func (ds *DataStorage) OfferWaitlistSeat(seat *Seat, fromTime, toTime, now, expiresAt time.Time) ([]WaitlistEntry, error) {
+	var offered []WaitlistEntry
+	err := ds.RetryTransaction(func(ds *DataStorage) error {
+		offered = nil
+		query := ds.mysqlDB.Where("status = ? AND start_time < ? AND end_time > ? AND end_time > ?",
+			WaitlistStatusWaiting, toTime.UTC(), fromTime.UTC(), now.UTC())
+		if seat.ZoneID != nil {
+			query = query.Where("(seat_id = ? OR zone_id = ?)", seat.ID, *seat.ZoneID)
+		} else {
+			query = query.Where("seat_id = ?", seat.ID)
+		}
+		var waiting []WaitlistEntry
+		if err := query.Order("id").Find(&waiting).Error; err != nil {
+			return err
+		}
+
+		for _, entry := range waiting {
+			// a user is offered one seat at a time
+			offers, err := ds.countWaitlistOffers(entry.StartTime, entry.EndTime, now, "user_id = ?", entry.UserID)
+			if err != nil {
+				return err
+			}
+			if offers > 0 {
+				continue
+			}
+			if err := ds.checkBookingConflicts(entry.booking(seat.ID, now), now); err != nil {
+				if errors.Is(err, ErrUserAlreadyBooked) || errors.Is(err, ErrSeatAlreadyBooked) {
+					continue
+				}
+				return err
+			}
+			seatID, expires := seat.ID, expiresAt.UTC()
+			entry.Status, entry.OfferedSeatID, entry.OfferExpiresAt = WaitlistStatusOffered, &seatID, &expires
+			err = ds.mysqlDB.Model(&WaitlistEntry{}).Where("id = ?", entry.ID).Updates(map[string]interface{}{
+				"status":           entry.Status,
+				"offered_seat_id":  seat.ID,
+				"offer_expires_at": expires,
+			}).Error
+			if err != nil {
+				return err
+			}
+			offered = append(offered, entry)
+		}
+		return nil
+	})
+	return offered, err
+}
+
+// expire the offers which have not been claimed before now, it returns the expired entries
+func (ds *DataStorage) ExpireWaitlistOffers(now time.Time) ([]WaitlistEntry, error) {
+	var expired []WaitlistEntry
+	err := ds.Transaction(func(ds *DataStorage) error {
+		err := ds.mysqlDB.Where("status = ? AND offer_expires_at <= ?", WaitlistStatusOffered, now.UTC()).
+			Order("id").
+			Find(&expired).Error
+		if err != nil || len(expired) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(expired))
+		for i := range expired {
+			ids[i], expired[i].Status = expired[i].ID, WaitlistStatusExpired
+		}
+		return ds.mysqlDB.Model(&WaitlistEntry{}).Where("id IN ?", ids).Update("status", WaitlistStatusExpired).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+// book the seat offered to the entry for the rest of its time range, it fails with
+// ErrNoWaitlistOffer or ErrWaitlistOfferExpired when there is no offer to claim at now
+func (ds *DataStorage) ClaimWaitlistOffer(entry *WaitlistEntry, now time.Time) (*Booking, error) {
+	var booking *Booking
+	err := ds.RetryTransaction(func(ds *DataStorage) error {
+		stored, err := ds.GetWaitlistEntryByID(entry.ID)
+		if err != nil {
+			return err
+		}
+		if err := stored.claimable(now); err != nil {
+			return err
+		}
+
+		booking = stored.booking(*stored.OfferedSeatID, now)
+		if err := ds.checkBookingConflicts(booking, now); err != nil {
+			return err
+		}
+		if err := ds.CreateBooking(booking); err != nil {
+			return err
+		}
+
+		bookingID := booking.ID
+		stored.Status, stored.BookingID = WaitlistStatusClaimed, &bookingID
+		err = ds.mysqlDB.Model(&WaitlistEntry{}).Where("id = ?", stored.ID).Updates(map[string]interface{}{
+			"status":     stored.Status,
+			"booking_id": booking.ID,
+		}).Error
+		if err != nil {
+			return err
+		}
+		*entry = *stored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return booking, nil
+}
+
+// insert the notifications in the outbox
+func (ds *DataStorage) CreateNotifications(notifications []Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return ds.mysqlDB.Create(&notifications).Error
+}
+
+// claim up to limit pending notifications due at now, their next attempt is pushed back
+// by lease so that they are not claimed again while they are being sent
+func (ds *DataStorage) ClaimDueNotifications(now time.Time, lease time.Duration, limit int) ([]Notification, error) {
+	var due []Notification
+	err := ds.RetryTransaction(func(ds *DataStorage) error {
+		due = nil
+		err := ds.mysqlDB.Where("status = ? AND next_attempt_at <= ?", NotificationStatusPending, now.UTC()).
+			Order("next_attempt_at, id").
+			Limit(limit).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		until := now.Add(lease).UTC()
+		ids := make([]uint, len(due))
+		for i := range due {
+			ids[i], due[i].NextAttemptAt = due[i].ID, until
+		}
+		return ds.mysqlDB.Model(&Notification{}).Where("id IN ?", ids).Update("next_attempt_at", until).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// save the outcome of a delivery attempt
+func (ds *DataStorage) UpdateNotificationDelivery(notification *Notification) error {
+	return ds.mysqlDB.Model(&Notification{}).Where("id = ?", notification.ID).Updates(map[string]interface{}{
+		"status":          notification.Status,
+		"attempts":        notification.Attempts,
+		"next_attempt_at": notification.NextAttemptAt.UTC(),
+		"last_error":      notification.LastError,
+		"sent_at":         notification.SentAt,
+	}).Error
+}
+
+// find the latest in-app notifications delivered to the user
+func (ds *DataStorage) FindInAppNotifications(userID uint, limit int) ([]Notification, error) {
+	var notifications []Notification
+	err := ds.mysqlDB.
+		Where("user_id = ? AND channel = ? AND status = ?", userID, NotificationChannelInApp, NotificationStatusSent).
+		Order("id DESC").
+		Limit(limit).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// mark a delivered in-app notification of the user as read, it is kept read at its first read time
+func (ds *DataStorage) MarkNotificationRead(userID, id uint, now time.Time) (*Notification, error) {
+	var notification Notification
+	err := ds.mysqlDB.
+		Where("id = ? AND user_id = ? AND channel = ? AND status = ?", id, userID, NotificationChannelInApp, NotificationStatusSent).
+		First(&notification).Error
+	if err != nil {
+		return nil, err
+	}
+	if notification.ReadAt != nil {
+		return &notification, nil
+	}
+
+	readAt := now.UTC()
+	if err := ds.mysqlDB.Model(&Notification{}).Where("id = ?", id).Update("read_at", readAt).Error; err != nil {
+		return nil, err
+	}
+	notification.ReadAt = &readAt
+	return &notification, nil
+}
+
+// find the active bookings not checked in starting within the time range which have no
+// reminder job for their start time, with the seat attributes release policies match on
+func (ds *DataStorage) FindUnscheduledReminders(fromTime, toTime time.Time) ([]releaseCandidate, error) {
+	var candidates []releaseCandidate
+	err := ds.mysqlDB.Raw(`
+        SELECT b.*, s.zone_id, s.type AS seat_type
+        FROM bookings b
+        JOIN seats s ON s.id = b.seat_id
+        WHERE b.checked_in = ?
+        AND b.status IN ?
+        AND b.start_time >= ? AND b.start_time < ?
+        AND NOT EXISTS (
+            SELECT 1 FROM reminder_jobs r WHERE r.booking_id = b.id AND r.start_time = b.start_time
+        )
+    `, false, activeBookingStatuses, fromTime.UTC(), toTime.UTC()).Scan(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// insert the reminder jobs, the ones another scheduler has planned already are skipped
+func (ds *DataStorage) CreateReminderJobs(jobs []ReminderJob) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	return ds.mysqlDB.Clauses(clause.OnConflict{DoNothing: true}).Create(&jobs).Error
+}
+
+// find up to limit pending reminder jobs due at now
+func (ds *DataStorage) FindDueReminderJobs(now time.Time, limit int) ([]ReminderJob, error) {
+	var jobs []ReminderJob
+	err := ds.mysqlDB.Where("status = ? AND run_at <= ?", ReminderStatusPending, now.UTC()).
+		Order("run_at, id").
+		Limit(limit).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// complete the pending job with status and queue its notifications in the same
+// transaction, it reports false when another scheduler completed the job first
+func (ds *DataStorage) CompleteReminderJob(job *ReminderJob, status string, notifications []Notification) (bool, error) {
+	completed := false
+	err := ds.Transaction(func(ds *DataStorage) error {
+		result := ds.mysqlDB.Model(&ReminderJob{}).
+			Where("id = ? AND status = ?", job.ID, ReminderStatusPending).
+			Update("status", status)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		completed = true
+		return ds.CreateNotifications(notifications)
+	})
+	if err != nil {
+		return false, err
+	}
+	if completed {
+		job.Status = status
+	}
+	return completed, nil
+}
+
+// take the lease of the job for holder until now+ttl, it is granted when holder has it
+// already or the lease of the previous holder has expired
+func (ds *DataStorage) AcquireJobLease(name, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	err := ds.mysqlDB.Clauses(clause.OnConflict{DoNothing: true}).Create(&Job{Name: name, LeaseExpiresAt: now.UTC()}).Error
+	if err != nil {
+		return false, err
+	}
+	result := ds.mysqlDB.Model(&Job{}).
+		Where("name = ? AND (holder = ? OR lease_expires_at <= ?)", name, holder, now.UTC()).
+		Updates(map[string]interface{}{"holder": holder, "lease_expires_at": now.Add(ttl).UTC()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// expire the lease of the job if holder has it, so that another instance takes over
+// without waiting for it to expire
+func (ds *DataStorage) ReleaseJobLease(name, holder string, now time.Time) error {
+	return ds.mysqlDB.Model(&Job{}).
+		Where("name = ? AND holder = ?", name, holder).
+		Update("lease_expires_at", now.UTC()).Error
+}
+
+// add the run to the metrics of the job
+func (ds *DataStorage) RecordJobRun(name string, run JobRun) error {
+	failures, lastError := 0, ""
+	if run.Err != nil {
+		failures, lastError = 1, run.Err.Error()
+	}
+	startedAt := run.StartedAt.UTC()
+	return ds.mysqlDB.Model(&Job{}).Where("name = ?", name).Updates(map[string]interface{}{
+		"last_run_at":      startedAt,
+		"last_duration_ms": run.Duration.Milliseconds(),
+		"last_processed":   run.Processed,
+		"last_error":       lastError,
+		"runs":             gorm.Expr("runs + 1"),
+		"failures":         gorm.Expr("failures + ?", failures),
+		"total_processed":  gorm.Expr("total_processed + ?", run.Processed),
+	}).Error
+}
+
+func (ds *DataStorage) ListJobs() ([]Job, error) {
+	var jobs []Job
+	if err := ds.mysqlDB.Order("name").Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
 }
 
 // gorm transaction
@@ -171,3 +1216,59 @@ func (ds *DataStorage) Transaction(fn func(ds *DataStorage) error) error {
 		return fn(&DataStorage{mysqlDB: tx})
 	})
 }
+
+// WithTransaction run fn in a transaction, see Storage
+func (ds *DataStorage) WithTransaction(fn func(s Storage) error) error {
+	return ds.Transaction(func(ds *DataStorage) error {
+		return fn(ds)
+	})
+}
+
+// RetryTransaction run fn in a serializable transaction and run it again when the database
+// aborts it because of a concurrent transaction, fn must be safe to run more than once.
+// Inside another transaction fn runs once in a savepoint, the isolation of the outer
+// transaction applies and a failure aborts it so that there is nothing left to retry
+func (ds *DataStorage) RetryTransaction(fn func(ds *DataStorage) error) error {
+	if ds.inTransaction() {
+		return ds.Transaction(fn)
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxTransactionAttempts; attempt++ {
+		err = ds.mysqlDB.Transaction(func(tx *gorm.DB) error {
+			return fn(&DataStorage{mysqlDB: tx})
+		}, &sql.TxOptions{Isolation: sql.LevelSerializable})
+		if !isSerializationFailure(err) {
+			return err
+		}
+		// back off with jitter so the competing transactions do not collide again
+		time.Sleep(time.Duration(attempt) * transactionRetryDelay * time.Duration(1+rand.Intn(4)))
+	}
+	return err
+}
+
+// inTransaction report whether ds runs inside a transaction
+func (ds *DataStorage) inTransaction() bool {
+	committer, ok := ds.mysqlDB.Statement.ConnPool.(gorm.TxCommitter)
+	return ok && committer != nil
+}
+
+// isSerializationFailure report whether err means the transaction lost a race and can be retried
+func isSerializationFailure(err error) bool {
+	var (
+		sqliteErr sqlite3.Error
+		pgErr     *pgconn.PgError
+		mysqlErr  *mysql.MySQLError
+	)
+	switch {
+	case errors.As(err, &sqliteErr):
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	case errors.As(err, &pgErr):
+		// serialization_failure, deadlock_detected
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	case errors.As(err, &mysqlErr):
+		// ER_LOCK_DEADLOCK, ER_LOCK_WAIT_TIMEOUT
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+	return false
+}
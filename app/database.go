@@ -0,0 +1,69 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// DatabaseConfig is the `database` section of config.yaml
+type DatabaseConfig struct {
+	Driver          string        `mapstructure:"driver"`
+	DSN             string        `mapstructure:"dsn"`
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+}
+
+// OpenDatabase connect to the configured database, every timestamp is written in UTC
+// so that SQLite, which compares them as text, orders them the same as the other drivers
+func OpenDatabase(config DatabaseConfig) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch config.Driver {
+	case DriverSQLite, "":
+		dialector = sqlite.Open(config.DSN)
+	case DriverPostgres:
+		dialector = postgres.Open(config.DSN)
+	case DriverMySQL:
+		dialector = mysql.Open(config.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", config.Driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		// report unique violations of every driver as gorm.ErrDuplicatedKey
+		TranslateError: true,
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if config.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+	return db, nil
+}
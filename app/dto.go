@@ -4,18 +4,146 @@ import "time"
 
 type (
 	RegisterRequest struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Email    string `json:"email" binding:"required,email"`
+		Name     string `json:"name" binding:"required"`
+		Password string `json:"password" binding:"required,min=8,max=72"`
 	}
 
 	LoginRequest struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
 	}
 
 	BookSeatRequest struct {
 		SeatNumber string `json:"seat_number"`
-		UserID     uint   `json:"user_id"`
+	}
+
+	RecurringBookingRequest struct {
+		SeatNumber string `json:"seat_number" binding:"required"`
+		// FromTime and ToTime are the time range of the first occurrence
+		FromTime string `json:"from_time" binding:"required"`
+		ToTime   string `json:"to_time" binding:"required"`
+		// RRule is a recurrence rule like "FREQ=WEEKLY;BYDAY=TU,TH;UNTIL=20240331"
+		RRule string `json:"rrule" binding:"required"`
+	}
+
+	// UpdateBookingRequest change a booking, empty fields are kept
+	UpdateBookingRequest struct {
+		SeatNumber string `json:"seat_number"`
+		FromTime   string `json:"from_time"`
+		ToTime     string `json:"to_time"`
+	}
+
+	// JoinWaitlistRequest wait for the seat, or any seat of the zone, over a time range
+	JoinWaitlistRequest struct {
+		SeatNumber string `json:"seat_number"`
+		ZoneID     *uint  `json:"zone_id"`
+		FromTime   string `json:"from_time" binding:"required"`
+		ToTime     string `json:"to_time" binding:"required"`
+	}
+
+	// OccurrenceResult describe one occurrence of a recurring booking
+	OccurrenceResult struct {
+		BookingID int       `json:"booking_id,omitempty"`
+		StartTime time.Time `json:"start_time"`
+		EndTime   time.Time `json:"end_time"`
+		Reason    string    `json:"reason,omitempty"`
+	}
+
+	// BookingView is a booking joined with its seat and location
+	BookingView struct {
+		ID           int        `json:"id"`
+		SeriesID     *uint      `json:"series_id,omitempty"`
+		SeatID       uint       `json:"seat_id"`
+		SeatNumber   string     `json:"seat_number"`
+		ZoneID       *uint      `json:"zone_id"`
+		ZoneName     *string    `json:"zone_name"`
+		FloorID      *uint      `json:"floor_id"`
+		FloorName    *string    `json:"floor_name"`
+		BuildingID   *uint      `json:"building_id"`
+		BuildingName *string    `json:"building_name"`
+		StartTime    time.Time  `json:"start_time"`
+		EndTime      time.Time  `json:"end_time"`
+		CheckedIn    bool       `json:"checked_in"`
+		Status       string     `json:"status"`
+		CheckedInAt  *time.Time `json:"checked_in_at,omitempty"`
+		CheckedOutAt *time.Time `json:"checked_out_at,omitempty"`
+	}
+
+	SeatRequest struct {
+		Number      string `json:"number" binding:"required"`
+		ZoneID      *uint  `json:"zone_id"`
+		Type        string `json:"type"`
+		DualMonitor bool   `json:"dual_monitor"`
+		NearWindow  bool   `json:"near_window"`
+		Accessible  bool   `json:"accessible"`
+	}
+
+	BuildingRequest struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	FloorRequest struct {
+		BuildingID uint   `json:"building_id" binding:"required"`
+		Name       string `json:"name" binding:"required"`
+		Level      int    `json:"level"`
+	}
+
+	ZoneRequest struct {
+		FloorID uint   `json:"floor_id" binding:"required"`
+		Name    string `json:"name" binding:"required"`
+	}
+
+	// SeatFilter narrow seats down by location and attributes, nil fields are ignored
+	SeatFilter struct {
+		BuildingID  *uint  `json:"building_id" form:"building_id"`
+		FloorID     *uint  `json:"floor_id" form:"floor_id"`
+		ZoneID      *uint  `json:"zone_id" form:"zone_id"`
+		Type        string `json:"type" form:"type"`
+		DualMonitor *bool  `json:"dual_monitor" form:"dual_monitor"`
+		NearWindow  *bool  `json:"near_window" form:"near_window"`
+		Accessible  *bool  `json:"accessible" form:"accessible"`
+	}
+
+	UpdateRoleRequest struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	// BookingExportFilter select the exported bookings by start time in [From, To), user
+	// and seat number, nil and empty fields are ignored
+	BookingExportFilter struct {
+		From       *time.Time
+		To         *time.Time
+		UserID     *uint
+		SeatNumber string
+	}
+
+	// BookingExportRow is a booking joined with its user and seat
+	BookingExportRow struct {
+		ID           int
+		UserID       uint
+		UserEmail    string
+		UserName     string
+		SeatID       uint
+		SeatNumber   string
+		StartTime    time.Time
+		EndTime      time.Time
+		Status       string
+		CheckedIn    bool
+		CheckedInAt  *time.Time
+		CheckedOutAt *time.Time
+	}
+
+	// ReportBooking is the part of a booking the utilization reports read
+	ReportBooking struct {
+		ID           int
+		SeatID       uint
+		StartTime    time.Time
+		EndTime      time.Time
+		Status       string
+		CheckedIn    bool
+		CheckedInAt  *time.Time
+		CheckedOutAt *time.Time
 	}
 )
 
@@ -32,3 +160,12 @@ type (
 		CheckedIn bool
 	}
 )
+
+func (r *SeatRequest) applyTo(seat *Seat) {
+	seat.Number = r.Number
+	seat.ZoneID = r.ZoneID
+	seat.Type = r.Type
+	seat.DualMonitor = r.DualMonitor
+	seat.NearWindow = r.NearWindow
+	seat.Accessible = r.Accessible
+}
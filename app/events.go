@@ -0,0 +1,160 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// seatEventBuffer is how many events a subscriber may fall behind before it is dropped
+const seatEventBuffer = 64
+
+// SeatEventFreed is the type of the event of the seat and time range a booking moved away from
+const SeatEventFreed = "freed"
+
+type (
+	// SeatEvent is a change of the state of a seat, Type is the new status of the booking
+	// holding it: booked, modified, checked_in, released or cancelled, or freed once the
+	// booking moved to another seat or time range
+	SeatEvent struct {
+		Type       string    `json:"type"`
+		SeatID     uint      `json:"seat_id"`
+		SeatNumber string    `json:"seat_number"`
+		ZoneID     *uint     `json:"zone_id"`
+		FloorID    *uint     `json:"floor_id"`
+		BookingID  int       `json:"booking_id"`
+		StartTime  time.Time `json:"start_time"`
+		EndTime    time.Time `json:"end_time"`
+		At         time.Time `json:"at"`
+	}
+
+	// SeatEventFilter select the events of a floor and/or zone, nil fields are ignored
+	SeatEventFilter struct {
+		FloorID *uint `form:"floor_id"`
+		ZoneID  *uint `form:"zone_id"`
+	}
+
+	// SeatEvents is an in-process pub/sub of the seat events. Publishing never blocks,
+	// a subscriber which does not keep up is dropped and its channel closed so the
+	// client reconnects and reloads the seats
+	SeatEvents struct {
+		seats       SeatRepository
+		locations   LocationRepository
+		mu          sync.Mutex
+		subscribers map[chan SeatEvent]SeatEventFilter
+		closed      bool
+	}
+)
+
+func NewSeatEvents(seats SeatRepository, locations LocationRepository) *SeatEvents {
+	return &SeatEvents{
+		seats:       seats,
+		locations:   locations,
+		subscribers: map[chan SeatEvent]SeatEventFilter{},
+	}
+}
+
+// Subscribe return the channel of the events matching filter and the func ending the
+// subscription. The channel is closed when the subscription ends
+func (e *SeatEvents) Subscribe(filter SeatEventFilter) (<-chan SeatEvent, func()) {
+	ch := make(chan SeatEvent, seatEventBuffer)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	e.subscribers[ch] = filter
+	return ch, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.remove(ch)
+	}
+}
+
+// Publish send the event of the booking's current status to the subscribers of its seat
+func (e *SeatEvents) Publish(booking Booking) {
+	e.publishBooking(booking.Status, booking)
+}
+
+// PublishFreed send the freed event of the seat and time range the booking held before
+// it moved
+func (e *SeatEvents) PublishFreed(previous Booking) {
+	e.publishBooking(SeatEventFreed, previous)
+}
+
+func (e *SeatEvents) publishBooking(eventType string, booking Booking) {
+	seat, err := e.seats.GetSeatByID(booking.SeatID, true)
+	if err != nil {
+		log.WithError(err).WithField("seat_id", booking.SeatID).Warn("seat event fail")
+		return
+	}
+
+	event := SeatEvent{
+		Type:       eventType,
+		SeatID:     seat.ID,
+		SeatNumber: seat.Number,
+		ZoneID:     seat.ZoneID,
+		BookingID:  booking.ID,
+		StartTime:  booking.StartTime,
+		EndTime:    booking.EndTime,
+		At:         time.Now(),
+	}
+	if seat.ZoneID != nil {
+		zone, err := e.locations.GetZoneByID(*seat.ZoneID)
+		if err != nil {
+			log.WithError(err).WithField("zone_id", *seat.ZoneID).Warn("seat event fail")
+			return
+		}
+		event.FloorID = &zone.FloorID
+	}
+	e.publish(event)
+}
+
+func (e *SeatEvents) publish(event SeatEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for ch, filter := range e.subscribers {
+		if !filter.matches(event) {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+			log.WithField("seat_id", event.SeatID).Warn("drop slow seat event subscriber")
+			e.remove(ch)
+		}
+	}
+}
+
+// Close end every subscription, later subscriptions are closed right away
+func (e *SeatEvents) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+	for ch := range e.subscribers {
+		e.remove(ch)
+	}
+}
+
+// remove end the subscription of ch, e.mu must be held
+func (e *SeatEvents) remove(ch chan SeatEvent) {
+	if _, ok := e.subscribers[ch]; ok {
+		delete(e.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (f SeatEventFilter) matches(event SeatEvent) bool {
+	if f.FloorID != nil && (event.FloorID == nil || *event.FloorID != *f.FloorID) {
+		return false
+	}
+	if f.ZoneID != nil && (event.ZoneID == nil || *event.ZoneID != *f.ZoneID) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,244 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeatEventFilter_matches(t *testing.T) {
+	floor, zone, other := uint(1), uint(2), uint(3)
+	tests := []struct {
+		name   string
+		filter SeatEventFilter
+		event  SeatEvent
+		want   bool
+	}{
+		{
+			name:   "no filter",
+			filter: SeatEventFilter{},
+			event:  SeatEvent{},
+			want:   true,
+		},
+		{
+			name:   "same floor",
+			filter: SeatEventFilter{FloorID: &floor},
+			event:  SeatEvent{FloorID: &floor, ZoneID: &zone},
+			want:   true,
+		},
+		{
+			name:   "other zone",
+			filter: SeatEventFilter{ZoneID: &other},
+			event:  SeatEvent{FloorID: &floor, ZoneID: &zone},
+			want:   false,
+		},
+		{
+			name:   "seat without location",
+			filter: SeatEventFilter{FloorID: &floor},
+			event:  SeatEvent{},
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.matches(tt.event))
+		})
+	}
+}
+
+func TestSeatEvents_SlowSubscriber(t *testing.T) {
+	s := NewMemoryStorage()
+	seat := &Seat{Number: "A1"}
+	require.NoError(t, s.CreateSeat(seat))
+	events := NewSeatEvents(s, s)
+
+	slow, _ := events.Subscribe(SeatEventFilter{})
+	fast, unsubscribe := events.Subscribe(SeatEventFilter{})
+	defer unsubscribe()
+	for i := 0; i <= seatEventBuffer; i++ {
+		events.Publish(Booking{ID: i, SeatID: seat.ID, Status: BookingStatusBooked})
+		<-fast
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	assert.Equal(t, seatEventBuffer, received, "the slow subscriber is closed once its buffer is full")
+
+	events.Close()
+	_, ok := <-fast
+	assert.False(t, ok, "Close ends the subscriptions")
+	closed, _ := events.Subscribe(SeatEventFilter{})
+	_, ok = <-closed
+	assert.False(t, ok)
+}
+
+// readSeatEvents open the stream at url and send the seat events it reads to the channel
+func readSeatEvents(t *testing.T, ctx context.Context, url string) <-chan SeatEvent {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan SeatEvent)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+			var event SeatEvent
+			if json.Unmarshal([]byte(data), &event) == nil {
+				events <- event
+			}
+		}
+	}()
+	return events
+}
+
+func nextSeatEvent(t *testing.T, events <-chan SeatEvent) SeatEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no seat event received")
+		return SeatEvent{}
+	}
+}
+
+func TestSeatEventsStream(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			srv   = httptest.NewServer(newTestRouter(s))
+			users = createTestUsers(t, s, 2)
+			from  = time.Now().In(dateutil.LocVN).Add(24 * time.Hour).Truncate(time.Hour)
+		)
+		defer srv.Close()
+
+		building := &Building{Name: "HQ"}
+		require.NoError(t, s.CreateBuilding(building))
+		floor := &Floor{BuildingID: building.ID, Name: "Ground"}
+		require.NoError(t, s.CreateFloor(floor))
+		north, south := &Zone{FloorID: floor.ID, Name: "North"}, &Zone{FloorID: floor.ID, Name: "South"}
+		require.NoError(t, s.CreateZone(north))
+		require.NoError(t, s.CreateZone(south))
+		require.NoError(t, s.CreateSeat(&Seat{Number: "N1", ZoneID: &north.ID}))
+		require.NoError(t, s.CreateSeat(&Seat{Number: "S1", ZoneID: &south.ID}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events := readSeatEvents(t, ctx, fmt.Sprintf("%s/seats/events?zone_id=%d", srv.URL, north.ID))
+
+		require.Equal(t, http.StatusOK, bookSeat(srv.Config.Handler, users[0], "S1", from, from.Add(time.Hour)))
+		require.Equal(t, http.StatusOK, bookSeat(srv.Config.Handler, users[1], "N1", from, from.Add(time.Hour)))
+
+		event := nextSeatEvent(t, events)
+		assert.Equal(t, BookingStatusBooked, event.Type, "the booking of the other zone is not sent")
+		assert.Equal(t, "N1", event.SeatNumber)
+		require.NotNil(t, event.FloorID)
+		assert.Equal(t, floor.ID, *event.FloorID)
+
+		w := serveJSON(srv.Config.Handler, http.MethodDelete, fmt.Sprintf("/bookings/%d", event.BookingID), users[1], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, BookingStatusCancelled, nextSeatEvent(t, events).Type)
+	})
+}
+
+func TestSeatEventsStream_FreedSeats(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			srv   = httptest.NewServer(newTestRouter(s))
+			users = createTestUsers(t, s, 1)
+			from  = time.Now().In(dateutil.LocVN).Add(24 * time.Hour).Truncate(time.Hour)
+			to    = from.Add(time.Hour)
+		)
+		defer srv.Close()
+		require.NoError(t, s.CreateSeat(&Seat{Number: "A1"}))
+		require.NoError(t, s.CreateSeat(&Seat{Number: "B1"}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events := readSeatEvents(t, ctx, srv.URL+"/seats/events")
+
+		// moving a booking frees the seat it left
+		require.Equal(t, http.StatusOK, bookSeat(srv.Config.Handler, users[0], "A1", from, to))
+		booked := nextSeatEvent(t, events)
+		w := serveJSON(srv.Config.Handler, http.MethodPatch, fmt.Sprintf("/bookings/%d", booked.BookingID), users[0], gin.H{"seat_number": "B1"})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		moved := nextSeatEvent(t, events)
+		assert.Equal(t, BookingStatusModified, moved.Type)
+		assert.Equal(t, "B1", moved.SeatNumber)
+		freed := nextSeatEvent(t, events)
+		assert.Equal(t, SeatEventFreed, freed.Type)
+		assert.Equal(t, "A1", freed.SeatNumber)
+		assert.Equal(t, booked.BookingID, freed.BookingID)
+		assert.True(t, freed.StartTime.Equal(from))
+
+		// cancelling a series frees the seat of every cancelled occurrence
+		request := gin.H{
+			"seat_number": "A1",
+			"from_time":   from.AddDate(0, 0, 1).Format(timeFormat),
+			"to_time":     to.AddDate(0, 0, 1).Format(timeFormat),
+			"rrule":       "FREQ=DAILY;COUNT=2",
+		}
+		w = serveJSON(srv.Config.Handler, http.MethodPost, "/book-seat/recurring", users[0], request)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var series struct {
+			SeriesID uint `json:"series_id"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, BookingStatusBooked, nextSeatEvent(t, events).Type)
+		}
+
+		w = serveJSON(srv.Config.Handler, http.MethodDelete, fmt.Sprintf("/booking-series/%d", series.SeriesID), users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		for i := 0; i < 2; i++ {
+			event := nextSeatEvent(t, events)
+			assert.Equal(t, BookingStatusCancelled, event.Type)
+			assert.Equal(t, "A1", event.SeatNumber)
+			assert.True(t, event.StartTime.Equal(from.AddDate(0, 0, i+1)))
+		}
+	})
+}
+
+func TestCheckinService_ReleaseBookingPublish(t *testing.T) {
+	s := NewMemoryStorage()
+	seat := &Seat{Number: "A1"}
+	require.NoError(t, s.CreateSeat(seat))
+	booking := &Booking{UserID: 1, SeatID: seat.ID, StartTime: time.Now().Add(-time.Hour), EndTime: time.Now().Add(time.Hour)}
+	require.NoError(t, s.CreateBookingIfAvailable(booking))
+
+	events := NewSeatEvents(s, s)
+	received, unsubscribe := events.Subscribe(SeatEventFilter{})
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	notifier := NewNotifier(s, s, DefaultNotificationConfig())
+	waitlist := NewWaitlist(s, s, DefaultWaitlistConfig(), notifier)
+	NewCheckInService(s, testJWTSecret, DefaultReleaseConfig(), events, waitlist, notifier, NewJobRunner(s, DefaultJobConfig())).ReleaseBooking(ctx)
+
+	event := nextSeatEvent(t, received)
+	assert.Equal(t, BookingStatusReleased, event.Type)
+	assert.Equal(t, booking.ID, event.BookingID)
+}
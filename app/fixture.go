@@ -0,0 +1,221 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+type (
+	// Fixtures are the records loaded by cmd/seed. Buildings are matched by name, floors
+	// by building and name, zones by floor and name, seats by number and users by email
+	Fixtures struct {
+		Buildings []BuildingFixture `mapstructure:"buildings"`
+		Seats     []SeatFixture     `mapstructure:"seats"`
+		Users     []UserFixture     `mapstructure:"users"`
+	}
+
+	BuildingFixture struct {
+		Name   string         `mapstructure:"name"`
+		Floors []FloorFixture `mapstructure:"floors"`
+	}
+
+	FloorFixture struct {
+		Name  string   `mapstructure:"name"`
+		Level int      `mapstructure:"level"`
+		Zones []string `mapstructure:"zones"`
+	}
+
+	// SeatFixture place the seat in the zone named by Building, Floor and Zone, a seat
+	// without Zone has no location
+	SeatFixture struct {
+		Number      string `mapstructure:"number"`
+		Building    string `mapstructure:"building"`
+		Floor       string `mapstructure:"floor"`
+		Zone        string `mapstructure:"zone"`
+		Type        string `mapstructure:"type"`
+		DualMonitor bool   `mapstructure:"dual_monitor"`
+		NearWindow  bool   `mapstructure:"near_window"`
+		Accessible  bool   `mapstructure:"accessible"`
+	}
+
+	// UserFixture is a user account, the password is only used when the user is created.
+	// PasswordEnv names the environment variable holding it instead of Password, so that
+	// the fixture files do not hold the password of an admin
+	UserFixture struct {
+		Email       string `mapstructure:"email"`
+		Name        string `mapstructure:"name"`
+		Role        string `mapstructure:"role"`
+		Password    string `mapstructure:"password"`
+		PasswordEnv string `mapstructure:"password_env"`
+	}
+)
+
+// LoadFixtures read and merge the fixture files. YAML files hold any of the
+// buildings, seats and users lists. A CSV or XLSX file holds one kind of record, told
+// by its header: floors (building,floor,level,zone), seats (number,...) or users (email,...)
+func LoadFixtures(paths ...string) (Fixtures, error) {
+	var fixtures Fixtures
+	for _, path := range paths {
+		var (
+			loaded Fixtures
+			err    error
+		)
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			loaded, err = loadYAMLFixtures(path)
+		default:
+			var format string
+			if format, err = SpreadsheetFormat(path); err == nil {
+				loaded, err = loadSpreadsheetFixtures(path, format)
+			}
+		}
+		if err != nil {
+			return Fixtures{}, fmt.Errorf("%s: %w", path, err)
+		}
+		fixtures.merge(loaded)
+	}
+	return fixtures, nil
+}
+
+func loadYAMLFixtures(path string) (Fixtures, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return Fixtures{}, err
+	}
+
+	var fixtures Fixtures
+	if err := v.Unmarshal(&fixtures); err != nil {
+		return Fixtures{}, err
+	}
+	return fixtures, nil
+}
+
+func loadSpreadsheetFixtures(path, format string) (Fixtures, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Fixtures{}, err
+	}
+	defer f.Close()
+
+	rows, err := readSpreadsheet(f, format)
+	if err != nil {
+		return Fixtures{}, err
+	}
+	if len(rows) == 0 {
+		return Fixtures{}, errors.New("missing header")
+	}
+
+	var (
+		fixtures Fixtures
+		columns  = sheetColumns(rows[0])
+	)
+	for i, record := range rows[1:] {
+		row := sheetRow{columns: columns, record: record}
+		if row.empty() {
+			continue
+		}
+		switch {
+		case row.has("level"):
+			level, err := row.int("level")
+			if err != nil {
+				return Fixtures{}, fmt.Errorf("row %d: %w", i+2, err)
+			}
+			floor := FloorFixture{Name: row.get("floor"), Level: level}
+			if zone := row.get("zone"); zone != "" {
+				floor.Zones = []string{zone}
+			}
+			fixtures.merge(Fixtures{Buildings: []BuildingFixture{{Name: row.get("building"), Floors: []FloorFixture{floor}}}})
+		case row.has("number"):
+			seat, err := parseSeatRow(row)
+			if err != nil {
+				return Fixtures{}, fmt.Errorf("row %d: %w", i+2, err)
+			}
+			fixtures.Seats = append(fixtures.Seats, seat)
+		case row.has("email"):
+			fixtures.Users = append(fixtures.Users, UserFixture{
+				Email:       row.get("email"),
+				Name:        row.get("name"),
+				Role:        row.get("role"),
+				Password:    row.get("password"),
+				PasswordEnv: row.get("password_env"),
+			})
+		default:
+			return Fixtures{}, fmt.Errorf("unknown header %v", rows[0])
+		}
+	}
+	return fixtures, nil
+}
+
+// parseSeatRow read a seat from a row with the columns of fixtures/seats.csv
+func parseSeatRow(row sheetRow) (SeatFixture, error) {
+	seat := SeatFixture{
+		Number:   row.get("number"),
+		Building: row.get("building"),
+		Floor:    row.get("floor"),
+		Zone:     row.get("zone"),
+		Type:     row.get("type"),
+	}
+	var err error
+	if seat.DualMonitor, err = row.bool("dual_monitor"); err != nil {
+		return SeatFixture{}, err
+	}
+	if seat.NearWindow, err = row.bool("near_window"); err != nil {
+		return SeatFixture{}, err
+	}
+	if seat.Accessible, err = row.bool("accessible"); err != nil {
+		return SeatFixture{}, err
+	}
+	return seat, nil
+}
+
+// merge add the records of other, buildings and floors with the same name are merged
+func (f *Fixtures) merge(other Fixtures) {
+	for _, building := range other.Buildings {
+		i := 0
+		for i < len(f.Buildings) && f.Buildings[i].Name != building.Name {
+			i++
+		}
+		if i == len(f.Buildings) {
+			f.Buildings = append(f.Buildings, BuildingFixture{Name: building.Name})
+		}
+
+		for _, floor := range building.Floors {
+			floors := f.Buildings[i].Floors
+			j := 0
+			for j < len(floors) && floors[j].Name != floor.Name {
+				j++
+			}
+			if j == len(floors) {
+				f.Buildings[i].Floors = append(floors, floor)
+				continue
+			}
+			// the level is checked by Validate, a different one is kept so it is reported
+			if floors[j].Level != floor.Level {
+				f.Buildings[i].Floors = append(floors, floor)
+				continue
+			}
+			for _, zone := range floor.Zones {
+				if !containsString(floors[j].Zones, zone) {
+					floors[j].Zones = append(floors[j].Zones, zone)
+				}
+			}
+		}
+	}
+	f.Seats = append(f.Seats, other.Seats...)
+	f.Users = append(f.Users, other.Users...)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
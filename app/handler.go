@@ -1,33 +1,144 @@
 package app
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"code-challenge-backend/pkg/dateutil"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 const (
 	timeFormat = "2006-01-02 15:04"
+
+	maxFailedLoginAttempts = 5
+	loginLockoutDuration   = 15 * time.Minute
 )
 
 type (
 	Handler struct {
-		ds *DataStorage
+		ds        Storage
+		jwtSecret string
+		events    *SeatEvents
+		waitlist  *Waitlist
+		notifier  *Notifier
 	}
 )
 
-func NewHandler(ds *DataStorage) *Handler {
+func NewHandler(ds Storage, jwtSecret string, events *SeatEvents, waitlist *Waitlist, notifier *Notifier) *Handler {
 	return &Handler{
-		ds: ds,
+		ds:        ds,
+		jwtSecret: jwtSecret,
+		events:    events,
+		waitlist:  waitlist,
+		notifier:  notifier,
+	}
+}
+
+func (h *Handler) Register(c *gin.Context) {
+	var request RegisterRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	_, err := h.ds.GetUserByEmail(request.Email)
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return
+	}
+
+	passwordHash, err := hashPassword(request.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register"})
+		return
+	}
+
+	user := &User{
+		Email:        request.Email,
+		Name:         request.Name,
+		Role:         RoleEmployee,
+		PasswordHash: passwordHash,
+	}
+
+	if err := h.ds.Create(user); err != nil {
+		// another registration of the email won the race since it was checked
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register"})
+		return
 	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Registration successful", "user": user})
 }
 
 func (h *Handler) Login(c *gin.Context) {
+	var request LoginRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, err := h.ds.GetUserByEmail(request.Email)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+			return
+		}
+		checkPassword("", request.Password)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+
+	now := time.Now()
+	if user.LockedUntil != nil && user.LockedUntil.After(now) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Account is locked, try again later"})
+		return
+	}
+
+	if !checkPassword(user.PasswordHash, request.Password) {
+		if err := h.ds.RecordFailedLogin(user.ID, maxFailedLoginAttempts, now.Add(loginLockoutDuration)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record login attempt"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := h.ds.ResetFailedLogins(user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record login attempt"})
+			return
+		}
+	}
+
+	accessToken, refreshToken, err := issueTokenPair(h.jwtSecret, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Login successful",
+		"user":          user,
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	})
+}
+
+func (h *Handler) RefreshToken(c *gin.Context) {
 	var request struct {
-		Email string `json:"email"`
-		Name  string `json:"name"`
+		RefreshToken string `json:"refresh_token" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -35,24 +146,35 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	user := &User{
-		Email: request.Email,
-		Name:  request.Name,
+	subject, err := parseToken(h.jwtSecret, request.RefreshToken, tokenTypeRefresh)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	user, err := h.ds.GetUserByID(subject.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
 	}
 
-	err := h.ds.Upsert(user)
+	accessToken, refreshToken, err := issueTokenPair(h.jwtSecret, user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Login successful", "user": user})
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	})
 }
 
 func (h *Handler) ListAvailableSeats(c *gin.Context) {
 	var request struct {
 		FromTime time.Time `json:"from_time" binding:"required"`
 		ToTime   time.Time `json:"to_time" binding:"required"`
+		SeatFilter
 	}
 	err := c.ShouldBindBodyWithJSON(&request)
 	if err != nil {
@@ -60,7 +182,7 @@ func (h *Handler) ListAvailableSeats(c *gin.Context) {
 		return
 	}
 
-	seats, err := h.ds.FindAvailableSeats(request.FromTime, request.ToTime)
+	seats, err := h.ds.FindAvailableSeats(request.FromTime, request.ToTime, request.SeatFilter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
@@ -69,10 +191,45 @@ func (h *Handler) ListAvailableSeats(c *gin.Context) {
 	c.JSON(http.StatusOK, seats)
 }
 
+// SeatAvailability return the free, booked and checked in slots of every seat matching
+// the seat filter for ?date, the slots last ?slot_minutes (30 by default)
+func (h *Handler) SeatAvailability(c *gin.Context) {
+	var request struct {
+		Date        string `form:"date" binding:"required"`
+		SlotMinutes int    `form:"slot_minutes"`
+		SeatFilter
+	}
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	date, err := parseDateParam("date", request.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slotSize := DefaultSlotSize
+	if request.SlotMinutes != 0 {
+		slotSize = time.Duration(request.SlotMinutes) * time.Minute
+	}
+
+	grid, err := BuildAvailabilityGrid(h.ds, date, slotSize, request.SeatFilter)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSlotSize) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, grid)
+}
+
 func (h *Handler) BookSeat(c *gin.Context) {
 	var request struct {
 		SeatNumber string `json:"seat_number"`
-		UserEmail  string `json:"user_email"`
 		FromTime   string `json:"from_time"`
 		ToTime     string `json:"to_time"`
 	}
@@ -82,7 +239,7 @@ func (h *Handler) BookSeat(c *gin.Context) {
 		return
 	}
 
-	user, err := h.ds.GetUserByEmail(request.UserEmail)
+	user, err := h.ds.GetUserByID(userIDFromContext(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -106,7 +263,7 @@ func (h *Handler) BookSeat(c *gin.Context) {
 		return
 	}
 
-	if fromTime.After(toTime) {
+	if !fromTime.Before(toTime) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time range"})
 		return
 	}
@@ -116,26 +273,6 @@ func (h *Handler) BookSeat(c *gin.Context) {
 		return
 	}
 
-	userBookings, err := h.ds.FindOverlapBookingsByUserID(user.ID, fromTime, toTime)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user bookings"})
-		return
-	}
-	if len(userBookings) > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User already has a booking"})
-		return
-	}
-
-	overlapBookings, err := h.ds.FindOverlapBookingsBySeatID(seat.ID, fromTime, toTime)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
-		return
-	}
-	if len(overlapBookings) > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Seat already booked on that duration"})
-		return
-	}
-
 	booking := &Booking{
 		UserID:    user.ID,
 		SeatID:    seat.ID,
@@ -143,10 +280,11 @@ func (h *Handler) BookSeat(c *gin.Context) {
 		EndTime:   toTime,
 	}
 
-	if err := h.ds.CreateBooking(booking); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to book seat"})
+	if err := h.ds.CreateBookingIfAvailable(booking); err != nil {
+		writeBookingConflictError(c, err, "Failed to book seat")
 		return
 	}
+	h.events.Publish(*booking)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Seat booked successfully",
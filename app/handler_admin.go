@@ -0,0 +1,263 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (h *Handler) ListSeats(c *gin.Context) {
+	includeDeleted, _ := strconv.ParseBool(c.Query("include_deleted"))
+
+	seats, err := h.ds.ListSeats(includeDeleted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, seats)
+}
+
+func (h *Handler) CreateSeat(c *gin.Context) {
+	var request SeatRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if !h.isSeatNumberAvailable(c, request.Number, 0) || !h.isValidSeatRequest(c, &request) {
+		return
+	}
+
+	seat := &Seat{}
+	request.applyTo(seat)
+	if err := h.ds.CreateSeat(seat); err != nil {
+		writeSeatWriteError(c, err, "Failed to create seat")
+		return
+	}
+
+	c.JSON(http.StatusCreated, seat)
+}
+
+func (h *Handler) UpdateSeat(c *gin.Context) {
+	seat, ok := h.seatFromParam(c)
+	if !ok {
+		return
+	}
+
+	var request SeatRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if !h.isSeatNumberAvailable(c, request.Number, seat.ID) || !h.isValidSeatRequest(c, &request) {
+		return
+	}
+
+	request.applyTo(seat)
+	if err := h.ds.UpdateSeat(seat); err != nil {
+		writeSeatWriteError(c, err, "Failed to update seat")
+		return
+	}
+
+	c.JSON(http.StatusOK, seat)
+}
+
+func (h *Handler) DeleteSeat(c *gin.Context) {
+	seat, ok := h.seatFromParam(c)
+	if !ok {
+		return
+	}
+
+	upcoming, err := h.ds.CountUpcomingBookingsBySeatID(seat.ID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+	if upcoming > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Seat has upcoming bookings"})
+		return
+	}
+
+	if err := h.ds.DeleteSeat(seat.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete seat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Seat deleted successfully"})
+}
+
+func (h *Handler) RestoreSeat(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid seat id"})
+		return
+	}
+
+	seat, err := h.ds.GetSeatByID(uint(id), true)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Seat not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve seat"})
+		}
+		return
+	}
+
+	if !h.isSeatNumberAvailable(c, seat.Number, seat.ID) {
+		return
+	}
+
+	if err := h.ds.RestoreSeat(seat.ID); err != nil {
+		writeSeatWriteError(c, err, "Failed to restore seat")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Seat restored successfully"})
+}
+
+func (h *Handler) UpdateUserRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	var request UpdateRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil || !IsValidRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, err := h.ds.GetUserByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		}
+		return
+	}
+
+	if err := h.ds.UpdateUserRole(user.ID, request.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully"})
+}
+
+// SeatQRCode return the QR code of the seat's check-in token as a ?format=png|svg image
+// of ?size pixels, PNG of 256 pixels by default
+func (h *Handler) SeatQRCode(c *gin.Context) {
+	seat, ok := h.seatFromParam(c)
+	if !ok {
+		return
+	}
+
+	size := defaultQRSize
+	if value := c.Query("size"); value != "" {
+		var err error
+		if size, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidQRSize.Error()})
+			return
+		}
+	}
+
+	token, err := issueSeatToken(h.jwtSecret, seat.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign seat code"})
+		return
+	}
+
+	format := c.DefaultQuery("format", QRFormatPNG)
+	image, contentType, err := encodeQR(token, format, size)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedQRFormat) || errors.Is(err, ErrInvalidQRSize) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="seat-%s.%s"`, seat.Number, format))
+	c.Data(http.StatusOK, contentType, image)
+}
+
+// seatFromParam load the active seat identified by the :id path parameter, it writes the error response when not found
+func (h *Handler) seatFromParam(c *gin.Context) (*Seat, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid seat id"})
+		return nil, false
+	}
+
+	seat, err := h.ds.GetSeatByID(uint(id), false)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Seat not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve seat"})
+		}
+		return nil, false
+	}
+	return seat, true
+}
+
+// writeSeatWriteError write the response of a failed seat write, the number taken by a
+// concurrent write is reported as a conflict and anything else as an internal error
+func writeSeatWriteError(c *gin.Context, err error, message string) {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Seat number already exists"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// isSeatNumberAvailable check no other active seat uses number, it writes the error response when taken
+func (h *Handler) isSeatNumberAvailable(c *gin.Context, number string, seatID uint) bool {
+	existing, err := h.ds.GetSeatByNumber(number)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve seat"})
+		return false
+	}
+	if existing.ID == seatID {
+		return true
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "Seat number already exists"})
+	return false
+}
+
+// isValidSeatRequest check the seat type and zone, it writes the error response when invalid
+func (h *Handler) isValidSeatRequest(c *gin.Context, request *SeatRequest) bool {
+	if request.Type == "" {
+		request.Type = SeatTypeDesk
+	}
+	if !IsValidSeatType(request.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid seat type"})
+		return false
+	}
+
+	if request.ZoneID == nil {
+		return true
+	}
+	if _, err := h.ds.GetZoneByID(*request.ZoneID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Zone not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve zone"})
+		}
+		return false
+	}
+	return true
+}
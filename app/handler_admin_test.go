@@ -0,0 +1,265 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminRoutes_Roles(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r                       = newTestRouter(s)
+			employee, employeeToken = createTestUser(t, s, "employee@example.com", RoleEmployee)
+			_, facilityToken        = createTestUser(t, s, "facility@example.com", RoleFacilityAdmin)
+			_, superToken           = createTestUser(t, s, "super@example.com", RoleSuperAdmin)
+			rolePath                = fmt.Sprintf("/admin/users/%d/role", employee.ID)
+		)
+		tests := []struct {
+			name      string
+			token     string
+			wantSeats int
+			wantRole  int
+		}{
+			{name: "anonymous", token: "", wantSeats: http.StatusUnauthorized, wantRole: http.StatusUnauthorized},
+			{name: "employee", token: employeeToken, wantSeats: http.StatusForbidden, wantRole: http.StatusForbidden},
+			{name: "facility admin", token: facilityToken, wantSeats: http.StatusOK, wantRole: http.StatusForbidden},
+			{name: "super admin", token: superToken, wantSeats: http.StatusOK, wantRole: http.StatusOK},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveJSON(r, http.MethodGet, "/admin/seats", tt.token, nil)
+				assert.Equal(t, tt.wantSeats, w.Code, w.Body.String())
+				w = serveJSON(r, http.MethodPut, rolePath, tt.token, gin.H{"role": RoleEmployee})
+				assert.Equal(t, tt.wantRole, w.Code, w.Body.String())
+			})
+		}
+	})
+}
+
+func TestRequireRole_Demoted(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		r := newTestRouter(s)
+		user, token := createTestUser(t, s, "admin@example.com", RoleFacilityAdmin)
+
+		w := serveJSON(r, http.MethodGet, "/admin/seats", token, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		// the token still claims facility_admin until it expires
+		require.NoError(t, s.UpdateUserRole(user.ID, RoleEmployee))
+		w = serveJSON(r, http.MethodGet, "/admin/seats", token, nil)
+		require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	})
+}
+
+func TestAdminSeats(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r           = newTestRouter(s)
+			_, token    = createTestUser(t, s, "admin@example.com", RoleFacilityAdmin)
+			employee, _ = createTestUser(t, s, "employee@example.com", RoleEmployee)
+		)
+		seatPath := func(id uint, suffix string) string {
+			return fmt.Sprintf("/admin/seats/%d%s", id, suffix)
+		}
+		listSeats := func(query string) []Seat {
+			w := serveJSON(r, http.MethodGet, "/admin/seats"+query, token, nil)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var seats []Seat
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &seats))
+			return seats
+		}
+
+		w := serveJSON(r, http.MethodPost, "/admin/seats", token, gin.H{"number": "A1", "type": SeatTypeBooth, "near_window": true})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var seat Seat
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &seat))
+		assert.Equal(t, SeatTypeBooth, seat.Type)
+		assert.True(t, seat.NearWindow)
+
+		type args struct {
+			method string
+			path   string
+			body   interface{}
+		}
+		tests := []struct {
+			name      string
+			args      args
+			want      int
+			wantError string
+		}{
+			{
+				name:      "create with a taken number",
+				args:      args{method: http.MethodPost, path: "/admin/seats", body: gin.H{"number": "A1"}},
+				want:      http.StatusConflict,
+				wantError: "Seat number already exists",
+			},
+			{
+				name:      "create without number",
+				args:      args{method: http.MethodPost, path: "/admin/seats", body: gin.H{"type": SeatTypeDesk}},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid request",
+			},
+			{
+				name:      "create with an unknown type",
+				args:      args{method: http.MethodPost, path: "/admin/seats", body: gin.H{"number": "A2", "type": "sofa"}},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid seat type",
+			},
+			{
+				name:      "create in an unknown zone",
+				args:      args{method: http.MethodPost, path: "/admin/seats", body: gin.H{"number": "A2", "zone_id": 999}},
+				want:      http.StatusBadRequest,
+				wantError: "Zone not found",
+			},
+			{
+				name:      "update an unknown seat",
+				args:      args{method: http.MethodPut, path: seatPath(999, ""), body: gin.H{"number": "A2"}},
+				want:      http.StatusNotFound,
+				wantError: "Seat not found",
+			},
+			{
+				name:      "update with an invalid id",
+				args:      args{method: http.MethodPut, path: "/admin/seats/abc", body: gin.H{"number": "A2"}},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid seat id",
+			},
+			{
+				name:      "restore an unknown seat",
+				args:      args{method: http.MethodPost, path: seatPath(999, "/restore")},
+				want:      http.StatusNotFound,
+				wantError: "Seat not found",
+			},
+			{
+				name: "update",
+				args: args{method: http.MethodPut, path: seatPath(seat.ID, ""), body: gin.H{"number": "A1", "dual_monitor": true}},
+				want: http.StatusOK,
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveJSON(r, tt.args.method, tt.args.path, token, tt.args.body)
+				require.Equal(t, tt.want, w.Code, w.Body.String())
+				if tt.wantError != "" {
+					assert.Equal(t, tt.wantError, decodeError(t, w))
+				}
+			})
+		}
+
+		got, err := s.GetSeatByID(seat.ID, false)
+		require.NoError(t, err)
+		assert.Equal(t, SeatTypeDesk, got.Type, "an update replaces every attribute")
+		assert.True(t, got.DualMonitor)
+		assert.False(t, got.NearWindow)
+
+		// a seat with an upcoming booking can only be deleted once it is cancelled
+		from := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+		booking := &Booking{UserID: employee.ID, SeatID: seat.ID, StartTime: from, EndTime: from.Add(time.Hour)}
+		require.NoError(t, s.CreateBookingIfAvailable(booking))
+		w = serveJSON(r, http.MethodDelete, seatPath(seat.ID, ""), token, nil)
+		require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+		assert.Equal(t, "Seat has upcoming bookings", decodeError(t, w))
+		require.NoError(t, s.UpdateBookingStatus(booking, BookingStatusCancelled))
+		w = serveJSON(r, http.MethodDelete, seatPath(seat.ID, ""), token, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = serveJSON(r, http.MethodDelete, seatPath(seat.ID, ""), token, nil)
+		require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+		assert.Empty(t, listSeats(""))
+		require.Len(t, listSeats("?include_deleted=true"), 1)
+
+		// the number of a deleted seat can be reused, the seat is then restored under another one
+		w = serveJSON(r, http.MethodPost, "/admin/seats", token, gin.H{"number": "A1"})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var reused Seat
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reused))
+		w = serveJSON(r, http.MethodPost, seatPath(seat.ID, "/restore"), token, nil)
+		require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+		assert.Equal(t, "Seat number already exists", decodeError(t, w))
+
+		w = serveJSON(r, http.MethodPut, seatPath(reused.ID, ""), token, gin.H{"number": "A2"})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = serveJSON(r, http.MethodPost, seatPath(seat.ID, "/restore"), token, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Len(t, listSeats(""), 2)
+	})
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r                       = newTestRouter(s)
+			employee, employeeToken = createTestUser(t, s, "employee@example.com", RoleEmployee)
+			_, facilityToken        = createTestUser(t, s, "facility@example.com", RoleFacilityAdmin)
+			_, superToken           = createTestUser(t, s, "super@example.com", RoleSuperAdmin)
+			rolePath                = fmt.Sprintf("/admin/users/%d/role", employee.ID)
+		)
+		role := func() string {
+			user, err := s.GetUserByID(employee.ID)
+			require.NoError(t, err)
+			return user.Role
+		}
+
+		// a facility admin cannot hand out roles, least of all super_admin
+		w := serveJSON(r, http.MethodPut, rolePath, facilityToken, gin.H{"role": RoleSuperAdmin})
+		require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+		assert.Equal(t, RoleEmployee, role())
+
+		type args struct {
+			path string
+			body interface{}
+		}
+		tests := []struct {
+			name      string
+			args      args
+			want      int
+			wantError string
+		}{
+			{
+				name:      "unknown role",
+				args:      args{path: rolePath, body: gin.H{"role": "owner"}},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid request",
+			},
+			{
+				name:      "invalid user id",
+				args:      args{path: "/admin/users/abc/role", body: gin.H{"role": RoleFacilityAdmin}},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid user id",
+			},
+			{
+				name:      "unknown user",
+				args:      args{path: "/admin/users/999/role", body: gin.H{"role": RoleFacilityAdmin}},
+				want:      http.StatusNotFound,
+				wantError: "User not found",
+			},
+			{
+				name: "promote",
+				args: args{path: rolePath, body: gin.H{"role": RoleFacilityAdmin}},
+				want: http.StatusOK,
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveJSON(r, http.MethodPut, tt.args.path, superToken, tt.args.body)
+				require.Equal(t, tt.want, w.Code, w.Body.String())
+				if tt.wantError != "" {
+					assert.Equal(t, tt.wantError, decodeError(t, w))
+				}
+			})
+		}
+
+		assert.Equal(t, RoleFacilityAdmin, role())
+		w = serveJSON(r, http.MethodGet, "/admin/seats", employeeToken, nil)
+		require.Equal(t, http.StatusOK, w.Code, "a promotion applies to the tokens already issued")
+	})
+}
@@ -0,0 +1,427 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+	"code-challenge-backend/pkg/recurrence"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	bookingConflictMessages = map[error]string{
+		ErrUserAlreadyBooked: "User already has a booking",
+		ErrSeatAlreadyBooked: "Seat already booked on that duration",
+	}
+)
+
+func (h *Handler) BookRecurringSeat(c *gin.Context) {
+	var request RecurringBookingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID := userIDFromContext(c)
+
+	seat, err := h.ds.GetSeatByNumber(request.SeatNumber)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Seat not found"})
+		return
+	}
+
+	fromTime, err := time.ParseInLocation(timeFormat, request.FromTime, dateutil.LocVN)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from_time format"})
+		return
+	}
+
+	toTime, err := time.ParseInLocation(timeFormat, request.ToTime, dateutil.LocVN)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to_time format"})
+		return
+	}
+
+	if !fromTime.Before(toTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time range"})
+		return
+	}
+
+	if fromTime.Before(time.Now().In(dateutil.LocVN)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from_time"})
+		return
+	}
+
+	rule, err := recurrence.Parse(request.RRule, dateutil.LocVN)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	starts, err := rule.Occurrences(fromTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var (
+		duration = toTime.Sub(fromTime)
+		series   = &BookingSeries{UserID: userID, SeatID: seat.ID, Rule: rule.String()}
+		bookings = make([]Booking, len(starts))
+		booked   []OccurrenceResult
+		skipped  []OccurrenceResult
+	)
+	for i, start := range starts {
+		bookings[i] = Booking{
+			UserID:    userID,
+			SeatID:    seat.ID,
+			StartTime: start,
+			EndTime:   start.Add(duration),
+		}
+	}
+
+	conflicts, err := h.ds.CreateBookingSeriesIfAvailable(series, bookings)
+	if err != nil && !errors.Is(err, ErrNoOccurrenceBooked) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to book seat"})
+		return
+	}
+
+	for i, start := range starts {
+		occurrence := OccurrenceResult{StartTime: start, EndTime: start.Add(duration)}
+		if conflicts[i] != nil {
+			occurrence.Reason = bookingConflictMessages[conflicts[i]]
+			skipped = append(skipped, occurrence)
+			continue
+		}
+		occurrence.BookingID = bookings[i].ID
+		booked = append(booked, occurrence)
+		h.events.Publish(bookings[i])
+	}
+
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "No occurrence could be booked", "skipped": skipped})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Seat booked successfully",
+		"series_id": series.ID,
+		"booked":    booked,
+		"skipped":   skipped,
+	})
+}
+
+// CancelBookingSeries cancel every occurrence of the series which has not started yet
+func (h *Handler) CancelBookingSeries(c *gin.Context) {
+	series, ok := h.seriesFromParam(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	cancelled, err := h.ds.CancelUpcomingBookingsBySeriesID(series.ID, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel bookings"})
+		return
+	}
+	for _, booking := range cancelled {
+		h.events.Publish(booking)
+		h.notifier.BookingCancelled(booking)
+		h.waitlist.SeatFreed(booking.SeatID, booking.StartTime, booking.EndTime, now)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bookings cancelled successfully", "cancelled": len(cancelled)})
+}
+
+// CancelBookingOccurrence cancel a single occurrence of the series
+func (h *Handler) CancelBookingOccurrence(c *gin.Context) {
+	series, ok := h.seriesFromParam(c)
+	if !ok {
+		return
+	}
+
+	bookingID, err := strconv.ParseUint(c.Param("booking_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking id"})
+		return
+	}
+
+	booking, err := h.ds.QueryBooking(uint(bookingID))
+	if err != nil || booking.SeriesID == nil || *booking.SeriesID != series.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	h.cancelBooking(c, booking)
+}
+
+// CancelBooking cancel a booking of the current user which has not ended yet
+func (h *Handler) CancelBooking(c *gin.Context) {
+	booking, ok := h.bookingFromParam(c)
+	if !ok {
+		return
+	}
+
+	h.cancelBooking(c, booking)
+}
+
+// UpdateBooking move a booking of the current user to another seat and/or time range
+func (h *Handler) UpdateBooking(c *gin.Context) {
+	booking, ok := h.bookingFromParam(c)
+	if !ok {
+		return
+	}
+
+	var request UpdateBookingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if !booking.IsActive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking is " + booking.Status})
+		return
+	}
+
+	if booking.CheckedIn || !booking.StartTime.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking has already started"})
+		return
+	}
+
+	previous := *booking
+	if request.SeatNumber != "" {
+		seat, err := h.ds.GetSeatByNumber(request.SeatNumber)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Seat not found"})
+			return
+		}
+		booking.SeatID = seat.ID
+	}
+
+	fromTime, toTime := booking.StartTime, booking.EndTime
+	if request.FromTime != "" {
+		t, err := time.ParseInLocation(timeFormat, request.FromTime, dateutil.LocVN)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from_time format"})
+			return
+		}
+		fromTime = t
+	}
+	if request.ToTime != "" {
+		t, err := time.ParseInLocation(timeFormat, request.ToTime, dateutil.LocVN)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to_time format"})
+			return
+		}
+		toTime = t
+	}
+
+	if !fromTime.Before(toTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time range"})
+		return
+	}
+
+	if fromTime.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from_time"})
+		return
+	}
+	booking.StartTime, booking.EndTime = fromTime, toTime
+
+	if err := h.ds.UpdateBookingIfAvailable(booking); err != nil {
+		writeBookingConflictError(c, err, "Failed to update booking")
+		return
+	}
+	h.events.Publish(*booking)
+	h.events.PublishFreed(previous)
+	// the time left of the previous seat and time range is offered to the waitlist
+	h.waitlist.SeatFreed(previous.SeatID, previous.StartTime, previous.EndTime, time.Now())
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking updated successfully", "booking": booking})
+}
+
+// CheckOutBooking end a checked in booking of the current user now so the seat is free
+// for the rest of its time range
+func (h *Handler) CheckOutBooking(c *gin.Context) {
+	booking, ok := h.bookingFromParam(c)
+	if !ok {
+		return
+	}
+
+	if booking.Status != BookingStatusCheckedIn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking is not checked in"})
+		return
+	}
+
+	now := time.Now()
+	if booking.EndTime.Before(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking has expired"})
+		return
+	}
+
+	bookedUntil := booking.EndTime
+	if err := h.ds.CheckOutBooking(booking, now); err != nil {
+		writeBookingStatusError(c, err, "Failed to check out")
+		return
+	}
+	h.events.Publish(*booking)
+	h.waitlist.SeatFreed(booking.SeatID, now, bookedUntil, now)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Check-out successful", "booking": booking})
+}
+
+// MyBookings list a page of the current user's bookings
+func (h *Handler) MyBookings(c *gin.Context) {
+	filter := c.Query("filter")
+	switch filter {
+	case BookingFilterAll, BookingFilterUpcoming, BookingFilterPast, BookingFilterCheckedIn:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filter"})
+		return
+	}
+
+	after, err := decodeBookingCursor(c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
+	limit := pageSize(c.Query("limit"))
+	views, err := h.ds.FindBookingsByUserID(userIDFromContext(c), filter, after, limit+1, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+
+	var nextCursor string
+	if len(views) > limit {
+		views = views[:limit]
+		last := views[len(views)-1]
+		nextCursor = bookingCursor{StartTime: last.StartTime, ID: last.ID}.encode()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bookings":    views,
+		"next_cursor": nextCursor,
+	})
+}
+
+// BookingHistory list the status changes of a booking of the current user
+func (h *Handler) BookingHistory(c *gin.Context) {
+	booking, ok := h.bookingFromParam(c)
+	if !ok {
+		return
+	}
+
+	history, err := h.ds.FindBookingHistory(booking.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve booking history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+func (h *Handler) cancelBooking(c *gin.Context, booking *Booking) {
+	if !booking.IsActive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking is " + booking.Status})
+		return
+	}
+
+	if booking.CheckedIn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking already checked in"})
+		return
+	}
+
+	if booking.EndTime.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking has expired"})
+		return
+	}
+
+	if err := h.ds.UpdateBookingStatus(booking, BookingStatusCancelled); err != nil {
+		writeBookingStatusError(c, err, "Failed to cancel booking")
+		return
+	}
+	h.events.Publish(*booking)
+	h.notifier.BookingCancelled(*booking)
+	h.waitlist.SeatFreed(booking.SeatID, booking.StartTime, booking.EndTime, time.Now())
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled successfully"})
+}
+
+// bookingFromParam load the booking identified by the :id path parameter and owned by the current user,
+// it writes the error response when not found
+func (h *Handler) bookingFromParam(c *gin.Context) (*Booking, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking id"})
+		return nil, false
+	}
+
+	booking, err := h.ds.QueryBooking(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve booking"})
+		}
+		return nil, false
+	}
+
+	if booking.UserID != userIDFromContext(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return nil, false
+	}
+	return booking, true
+}
+
+// writeBookingConflictError write the response of a failed booking write, conflicts are
+// reported as bad requests and anything else as writeBookingStatusError does
+func writeBookingConflictError(c *gin.Context, err error, message string) {
+	for conflictErr, conflict := range bookingConflictMessages {
+		if errors.Is(err, conflictErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": conflict})
+			return
+		}
+	}
+	writeBookingStatusError(c, err, message)
+}
+
+// writeBookingStatusError write the response of a failed status change, a booking released
+// or cancelled since it was read is reported as a conflict
+func writeBookingStatusError(c *gin.Context, err error, message string) {
+	if errors.Is(err, ErrBookingNotActive) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking is no longer active"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// seriesFromParam load the series identified by the :id path parameter and owned by the current user,
+// it writes the error response when not found
+func (h *Handler) seriesFromParam(c *gin.Context) (*BookingSeries, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series id"})
+		return nil, false
+	}
+
+	series, err := h.ds.GetBookingSeriesByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve series"})
+		}
+		return nil, false
+	}
+
+	if series.UserID != userIDFromContext(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return nil, false
+	}
+	return series, true
+}
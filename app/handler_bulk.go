@@ -0,0 +1,110 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxImportSize is the size of the largest spreadsheet ImportSeats accepts
+const maxImportSize = 10 << 20
+
+// exportWriteTimeout is how long a single write of an export may take, the deadline is
+// extended before every write so that large exports outlast the server WriteTimeout
+const exportWriteTimeout = time.Minute
+
+// deadlineWriter extend the write deadline of the response before every write
+type deadlineWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (w deadlineWriter) Write(p []byte) (int, error) {
+	_ = w.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	return w.w.Write(p)
+}
+
+// ImportSeats upsert the seats of the uploaded CSV or XLSX file, nothing is written
+// when a row is invalid or with ?dry_run=true
+func (h *Handler) ImportSeats(c *gin.Context) {
+	// the body is bounded before it is parsed, the rest of the form is far below 1 MiB
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize+1<<20)
+	file, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file"})
+		}
+		return
+	}
+	if file.Size > maxImportSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large"})
+		return
+	}
+
+	format, err := SpreadsheetFormat(file.Filename)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer f.Close()
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	result, err := ImportSeats(h.ds, f, format, dryRun)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSpreadsheet) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import seats"})
+		}
+		return
+	}
+
+	if len(result.Errors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rows", "errors": result.Errors})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportBookings stream the bookings as a CSV or XLSX file (?format), filtered by the
+// ?from and ?to dates, ?user_id and ?seat_number
+func (h *Handler) ExportBookings(c *gin.Context) {
+	format := c.DefaultQuery("format", SpreadsheetCSV)
+	contentType, ok := spreadsheetContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrUnsupportedSpreadsheet.Error()})
+		return
+	}
+
+	filter, err := ParseBookingExportFilter(c.Query("from"), c.Query("to"), c.Query("user_id"), c.Query("seat_number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="bookings.%s"`, format))
+	c.Status(http.StatusOK)
+	out := deadlineWriter{w: c.Writer, rc: http.NewResponseController(c.Writer)}
+	if err := ExportBookings(h.ds, out, format, filter); err != nil {
+		// the file has been partly sent already, the client gets a truncated file
+		log.WithError(err).Error("export bookings fail")
+		c.Abort()
+	}
+}
@@ -1,31 +1,44 @@
 package app
 
 import (
-	"database/sql"
+	"context"
 	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
+// qrCheckInEarly is how long before its start a booking can be checked in with a QR code
+const qrCheckInEarly = 15 * time.Minute
+
 type CheckinService struct {
-	ds        *DataStorage
+	ds        BookingRepository
 	jwtSecret string
+	release   ReleaseConfig
+	events    *SeatEvents
+	waitlist  *Waitlist
+	notifier  *Notifier
+	jobs      *JobRunner
 }
 
-func NewCheckInService(ds *DataStorage, jwtSecret string) *CheckinService {
+func NewCheckInService(ds BookingRepository, jwtSecret string, release ReleaseConfig, events *SeatEvents, waitlist *Waitlist, notifier *Notifier, jobs *JobRunner) *CheckinService {
 	return &CheckinService{
 		ds:        ds,
 		jwtSecret: jwtSecret,
+		release:   release,
+		events:    events,
+		waitlist:  waitlist,
+		notifier:  notifier,
+		jobs:      jobs,
 	}
 }
 
 func (h *CheckinService) CheckIn(c *gin.Context) {
 	var checkIn struct {
 		SeatID    uint `json:"seat_id"`
-		UserID    uint `json:"user_id"`
 		BookingID uint `json:"booking_id"`
 	}
 	if err := c.ShouldBindJSON(&checkIn); err != nil {
@@ -37,7 +50,7 @@ func (h *CheckinService) CheckIn(c *gin.Context) {
 
 	booking, err := h.ds.QueryBooking(checkIn.BookingID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve booking"})
@@ -49,39 +62,93 @@ func (h *CheckinService) CheckIn(c *gin.Context) {
 		return
 	}
 
-	if booking.UserID != checkIn.UserID {
+	if booking.UserID != userIDFromContext(c) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking does not match user"})
 		return
 	}
 
+	h.checkIn(c, booking)
+}
+
+// CheckInQR check the current user in with the token of the seat's QR code, the booking
+// is their active booking of the seat which has started or starts within qrCheckInEarly
+func (h *CheckinService) CheckInQR(c *gin.Context) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	seatID, err := parseSeatToken(h.jwtSecret, request.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid seat code"})
+		return
+	}
+
+	now := time.Now()
+	booking, err := h.ds.FindUserSeatBooking(userIDFromContext(c), seatID, now, now.Add(qrCheckInEarly))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No booking to check in for this seat"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve booking"})
+		}
+		return
+	}
+
+	h.checkIn(c, booking)
+}
+
+// checkIn check the booking in once it is known to belong to the current user
+func (h *CheckinService) checkIn(c *gin.Context, booking *Booking) {
 	if booking.CheckedIn {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking already checked in"})
 		return
 	}
 
+	if !booking.IsActive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking is " + booking.Status})
+		return
+	}
+
 	if booking.EndTime.Before(time.Now()) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking has expired"})
 		return
 	}
 
 	// Check in the booking
-	err = h.ds.ReseverBooking(booking)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in"})
+	if err := h.ds.ReseverBooking(booking); err != nil {
+		writeBookingStatusError(c, err, "Failed to check in")
 		return
 	}
+	h.events.Publish(*booking)
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Check-in successful"})
+	c.JSON(http.StatusCreated, gin.H{"message": "Check-in successful", "booking_id": booking.ID})
 }
 
-func (h *CheckinService) ReleaseBooking() {
-	log.Printf("start release booking")
-	for {
-		err := h.ds.ReleaseBooking()
-		if err != nil {
-			// Log the error
-			log.Printf("release fail")
-		}
-		time.Sleep(1 * time.Second)
+// ReleaseBooking release no-show bookings, and expire the waitlist offers not claimed in
+// time, every poll interval until ctx is cancelled. Only the instance holding the lease
+// of the job sweeps
+func (h *CheckinService) ReleaseBooking(ctx context.Context) {
+	h.jobs.Run(ctx, JobReleaseBooking, h.release.PollInterval, h.releaseBooking)
+}
+
+// releaseBooking sweep the no-show bookings once, the users of released bookings are
+// notified and their seats go to the waitlist
+func (h *CheckinService) releaseBooking(_ context.Context, now time.Time) (int, error) {
+	released, err := h.ds.ReleaseBooking(h.release, now)
+	if len(released) > 0 {
+		log.Infof("released %d bookings", len(released))
+	}
+	for _, booking := range released {
+		h.events.Publish(booking)
+		h.notifier.BookingReleased(booking)
+	}
+	h.waitlist.ExpireOffers(now)
+	for _, booking := range released {
+		h.waitlist.SeatFreed(booking.SeatID, booking.StartTime, booking.EndTime, now)
 	}
+	return len(released), err
 }
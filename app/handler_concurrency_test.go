@@ -0,0 +1,151 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookSeat_Concurrent(t *testing.T) {
+	forEachBackend(t, testBookSeatConcurrent)
+}
+
+func testBookSeatConcurrent(t *testing.T, ds *DataStorage) {
+	const requests = 300
+
+	var (
+		r      = newTestRouter(ds)
+		tokens = createTestUsers(t, ds, requests)
+		from   = time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+		to     = from.Add(2 * time.Hour)
+	)
+	require.NoError(t, ds.CreateSeat(&Seat{Number: "A1", Type: SeatTypeDesk}))
+
+	tests := []struct {
+		name  string
+		token func(i int) string
+		seat  func(i int) string
+	}{
+		{
+			name:  "many users book the same seat",
+			token: func(i int) string { return tokens[i] },
+			seat:  func(int) string { return "A1" },
+		},
+		{
+			name:  "one user books many seats",
+			token: func(int) string { return tokens[0] },
+			seat:  func(i int) string { return fmt.Sprintf("B%d", i) },
+		},
+	}
+	for i := 0; i < requests; i++ {
+		require.NoError(t, ds.CreateSeat(&Seat{Number: fmt.Sprintf("B%d", i), Type: SeatTypeDesk}))
+	}
+
+	for n, tt := range tests {
+		tt := tt
+		start, end := from.AddDate(0, 0, n), to.AddDate(0, 0, n)
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				wg    sync.WaitGroup
+				codes = make(chan int, requests)
+			)
+			for i := 0; i < requests; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					codes <- bookSeat(r, tt.token(i), tt.seat(i), start, end)
+				}(i)
+			}
+			wg.Wait()
+			close(codes)
+
+			counts := map[int]int{}
+			for code := range codes {
+				counts[code]++
+			}
+			assert.Equal(t, 1, counts[http.StatusOK], "status codes: %v", counts)
+			assert.Equal(t, requests-1, counts[http.StatusBadRequest], "status codes: %v", counts)
+
+			// every earlier sub test leaves exactly one booking too
+			var booked int64
+			err := ds.mysqlDB.Model(&Booking{}).Count(&booked).Error
+			require.NoError(t, err)
+			assert.Equal(t, int64(n+1), booked)
+		})
+	}
+}
+
+func TestRegister_Concurrent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ds *DataStorage) {
+		const requests = 20
+
+		var (
+			r     = newTestRouter(ds)
+			wg    sync.WaitGroup
+			codes = make(chan int, requests)
+		)
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w := serveJSON(r, http.MethodPost, "/register", "", gin.H{"email": "jane@example.com", "name": "Jane", "password": "password1"})
+				codes <- w.Code
+			}()
+		}
+		wg.Wait()
+		close(codes)
+
+		counts := map[int]int{}
+		for code := range codes {
+			counts[code]++
+		}
+		assert.Equal(t, 1, counts[http.StatusCreated], "status codes: %v", counts)
+		assert.Equal(t, requests-1, counts[http.StatusConflict], "status codes: %v", counts)
+	})
+}
+
+func TestRetryTransaction_InTransaction(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ds *DataStorage) {
+		var (
+			alice, _ = createTestUser(t, ds, "alice@example.com", RoleEmployee)
+			bob, _   = createTestUser(t, ds, "bob@example.com", RoleEmployee)
+			from     = time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+			to       = from.Add(time.Hour)
+		)
+		require.NoError(t, ds.CreateSeat(&Seat{Number: "A1", Type: SeatTypeDesk}))
+		require.NoError(t, ds.CreateSeat(&Seat{Number: "B1", Type: SeatTypeDesk}))
+		seats, err := ds.ListSeats(false)
+		require.NoError(t, err)
+		require.Len(t, seats, 2)
+
+		calls := 0
+		err = ds.WithTransaction(func(tx Storage) error {
+			// the booking writes run in a savepoint of this transaction
+			require.NoError(t, tx.CreateBookingIfAvailable(&Booking{UserID: alice.ID, SeatID: seats[0].ID, StartTime: from, EndTime: to}))
+			err := tx.CreateBookingIfAvailable(&Booking{UserID: bob.ID, SeatID: seats[0].ID, StartTime: from, EndTime: to})
+			require.ErrorIs(t, err, ErrSeatAlreadyBooked)
+			require.NoError(t, tx.CreateBookingIfAvailable(&Booking{UserID: bob.ID, SeatID: seats[1].ID, StartTime: from, EndTime: to}))
+
+			// a serialization failure aborts the outer transaction, it is not retried in a savepoint
+			err = tx.(*DataStorage).RetryTransaction(func(*DataStorage) error {
+				calls++
+				return &pgconn.PgError{Code: "40001"}
+			})
+			require.Error(t, err)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, calls)
+
+		var booked int64
+		require.NoError(t, ds.mysqlDB.Model(&Booking{}).Count(&booked).Error)
+		assert.Equal(t, int64(2), booked)
+	})
+}
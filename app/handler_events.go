@@ -0,0 +1,59 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// seatEventHeartbeat is how often an idle stream sends a comment, it keeps proxies from
+// closing the connection and pushes the write deadline of the server further
+const seatEventHeartbeat = 15 * time.Second
+
+// SeatEvents stream the seat events of ?floor_id and/or ?zone_id as server-sent events
+// named "seat". The stream ends when the client leaves or the server shuts down
+func (h *Handler) SeatEvents(c *gin.Context) {
+	var filter SeatEventFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	events, unsubscribe := h.events.Subscribe(filter)
+	defer unsubscribe()
+
+	// the server WriteTimeout would cut the stream, each write extends its own deadline
+	rc := http.NewResponseController(c.Writer)
+	extendDeadline := func() {
+		_ = rc.SetWriteDeadline(time.Now().Add(2 * seatEventHeartbeat))
+	}
+	extendDeadline()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(seatEventHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			extendDeadline()
+			c.SSEvent("seat", event)
+		case <-heartbeat.C:
+			extendDeadline()
+			_, _ = io.WriteString(w, ": heartbeat\n\n")
+		case <-c.Request.Context().Done():
+			return false
+		}
+		return true
+	})
+}
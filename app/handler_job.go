@@ -0,0 +1,30 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobStatus is a background job with whether its lease is currently held
+type JobStatus struct {
+	Job
+	Leased bool `json:"leased"`
+}
+
+// ListJobs return the lease holder and run metrics of every background job
+func (h *Handler) ListJobs(c *gin.Context) {
+	jobs, err := h.ds.ListJobs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve jobs"})
+		return
+	}
+
+	now := time.Now()
+	statuses := make([]JobStatus, len(jobs))
+	for i, job := range jobs {
+		statuses[i] = JobStatus{Job: job, Leased: job.LeaseExpiresAt.After(now)}
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": statuses})
+}
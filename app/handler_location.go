@@ -0,0 +1,104 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (h *Handler) ListLocations(c *gin.Context) {
+	buildings, err := h.ds.ListLocations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildings)
+}
+
+func (h *Handler) CreateBuilding(c *gin.Context) {
+	var request BuildingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	building := &Building{
+		Name: request.Name,
+	}
+	if err := h.ds.CreateBuilding(building); err != nil {
+		writeLocationWriteError(c, err, "Building already exists", "Failed to create building")
+		return
+	}
+
+	c.JSON(http.StatusCreated, building)
+}
+
+func (h *Handler) CreateFloor(c *gin.Context) {
+	var request FloorRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if _, err := h.ds.GetBuildingByID(request.BuildingID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Building not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve building"})
+		}
+		return
+	}
+
+	floor := &Floor{
+		BuildingID: request.BuildingID,
+		Name:       request.Name,
+		Level:      request.Level,
+	}
+	if err := h.ds.CreateFloor(floor); err != nil {
+		writeLocationWriteError(c, err, "Floor already exists", "Failed to create floor")
+		return
+	}
+
+	c.JSON(http.StatusCreated, floor)
+}
+
+func (h *Handler) CreateZone(c *gin.Context) {
+	var request ZoneRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if _, err := h.ds.GetFloorByID(request.FloorID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Floor not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve floor"})
+		}
+		return
+	}
+
+	zone := &Zone{
+		FloorID: request.FloorID,
+		Name:    request.Name,
+	}
+	if err := h.ds.CreateZone(zone); err != nil {
+		writeLocationWriteError(c, err, "Zone already exists", "Failed to create zone")
+		return
+	}
+
+	c.JSON(http.StatusCreated, zone)
+}
+
+// writeLocationWriteError write the response of a failed location write, a name already
+// used under the same parent is reported as a conflict and anything else as an internal error
+func writeLocationWriteError(c *gin.Context, err error, conflict, message string) {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusConflict, gin.H{"error": conflict})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"code-challenge-backend/migrations"
+	"code-challenge-backend/pkg/migrate"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestLocations(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r                = newTestRouter(s)
+			_, token         = createTestUser(t, s, "admin@example.com", RoleFacilityAdmin)
+			_, employeeToken = createTestUser(t, s, "employee@example.com", RoleEmployee)
+		)
+		create := func(path string, body gin.H) uint {
+			w := serveJSON(r, http.MethodPost, path, token, body)
+			require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			var created struct {
+				ID uint `json:"ID"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+			return created.ID
+		}
+
+		hq := create("/admin/buildings", gin.H{"name": "HQ"})
+		annex := create("/admin/buildings", gin.H{"name": "Annex"})
+		first := create("/admin/floors", gin.H{"building_id": hq, "name": "First", "level": 1})
+		ground := create("/admin/floors", gin.H{"building_id": hq, "name": "Ground", "level": 0})
+		create("/admin/floors", gin.H{"building_id": annex, "name": "Ground", "level": 0})
+		create("/admin/zones", gin.H{"floor_id": ground, "name": "South"})
+		create("/admin/zones", gin.H{"floor_id": ground, "name": "North"})
+		create("/admin/zones", gin.H{"floor_id": first, "name": "North"})
+
+		type args struct {
+			token string
+			path  string
+			body  gin.H
+		}
+		tests := []struct {
+			name      string
+			args      args
+			want      int
+			wantError string
+		}{
+			{
+				name: "employee",
+				args: args{token: employeeToken, path: "/admin/buildings", body: gin.H{"name": "Lab"}},
+				want: http.StatusForbidden,
+			},
+			{
+				name:      "building without name",
+				args:      args{token: token, path: "/admin/buildings", body: gin.H{}},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid request",
+			},
+			{
+				name:      "duplicate building",
+				args:      args{token: token, path: "/admin/buildings", body: gin.H{"name": "HQ"}},
+				want:      http.StatusConflict,
+				wantError: "Building already exists",
+			},
+			{
+				name:      "floor of an unknown building",
+				args:      args{token: token, path: "/admin/floors", body: gin.H{"building_id": 999, "name": "Ground"}},
+				want:      http.StatusNotFound,
+				wantError: "Building not found",
+			},
+			{
+				name:      "duplicate floor",
+				args:      args{token: token, path: "/admin/floors", body: gin.H{"building_id": hq, "name": "Ground", "level": 2}},
+				want:      http.StatusConflict,
+				wantError: "Floor already exists",
+			},
+			{
+				name:      "zone of an unknown floor",
+				args:      args{token: token, path: "/admin/zones", body: gin.H{"floor_id": 999, "name": "North"}},
+				want:      http.StatusNotFound,
+				wantError: "Floor not found",
+			},
+			{
+				name:      "duplicate zone",
+				args:      args{token: token, path: "/admin/zones", body: gin.H{"floor_id": ground, "name": "North"}},
+				want:      http.StatusConflict,
+				wantError: "Zone already exists",
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveJSON(r, http.MethodPost, tt.args.path, tt.args.token, tt.args.body)
+				require.Equal(t, tt.want, w.Code, w.Body.String())
+				if tt.wantError != "" {
+					assert.Equal(t, tt.wantError, decodeError(t, w))
+				}
+			})
+		}
+
+		// buildings by name, floors by level and zones by name
+		w := serveJSON(r, http.MethodGet, "/locations", "", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var buildings []Building
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &buildings))
+		var got []string
+		for _, building := range buildings {
+			for _, floor := range building.Floors {
+				for _, zone := range floor.Zones {
+					got = append(got, fmt.Sprintf("%s/%s/%s", building.Name, floor.Name, zone.Name))
+				}
+				if len(floor.Zones) == 0 {
+					got = append(got, fmt.Sprintf("%s/%s", building.Name, floor.Name))
+				}
+			}
+		}
+		assert.Equal(t, []string{"Annex/Ground", "HQ/Ground/North", "HQ/Ground/South", "HQ/First/North"}, got)
+	})
+}
+
+func TestLocationNameUnique_MigrateDuplicates(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ds *DataStorage) {
+		migrator, err := migrate.New(ds.mysqlDB, migrations.All())
+		require.NoError(t, err)
+		// revert the location name indexes and the migrations after them
+		var reverted int
+		for _, migration := range migrations.All() {
+			if migration.Version >= 20261018180000 {
+				reverted++
+			}
+		}
+		_, err = migrator.Down(reverted)
+		require.NoError(t, err)
+		require.NoError(t, ds.CreateBuilding(&Building{Name: "HQ"}))
+		duplicate := &Building{Name: "HQ"}
+		require.NoError(t, ds.CreateBuilding(duplicate))
+
+		_, err = migrator.Up()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "buildings names used more than once in the same parent: HQ,")
+
+		require.NoError(t, ds.mysqlDB.Model(duplicate).Update("name", "Annex").Error)
+		_, err = migrator.Up()
+		require.NoError(t, err)
+		assert.ErrorIs(t, ds.CreateBuilding(&Building{Name: "HQ"}), gorm.ErrDuplicatedKey)
+	})
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MyNotifications list the latest in-app notifications of the current user, newest first
+func (h *Handler) MyNotifications(c *gin.Context) {
+	notifications, err := h.ds.FindInAppNotifications(userIDFromContext(c), pageSize(c.Query("limit")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications})
+}
+
+// ReadNotification mark an in-app notification of the current user as read
+func (h *Handler) ReadNotification(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification id"})
+		return
+	}
+
+	notification, err := h.ds.MarkNotificationRead(userIDFromContext(c), uint(id), time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
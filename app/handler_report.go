@@ -0,0 +1,75 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UtilizationReport return the utilization of every bucket (?bucket=day|week|month)
+// of the ?from and ?to dates with the totals of the period
+func (h *Handler) UtilizationReport(c *gin.Context) {
+	report, ok := h.buildReport(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":    report.From,
+		"to":      report.To,
+		"bucket":  report.Bucket,
+		"total":   report.Total,
+		"buckets": report.Buckets,
+	})
+}
+
+// SeatUtilizationReport return the utilization of every seat between ?from and ?to
+func (h *Handler) SeatUtilizationReport(c *gin.Context) {
+	report, ok := h.buildReport(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"from": report.From, "to": report.To, "seats": report.Seats})
+}
+
+// ZoneUtilizationReport return the utilization of every zone between ?from and ?to
+func (h *Handler) ZoneUtilizationReport(c *gin.Context) {
+	report, ok := h.buildReport(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"from": report.From, "to": report.To, "zones": report.Zones})
+}
+
+// PeakHoursReport return the bookings held during every hour of the day between ?from and ?to
+func (h *Handler) PeakHoursReport(c *gin.Context) {
+	report, ok := h.buildReport(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":      report.From,
+		"to":        report.To,
+		"peak_hour": report.PeakHour,
+		"hours":     report.Hours,
+	})
+}
+
+func (h *Handler) buildReport(c *gin.Context) (*UtilizationReport, bool) {
+	filter, err := ParseReportFilter(c.Query("from"), c.Query("to"), c.Query("bucket"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	report, err := BuildUtilizationReport(h.ds, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+		return nil, false
+	}
+	return report, true
+}
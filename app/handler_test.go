@@ -0,0 +1,1164 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"code-challenge-backend/migrations"
+	"code-challenge-backend/pkg/dateutil"
+	"code-challenge-backend/pkg/migrate"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestLogin(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		r := newTestRouter(s)
+		w := serveJSON(r, http.MethodPost, "/register", "", gin.H{"email": "jane@example.com", "name": "Jane", "password": "password1"})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		type args struct {
+			body interface{}
+		}
+		tests := []struct {
+			name      string
+			args      args
+			want      int
+			wantError string
+		}{
+			{
+				name: "valid credentials",
+				args: args{body: gin.H{"email": "jane@example.com", "password": "password1"}},
+				want: http.StatusOK,
+			},
+			{
+				name:      "wrong password",
+				args:      args{body: gin.H{"email": "jane@example.com", "password": "password2"}},
+				want:      http.StatusUnauthorized,
+				wantError: "Invalid email or password",
+			},
+			{
+				name:      "unknown email",
+				args:      args{body: gin.H{"email": "john@example.com", "password": "password1"}},
+				want:      http.StatusUnauthorized,
+				wantError: "Invalid email or password",
+			},
+			{
+				name:      "missing password",
+				args:      args{body: gin.H{"email": "jane@example.com"}},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid request",
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveJSON(r, http.MethodPost, "/login", "", tt.args.body)
+				require.Equal(t, tt.want, w.Code, w.Body.String())
+				if tt.wantError != "" {
+					assert.Equal(t, tt.wantError, decodeError(t, w))
+					return
+				}
+
+				var body struct {
+					AccessToken  string `json:"access_token"`
+					RefreshToken string `json:"refresh_token"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				subject, err := parseToken(testJWTSecret, body.AccessToken, tokenTypeAccess)
+				require.NoError(t, err)
+				assert.Equal(t, RoleEmployee, subject.Role)
+				assert.NotEmpty(t, body.RefreshToken)
+			})
+		}
+	})
+}
+
+func TestLogin_Lockout(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		r := newTestRouter(s)
+		w := serveJSON(r, http.MethodPost, "/register", "", gin.H{"email": "jane@example.com", "name": "Jane", "password": "password1"})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		for i := 0; i < maxFailedLoginAttempts; i++ {
+			w := serveJSON(r, http.MethodPost, "/login", "", gin.H{"email": "jane@example.com", "password": "wrong-password"})
+			require.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+
+		w = serveJSON(r, http.MethodPost, "/login", "", gin.H{"email": "jane@example.com", "password": "password1"})
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, w.Body.String())
+	})
+}
+
+func TestLogin_LockoutParallel(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		r := newTestRouter(s)
+		w := serveJSON(r, http.MethodPost, "/register", "", gin.H{"email": "jane@example.com", "name": "Jane", "password": "password1"})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		// every guess reads the user before any of them is recorded
+		var wg sync.WaitGroup
+		for i := 0; i < maxFailedLoginAttempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w := serveJSON(r, http.MethodPost, "/login", "", gin.H{"email": "jane@example.com", "password": "wrong-password"})
+				assert.Equal(t, http.StatusUnauthorized, w.Code, w.Body.String())
+			}()
+		}
+		wg.Wait()
+
+		w = serveJSON(r, http.MethodPost, "/login", "", gin.H{"email": "jane@example.com", "password": "password1"})
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, w.Body.String())
+	})
+}
+
+func TestListAvailableSeats(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 1)
+			from  = time.Now().In(dateutil.LocVN).Add(24 * time.Hour).Truncate(time.Hour)
+		)
+		building := &Building{Name: "HQ"}
+		require.NoError(t, s.CreateBuilding(building))
+		floor := &Floor{BuildingID: building.ID, Name: "Ground", Level: 0}
+		require.NoError(t, s.CreateFloor(floor))
+		zone := &Zone{FloorID: floor.ID, Name: "North"}
+		require.NoError(t, s.CreateZone(zone))
+
+		seats := []*Seat{
+			{Number: "A1", ZoneID: &zone.ID, DualMonitor: true},
+			{Number: "A2", ZoneID: &zone.ID},
+			{Number: "B1"},
+		}
+		for _, seat := range seats {
+			require.NoError(t, s.CreateSeat(seat))
+		}
+		require.NoError(t, s.DeleteSeat(seats[2].ID))
+		require.Equal(t, http.StatusOK, bookSeat(r, users[0], "A2", from, from.Add(2*time.Hour)))
+
+		type args struct {
+			body interface{}
+		}
+		tests := []struct {
+			name    string
+			args    args
+			want    int
+			wantNum []string
+		}{
+			{
+				name:    "booked and deleted seats are hidden",
+				args:    args{body: gin.H{"from_time": from.Add(time.Hour), "to_time": from.Add(3 * time.Hour)}},
+				want:    http.StatusOK,
+				wantNum: []string{"A1"},
+			},
+			{
+				name:    "every seat is free before the booking",
+				args:    args{body: gin.H{"from_time": from.Add(-3 * time.Hour), "to_time": from.Add(-time.Hour)}},
+				want:    http.StatusOK,
+				wantNum: []string{"A1", "A2"},
+			},
+			{
+				name:    "filtered by zone and features",
+				args:    args{body: gin.H{"from_time": from.Add(-3 * time.Hour), "to_time": from.Add(-time.Hour), "zone_id": zone.ID, "dual_monitor": false}},
+				want:    http.StatusOK,
+				wantNum: []string{"A2"},
+			},
+			{
+				name: "missing time range",
+				args: args{body: gin.H{}},
+				want: http.StatusBadRequest,
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveJSON(r, http.MethodGet, "/seats", "", tt.args.body)
+				require.Equal(t, tt.want, w.Code, w.Body.String())
+				if tt.want != http.StatusOK {
+					return
+				}
+
+				var got []Seat
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+				numbers := []string{}
+				for _, seat := range got {
+					numbers = append(numbers, seat.Number)
+					if seat.ZoneID != nil {
+						require.NotNil(t, seat.Zone)
+						require.NotNil(t, seat.Zone.Floor)
+						require.NotNil(t, seat.Zone.Floor.Building)
+						assert.Equal(t, "HQ", seat.Zone.Floor.Building.Name)
+					}
+				}
+				assert.Equal(t, tt.wantNum, numbers)
+			})
+		}
+	})
+}
+
+func TestSeatAvailability(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = make([]*User, 2)
+			day   = time.Date(2026, 10, 20, 0, 0, 0, 0, dateutil.LocVN)
+		)
+		for i := range users {
+			users[i] = &User{Name: "user", Email: fmt.Sprintf("user%d@example.com", i), Role: RoleEmployee}
+			require.NoError(t, s.Create(users[i]))
+		}
+		a1, a2 := &Seat{Number: "A1"}, &Seat{Number: "A2", Type: SeatTypeBooth}
+		require.NoError(t, s.CreateSeat(a1))
+		require.NoError(t, s.CreateSeat(a2))
+
+		bookings := []*Booking{
+			{UserID: users[0].ID, SeatID: a1.ID, StartTime: day.Add(-2 * time.Hour), EndTime: day.Add(time.Hour)},
+			{UserID: users[0].ID, SeatID: a1.ID, StartTime: day.Add(9 * time.Hour), EndTime: day.Add(10*time.Hour + 30*time.Minute)},
+			{UserID: users[1].ID, SeatID: a2.ID, StartTime: day.Add(13 * time.Hour), EndTime: day.Add(14 * time.Hour)},
+			{UserID: users[1].ID, SeatID: a2.ID, StartTime: day.Add(15 * time.Hour), EndTime: day.Add(16 * time.Hour)},
+		}
+		for _, booking := range bookings {
+			require.NoError(t, s.CreateBookingIfAvailable(booking))
+		}
+		require.NoError(t, s.UpdateBookingStatus(bookings[2], BookingStatusCheckedIn))
+		require.NoError(t, s.UpdateBookingStatus(bookings[3], BookingStatusCancelled))
+
+		type args struct {
+			query string
+		}
+		tests := []struct {
+			name      string
+			args      args
+			want      int
+			wantSlots int
+			wantBusy  map[string]map[int]string
+		}{
+			{
+				name:      "hourly slots",
+				args:      args{query: "date=2026-10-20&slot_minutes=60"},
+				want:      http.StatusOK,
+				wantSlots: 24,
+				wantBusy: map[string]map[int]string{
+					"A1": {0: SlotBooked, 9: SlotBooked, 10: SlotBooked},
+					"A2": {13: SlotCheckedIn},
+				},
+			},
+			{
+				name:      "30 minutes slots by default, filtered by type",
+				args:      args{query: "date=2026-10-20&type=booth"},
+				want:      http.StatusOK,
+				wantSlots: 48,
+				wantBusy:  map[string]map[int]string{"A2": {26: SlotCheckedIn, 27: SlotCheckedIn}},
+			},
+			{
+				name: "missing date",
+				args: args{query: "slot_minutes=60"},
+				want: http.StatusBadRequest,
+			},
+			{
+				name: "slot not dividing the day",
+				args: args{query: "date=2026-10-20&slot_minutes=7"},
+				want: http.StatusBadRequest,
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveJSON(r, http.MethodGet, "/seats/availability?"+tt.args.query, "", nil)
+				require.Equal(t, tt.want, w.Code, w.Body.String())
+				if tt.want != http.StatusOK {
+					return
+				}
+
+				var got AvailabilityGrid
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+				assert.Equal(t, "2026-10-20", got.Date)
+				require.Len(t, got.Slots, tt.wantSlots)
+				assert.Equal(t, "00:00", got.Slots[0])
+				require.Len(t, got.Seats, len(tt.wantBusy))
+				for _, seat := range got.Seats {
+					require.Len(t, seat.Slots, tt.wantSlots)
+					for i, state := range seat.Slots {
+						want, ok := tt.wantBusy[seat.Number][i]
+						if !ok {
+							want = SlotFree
+						}
+						assert.Equal(t, want, state, "seat %s slot %s", seat.Number, got.Slots[i])
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestBookSeat(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 2)
+			from  = time.Now().In(dateutil.LocVN).Add(24 * time.Hour).Truncate(time.Hour)
+		)
+		require.NoError(t, s.CreateSeat(&Seat{Number: "A1"}))
+		require.NoError(t, s.CreateSeat(&Seat{Number: "A2"}))
+		require.Equal(t, http.StatusOK, bookSeat(r, users[0], "A1", from, from.Add(2*time.Hour)))
+
+		type args struct {
+			token string
+			body  interface{}
+		}
+		tests := []struct {
+			name      string
+			args      args
+			want      int
+			wantError string
+		}{
+			{
+				name: "free seat",
+				args: args{token: users[1], body: gin.H{"seat_number": "A2", "from_time": from.Format(timeFormat), "to_time": from.Add(time.Hour).Format(timeFormat)}},
+				want: http.StatusOK,
+			},
+			{
+				name:      "overlapping own booking",
+				args:      args{token: users[1], body: gin.H{"seat_number": "A1", "from_time": from.Add(-time.Hour).Format(timeFormat), "to_time": from.Add(30 * time.Minute).Format(timeFormat)}},
+				want:      http.StatusBadRequest,
+				wantError: "User already has a booking",
+			},
+			{
+				name:      "unknown seat",
+				args:      args{token: users[0], body: gin.H{"seat_number": "Z9", "from_time": from.Format(timeFormat), "to_time": from.Add(time.Hour).Format(timeFormat)}},
+				want:      http.StatusNotFound,
+				wantError: "Seat not found",
+			},
+			{
+				name:      "invalid time format",
+				args:      args{token: users[0], body: gin.H{"seat_number": "A2", "from_time": "tomorrow", "to_time": from.Add(time.Hour).Format(timeFormat)}},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid from_time format",
+			},
+			{
+				name:      "from_time in the past",
+				args:      args{token: users[0], body: gin.H{"seat_number": "A2", "from_time": time.Now().Add(-48 * time.Hour).Format(timeFormat), "to_time": time.Now().Add(-47 * time.Hour).Format(timeFormat)}},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid from_time",
+			},
+			{
+				name:      "missing token",
+				args:      args{body: gin.H{"seat_number": "A2", "from_time": from.Format(timeFormat), "to_time": from.Add(time.Hour).Format(timeFormat)}},
+				want:      http.StatusUnauthorized,
+				wantError: "Missing access token",
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveJSON(r, http.MethodPost, "/book-seat", tt.args.token, tt.args.body)
+				require.Equal(t, tt.want, w.Code, w.Body.String())
+				if tt.wantError != "" {
+					assert.Equal(t, tt.wantError, decodeError(t, w))
+				}
+			})
+		}
+	})
+}
+
+func TestBookSeat_SeatAlreadyBooked(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 2)
+			from  = time.Now().In(dateutil.LocVN).Add(24 * time.Hour).Truncate(time.Hour)
+		)
+		require.NoError(t, s.CreateSeat(&Seat{Number: "A1"}))
+		require.Equal(t, http.StatusOK, bookSeat(r, users[0], "A1", from, from.Add(2*time.Hour)))
+
+		w := serveJSON(r, http.MethodPost, "/book-seat", users[1], gin.H{
+			"seat_number": "A1",
+			"from_time":   from.Add(time.Hour).Format(timeFormat),
+			"to_time":     from.Add(3 * time.Hour).Format(timeFormat),
+		})
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.Equal(t, "Seat already booked on that duration", decodeError(t, w))
+
+		// the seat is free again once the booking is cancelled
+		user, err := s.GetUserByEmail("user0@example.com")
+		require.NoError(t, err)
+		views, err := s.FindBookingsByUserID(user.ID, BookingFilterUpcoming, nil, 10, time.Now())
+		require.NoError(t, err)
+		require.Len(t, views, 1)
+		booking, err := s.QueryBooking(uint(views[0].ID))
+		require.NoError(t, err)
+		require.NoError(t, s.UpdateBookingStatus(booking, BookingStatusCancelled))
+		assert.Equal(t, http.StatusOK, bookSeat(r, users[1], "A1", from.Add(time.Hour), from.Add(3*time.Hour)))
+	})
+}
+
+func TestBookSeat_BackToBack(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 2)
+			from  = time.Now().In(dateutil.LocVN).Add(24 * time.Hour).Truncate(time.Hour)
+			next  = from.Add(2 * time.Hour)
+		)
+		require.NoError(t, s.CreateSeat(&Seat{Number: "A1"}))
+		require.NoError(t, s.CreateSeat(&Seat{Number: "A2"}))
+		require.Equal(t, http.StatusOK, bookSeat(r, users[0], "A1", from, next))
+
+		w := serveJSON(r, http.MethodPost, "/book-seat", users[1], gin.H{"seat_number": "A1", "from_time": next.Format(timeFormat), "to_time": next.Format(timeFormat)})
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.Equal(t, "Invalid time range", decodeError(t, w))
+
+		// time ranges are half open, a seat listed as available right after a booking can be booked
+		w = serveJSON(r, http.MethodGet, "/seats", "", gin.H{"from_time": next, "to_time": next.Add(time.Hour)})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var seats []Seat
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &seats))
+		require.Len(t, seats, 2)
+		assert.Equal(t, http.StatusOK, bookSeat(r, users[1], "A1", next, next.Add(time.Hour)))
+		assert.Equal(t, http.StatusOK, bookSeat(r, users[0], "A2", next, next.Add(time.Hour)))
+	})
+}
+
+func TestBookRecurringSeat(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 2)
+			from  = time.Now().In(dateutil.LocVN).Add(24 * time.Hour).Truncate(time.Hour)
+		)
+		require.NoError(t, s.CreateSeat(&Seat{Number: "A1"}))
+		// the second occurrence is taken by another user
+		require.Equal(t, http.StatusOK, bookSeat(r, users[1], "A1", from.AddDate(0, 0, 1), from.AddDate(0, 0, 1).Add(time.Hour)))
+
+		request := gin.H{
+			"seat_number": "A1",
+			"from_time":   from.Format(timeFormat),
+			"to_time":     from.Add(time.Hour).Format(timeFormat),
+			"rrule":       "FREQ=DAILY;COUNT=3",
+		}
+		w := serveJSON(r, http.MethodPost, "/book-seat/recurring", users[0], request)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var body struct {
+			SeriesID uint               `json:"series_id"`
+			Booked   []OccurrenceResult `json:"booked"`
+			Skipped  []OccurrenceResult `json:"skipped"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.NotZero(t, body.SeriesID)
+		require.Len(t, body.Booked, 2)
+		require.Len(t, body.Skipped, 1)
+		assert.True(t, body.Skipped[0].StartTime.Equal(from.AddDate(0, 0, 1)))
+		assert.Equal(t, "Seat already booked on that duration", body.Skipped[0].Reason)
+		for _, occurrence := range body.Booked {
+			booking, err := s.QueryBooking(uint(occurrence.BookingID))
+			require.NoError(t, err)
+			require.NotNil(t, booking.SeriesID)
+			assert.Equal(t, body.SeriesID, *booking.SeriesID)
+		}
+
+		// every occurrence now conflicts with the series itself
+		w = serveJSON(r, http.MethodPost, "/book-seat/recurring", users[0], request)
+		require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Len(t, body.Skipped, 3)
+	})
+}
+
+func TestUpdateBooking(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 3)
+			from  = time.Now().In(dateutil.LocVN).Add(24 * time.Hour).Truncate(time.Hour)
+			to    = from.Add(time.Hour)
+		)
+		for _, number := range []string{"A1", "B1", "C1"} {
+			require.NoError(t, s.CreateSeat(&Seat{Number: number}))
+		}
+		owner, err := s.GetUserByEmail("user0@example.com")
+		require.NoError(t, err)
+		seat, err := s.GetSeatByNumber("A1")
+		require.NoError(t, err)
+		booking := &Booking{UserID: owner.ID, SeatID: seat.ID, StartTime: from, EndTime: to}
+		require.NoError(t, s.CreateBookingIfAvailable(booking))
+		cancelled := &Booking{UserID: owner.ID, SeatID: seat.ID, StartTime: from.AddDate(0, 0, 1), EndTime: to.AddDate(0, 0, 1)}
+		require.NoError(t, s.CreateBookingIfAvailable(cancelled))
+		require.NoError(t, s.UpdateBookingStatus(cancelled, BookingStatusCancelled))
+		require.Equal(t, http.StatusOK, bookSeat(r, users[1], "B1", from, to))
+
+		path := fmt.Sprintf("/bookings/%d", booking.ID)
+		later, laterEnd := from.Add(2*time.Hour), to.Add(2*time.Hour)
+		type args struct {
+			token string
+			path  string
+			body  interface{}
+		}
+		tests := []struct {
+			name      string
+			args      args
+			want      int
+			wantError string
+		}{
+			{
+				name:      "booking of another user",
+				args:      args{token: users[1], path: path, body: gin.H{"seat_number": "C1"}},
+				want:      http.StatusNotFound,
+				wantError: "Booking not found",
+			},
+			{
+				name:      "cancelled booking",
+				args:      args{token: users[0], path: fmt.Sprintf("/bookings/%d", cancelled.ID), body: gin.H{"seat_number": "C1"}},
+				want:      http.StatusBadRequest,
+				wantError: "Booking is cancelled",
+			},
+			{
+				name:      "unknown seat",
+				args:      args{token: users[0], path: path, body: gin.H{"seat_number": "Z9"}},
+				want:      http.StatusNotFound,
+				wantError: "Seat not found",
+			},
+			{
+				name:      "seat taken",
+				args:      args{token: users[0], path: path, body: gin.H{"seat_number": "B1"}},
+				want:      http.StatusBadRequest,
+				wantError: "Seat already booked on that duration",
+			},
+			{
+				name:      "invalid from_time",
+				args:      args{token: users[0], path: path, body: gin.H{"from_time": "tomorrow"}},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid from_time format",
+			},
+			{
+				name:      "ends before it starts",
+				args:      args{token: users[0], path: path, body: gin.H{"to_time": from.Add(-time.Hour).Format(timeFormat)}},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid time range",
+			},
+			{
+				name:      "starts in the past",
+				args:      args{token: users[0], path: path, body: gin.H{"from_time": time.Now().In(dateutil.LocVN).Add(-time.Hour).Format(timeFormat)}},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid from_time",
+			},
+			{
+				name: "seat and time",
+				args: args{token: users[0], path: path, body: gin.H{"seat_number": "C1", "from_time": later.Format(timeFormat), "to_time": laterEnd.Format(timeFormat)}},
+				want: http.StatusOK,
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveJSON(r, http.MethodPatch, tt.args.path, tt.args.token, tt.args.body)
+				require.Equal(t, tt.want, w.Code, w.Body.String())
+				if tt.wantError != "" {
+					assert.Equal(t, tt.wantError, decodeError(t, w))
+				}
+			})
+		}
+
+		got, err := s.QueryBooking(uint(booking.ID))
+		require.NoError(t, err)
+		c1, err := s.GetSeatByNumber("C1")
+		require.NoError(t, err)
+		assert.Equal(t, c1.ID, got.SeatID)
+		assert.True(t, got.StartTime.Equal(later))
+		assert.True(t, got.EndTime.Equal(laterEnd))
+		assert.Equal(t, BookingStatusModified, got.Status)
+		require.Equal(t, http.StatusOK, bookSeat(r, users[2], "A1", from, to), "the previous seat and time are free")
+	})
+}
+
+func TestBookingHistory(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 2)
+			from  = time.Now().In(dateutil.LocVN).Add(24 * time.Hour).Truncate(time.Hour)
+		)
+		require.NoError(t, s.CreateSeat(&Seat{Number: "A1"}))
+		require.NoError(t, s.CreateSeat(&Seat{Number: "B1"}))
+		require.Equal(t, http.StatusOK, bookSeat(r, users[0], "A1", from, from.Add(time.Hour)))
+		owner, err := s.GetUserByEmail("user0@example.com")
+		require.NoError(t, err)
+		bookings, err := s.FindBookingsByUserID(owner.ID, BookingFilterAll, nil, 1, time.Now())
+		require.NoError(t, err)
+		require.Len(t, bookings, 1)
+		id := bookings[0].ID
+
+		w := serveJSON(r, http.MethodPatch, fmt.Sprintf("/bookings/%d", id), users[0], gin.H{"seat_number": "B1"})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = serveJSON(r, http.MethodDelete, fmt.Sprintf("/bookings/%d", id), users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		path := fmt.Sprintf("/bookings/%d/history", id)
+		w = serveJSON(r, http.MethodGet, path, users[1], nil)
+		require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+		w = serveJSON(r, http.MethodGet, "/bookings/abc/history", users[0], nil)
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+		w = serveJSON(r, http.MethodGet, path, users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var history []BookingStatusHistory
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+		require.Len(t, history, 3)
+		statuses := []string{history[0].Status, history[1].Status, history[2].Status}
+		assert.Equal(t, []string{BookingStatusBooked, BookingStatusModified, BookingStatusCancelled}, statuses)
+		assert.NotEqual(t, history[0].SeatID, history[1].SeatID, "every entry keeps the seat of the booking at the time")
+	})
+}
+
+func TestCancelBookingSeries(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 2)
+			from  = time.Now().In(dateutil.LocVN).Add(24 * time.Hour).Truncate(time.Hour)
+		)
+		require.NoError(t, s.CreateSeat(&Seat{Number: "A1"}))
+		request := gin.H{
+			"seat_number": "A1",
+			"from_time":   from.Format(timeFormat),
+			"to_time":     from.Add(time.Hour).Format(timeFormat),
+			"rrule":       "FREQ=DAILY;COUNT=3",
+		}
+		w := serveJSON(r, http.MethodPost, "/book-seat/recurring", users[0], request)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var series struct {
+			SeriesID uint               `json:"series_id"`
+			Booked   []OccurrenceResult `json:"booked"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+		require.Len(t, series.Booked, 3)
+		seriesPath := fmt.Sprintf("/booking-series/%d", series.SeriesID)
+		occurrencePath := fmt.Sprintf("%s/bookings/%d", seriesPath, series.Booked[0].BookingID)
+
+		cancelled := func(w *httptest.ResponseRecorder) int {
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var body struct {
+				Cancelled int `json:"cancelled"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			return body.Cancelled
+		}
+
+		w = serveJSON(r, http.MethodDelete, seriesPath, users[1], nil)
+		require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+		assert.Equal(t, "Series not found", decodeError(t, w))
+		w = serveJSON(r, http.MethodDelete, occurrencePath, users[1], nil)
+		require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+		// a single occurrence first, the rest of the series then
+		w = serveJSON(r, http.MethodDelete, occurrencePath, users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = serveJSON(r, http.MethodDelete, fmt.Sprintf("%s/bookings/%d", seriesPath, 999), users[0], nil)
+		require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+		assert.Equal(t, 2, cancelled(serveJSON(r, http.MethodDelete, seriesPath, users[0], nil)))
+		assert.Equal(t, 0, cancelled(serveJSON(r, http.MethodDelete, seriesPath, users[0], nil)))
+
+		for _, occurrence := range series.Booked {
+			booking, err := s.QueryBooking(uint(occurrence.BookingID))
+			require.NoError(t, err)
+			assert.Equal(t, BookingStatusCancelled, booking.Status)
+		}
+		require.Equal(t, http.StatusOK, bookSeat(r, users[1], "A1", from, from.Add(time.Hour)))
+	})
+}
+
+func TestMyBookings_Pagination(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 1)
+			from  = time.Now().In(dateutil.LocVN).Add(24 * time.Hour).Truncate(time.Hour)
+		)
+		owner, err := s.GetUserByEmail("user0@example.com")
+		require.NoError(t, err)
+		seat := &Seat{Number: "A1"}
+		require.NoError(t, s.CreateSeat(seat))
+
+		// three bookings start at the same time, only the last one is still active
+		var ids []int
+		for i := 0; i < 3; i++ {
+			booking := &Booking{UserID: owner.ID, SeatID: seat.ID, StartTime: from, EndTime: from.Add(time.Hour)}
+			require.NoError(t, s.CreateBookingIfAvailable(booking))
+			if i < 2 {
+				require.NoError(t, s.UpdateBookingStatus(booking, BookingStatusCancelled))
+			}
+			ids = append(ids, booking.ID)
+		}
+		later := &Booking{UserID: owner.ID, SeatID: seat.ID, StartTime: from.Add(2 * time.Hour), EndTime: from.Add(3 * time.Hour)}
+		require.NoError(t, s.CreateBookingIfAvailable(later))
+		ids = append(ids, later.ID)
+
+		type page struct {
+			Bookings   []BookingView `json:"bookings"`
+			NextCursor string        `json:"next_cursor"`
+		}
+		pages := func(query string) [][]int {
+			var (
+				got    [][]int
+				cursor string
+			)
+			for {
+				w := serveJSON(r, http.MethodGet, "/me/bookings?"+query+"&cursor="+cursor, users[0], nil)
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+				var body page
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				var pageIDs []int
+				for _, booking := range body.Bookings {
+					pageIDs = append(pageIDs, booking.ID)
+				}
+				got = append(got, pageIDs)
+				if body.NextCursor == "" {
+					return got
+				}
+				require.Less(t, len(got), len(ids), "pagination does not end")
+				cursor = body.NextCursor
+			}
+		}
+
+		tests := []struct {
+			name  string
+			query string
+			want  [][]int
+		}{
+			{
+				name:  "newest first, ties by id",
+				query: "limit=2",
+				want:  [][]int{{ids[3], ids[2]}, {ids[1], ids[0]}},
+			},
+			{
+				name:  "page boundary inside a tie",
+				query: "limit=3",
+				want:  [][]int{{ids[3], ids[2], ids[1]}, {ids[0]}},
+			},
+			{
+				name:  "single page",
+				query: "limit=4",
+				want:  [][]int{{ids[3], ids[2], ids[1], ids[0]}},
+			},
+			{
+				name:  "upcoming oldest first",
+				query: "filter=upcoming&limit=1",
+				want:  [][]int{{ids[2]}, {ids[3]}},
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				assert.Equal(t, tt.want, pages(tt.query))
+			})
+		}
+
+		w := serveJSON(r, http.MethodGet, "/me/bookings?cursor=not-a-cursor", users[0], nil)
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.Equal(t, "Invalid cursor", decodeError(t, w))
+	})
+}
+
+func TestCheckIn(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 2)
+			now   = time.Now()
+		)
+		seat := &Seat{Number: "A1"}
+		require.NoError(t, s.CreateSeat(seat))
+		owner, err := s.GetUserByEmail("user0@example.com")
+		require.NoError(t, err)
+		other, err := s.GetUserByEmail("user1@example.com")
+		require.NoError(t, err)
+
+		// bookings which started a few minutes ago cannot be made through the API
+		booking := &Booking{UserID: owner.ID, SeatID: seat.ID, StartTime: now.Add(-5 * time.Minute), EndTime: now.Add(time.Hour)}
+		require.NoError(t, s.CreateBookingIfAvailable(booking))
+		cancelled := &Booking{UserID: other.ID, SeatID: seat.ID, StartTime: now.Add(2 * time.Hour), EndTime: now.Add(3 * time.Hour)}
+		require.NoError(t, s.CreateBookingIfAvailable(cancelled))
+		require.NoError(t, s.UpdateBookingStatus(cancelled, BookingStatusCancelled))
+
+		type args struct {
+			token string
+			body  interface{}
+		}
+		tests := []struct {
+			name      string
+			args      args
+			want      int
+			wantError string
+		}{
+			{
+				name:      "booking of another user",
+				args:      args{token: users[1], body: gin.H{"seat_id": seat.ID, "booking_id": booking.ID}},
+				want:      http.StatusBadRequest,
+				wantError: "Booking does not match user",
+			},
+			{
+				name:      "wrong seat",
+				args:      args{token: users[0], body: gin.H{"seat_id": seat.ID + 1, "booking_id": booking.ID}},
+				want:      http.StatusBadRequest,
+				wantError: "Booking does not match seat",
+			},
+			{
+				name:      "unknown booking",
+				args:      args{token: users[0], body: gin.H{"seat_id": seat.ID, "booking_id": 999}},
+				want:      http.StatusNotFound,
+				wantError: "Booking not found",
+			},
+			{
+				name:      "cancelled booking",
+				args:      args{token: users[1], body: gin.H{"seat_id": seat.ID, "booking_id": cancelled.ID}},
+				want:      http.StatusBadRequest,
+				wantError: "Booking is cancelled",
+			},
+			{
+				name: "own booking",
+				args: args{token: users[0], body: gin.H{"seat_id": seat.ID, "booking_id": booking.ID}},
+				want: http.StatusCreated,
+			},
+			{
+				name:      "already checked in",
+				args:      args{token: users[0], body: gin.H{"seat_id": seat.ID, "booking_id": booking.ID}},
+				want:      http.StatusBadRequest,
+				wantError: "Booking already checked in",
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveJSON(r, http.MethodPost, "/checkin", tt.args.token, tt.args.body)
+				require.Equal(t, tt.want, w.Code, w.Body.String())
+				if tt.wantError != "" {
+					assert.Equal(t, tt.wantError, decodeError(t, w))
+				}
+			})
+		}
+
+		got, err := s.QueryBooking(uint(booking.ID))
+		require.NoError(t, err)
+		assert.True(t, got.CheckedIn)
+		assert.Equal(t, BookingStatusCheckedIn, got.Status)
+
+		history, err := s.FindBookingHistory(booking.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, BookingStatusBooked, history[0].Status)
+		assert.Equal(t, BookingStatusCheckedIn, history[1].Status)
+
+		w := serveJSON(r, http.MethodGet, "/me/bookings?filter=checked_in", users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var body struct {
+			Bookings []BookingView `json:"bookings"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		require.Len(t, body.Bookings, 1)
+		assert.Equal(t, booking.ID, body.Bookings[0].ID)
+		assert.Equal(t, "A1", body.Bookings[0].SeatNumber)
+	})
+}
+
+func TestCheckInQR(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 2)
+			now   = time.Now()
+			seats = []*Seat{{Number: "A1"}, {Number: "A2"}, {Number: "A3"}}
+		)
+		codes := make([]string, len(seats))
+		for i, seat := range seats {
+			require.NoError(t, s.CreateSeat(seat))
+			code, err := issueSeatToken(testJWTSecret, seat.ID)
+			require.NoError(t, err)
+			codes[i] = code
+		}
+		owner, err := s.GetUserByEmail("user0@example.com")
+		require.NoError(t, err)
+
+		started := &Booking{UserID: owner.ID, SeatID: seats[0].ID, StartTime: now.Add(-5 * time.Minute), EndTime: now.Add(5 * time.Minute)}
+		soon := &Booking{UserID: owner.ID, SeatID: seats[1].ID, StartTime: now.Add(10 * time.Minute), EndTime: now.Add(time.Hour)}
+		later := &Booking{UserID: owner.ID, SeatID: seats[2].ID, StartTime: now.Add(2 * time.Hour), EndTime: now.Add(3 * time.Hour)}
+		for _, booking := range []*Booking{started, soon, later} {
+			require.NoError(t, s.CreateBookingIfAvailable(booking))
+		}
+		otherSecretCode, err := issueSeatToken("other-secret", seats[0].ID)
+		require.NoError(t, err)
+
+		type args struct {
+			token string
+			code  string
+		}
+		tests := []struct {
+			name        string
+			args        args
+			want        int
+			wantBooking int
+			wantError   string
+		}{
+			{
+				name:      "garbage code",
+				args:      args{token: users[0], code: "seat-A1"},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid seat code",
+			},
+			{
+				name:      "access token is not a seat code",
+				args:      args{token: users[0], code: users[0]},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid seat code",
+			},
+			{
+				name:      "code signed with another secret",
+				args:      args{token: users[0], code: otherSecretCode},
+				want:      http.StatusBadRequest,
+				wantError: "Invalid seat code",
+			},
+			{
+				name:      "user without booking on the seat",
+				args:      args{token: users[1], code: codes[0]},
+				want:      http.StatusNotFound,
+				wantError: "No booking to check in for this seat",
+			},
+			{
+				name:        "started booking",
+				args:        args{token: users[0], code: codes[0]},
+				want:        http.StatusCreated,
+				wantBooking: started.ID,
+			},
+			{
+				name:      "already checked in",
+				args:      args{token: users[0], code: codes[0]},
+				want:      http.StatusBadRequest,
+				wantError: "Booking already checked in",
+			},
+			{
+				name:        "booking starting soon",
+				args:        args{token: users[0], code: codes[1]},
+				want:        http.StatusCreated,
+				wantBooking: soon.ID,
+			},
+			{
+				name:      "booking starting later",
+				args:      args{token: users[0], code: codes[2]},
+				want:      http.StatusNotFound,
+				wantError: "No booking to check in for this seat",
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveJSON(r, http.MethodPost, "/checkin/qr", tt.args.token, gin.H{"token": tt.args.code})
+				require.Equal(t, tt.want, w.Code, w.Body.String())
+				if tt.wantError != "" {
+					assert.Equal(t, tt.wantError, decodeError(t, w))
+					return
+				}
+				var body struct {
+					BookingID int `json:"booking_id"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, tt.wantBooking, body.BookingID)
+			})
+		}
+	})
+}
+
+func TestCheckOut(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 2)
+			now   = time.Now().In(dateutil.LocVN)
+			seat  = &Seat{Number: "A1"}
+		)
+		require.NoError(t, s.CreateSeat(seat))
+		owner, err := s.GetUserByEmail("user0@example.com")
+		require.NoError(t, err)
+
+		booking := &Booking{UserID: owner.ID, SeatID: seat.ID, StartTime: now.Add(-5 * time.Minute), EndTime: now.Add(2 * time.Hour)}
+		require.NoError(t, s.CreateBookingIfAvailable(booking))
+		path := fmt.Sprintf("/bookings/%d/checkout", booking.ID)
+
+		w := serveJSON(r, http.MethodPost, path, users[0], nil)
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.Equal(t, "Booking is not checked in", decodeError(t, w))
+
+		w = serveJSON(r, http.MethodPost, "/checkin", users[0], gin.H{"seat_id": seat.ID, "booking_id": booking.ID})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		// the seat is held until the booking is checked out
+		from, to := now.Add(2*time.Minute), now.Add(time.Hour)
+		require.Equal(t, http.StatusBadRequest, bookSeat(r, users[1], "A1", from, to))
+
+		w = serveJSON(r, http.MethodPost, path, users[1], nil)
+		require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+		w = serveJSON(r, http.MethodPost, path, users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		stored, err := s.QueryBooking(uint(booking.ID))
+		require.NoError(t, err)
+		assert.Equal(t, BookingStatusCheckedOut, stored.Status)
+		require.NotNil(t, stored.CheckedInAt)
+		require.NotNil(t, stored.CheckedOutAt)
+		assert.WithinDuration(t, time.Now(), *stored.CheckedOutAt, time.Minute)
+		assert.False(t, stored.CheckedOutAt.Before(*stored.CheckedInAt))
+		assert.True(t, stored.EndTime.Equal(*stored.CheckedOutAt))
+
+		history, err := s.FindBookingHistory(booking.ID)
+		require.NoError(t, err)
+		require.NotEmpty(t, history)
+		assert.Equal(t, BookingStatusCheckedOut, history[len(history)-1].Status)
+
+		w = serveJSON(r, http.MethodPost, path, users[0], nil)
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.Equal(t, "Booking is not checked in", decodeError(t, w))
+
+		require.Equal(t, http.StatusOK, bookSeat(r, users[1], "A1", from, to))
+	})
+}
+
+func TestSeatQRCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := NewMemoryStorage()
+	seat := &Seat{Number: "A1"}
+	require.NoError(t, s.CreateSeat(seat))
+	h := newTestHandler(s)
+	r := gin.New()
+	r.GET("/admin/seats/:id/qr", h.SeatQRCode)
+
+	type args struct {
+		query string
+	}
+	tests := []struct {
+		name            string
+		args            args
+		want            int
+		wantContentType string
+	}{
+		{
+			name:            "png by default",
+			args:            args{query: fmt.Sprintf("/admin/seats/%d/qr?size=128", seat.ID)},
+			want:            http.StatusOK,
+			wantContentType: "image/png",
+		},
+		{
+			name:            "svg",
+			args:            args{query: fmt.Sprintf("/admin/seats/%d/qr?format=svg", seat.ID)},
+			want:            http.StatusOK,
+			wantContentType: "image/svg+xml",
+		},
+		{
+			name: "unknown format",
+			args: args{query: fmt.Sprintf("/admin/seats/%d/qr?format=gif", seat.ID)},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "size too large",
+			args: args{query: fmt.Sprintf("/admin/seats/%d/qr?size=10000", seat.ID)},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "unknown seat",
+			args: args{query: "/admin/seats/999/qr"},
+			want: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveJSON(r, http.MethodGet, tt.args.query, "", nil)
+			require.Equal(t, tt.want, w.Code, w.Body.String())
+			if tt.want != http.StatusOK {
+				return
+			}
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+
+			switch tt.wantContentType {
+			case "image/png":
+				img, err := png.Decode(w.Body)
+				require.NoError(t, err)
+				assert.Equal(t, 128, img.Bounds().Dx())
+			case "image/svg+xml":
+				assert.True(t, strings.HasPrefix(w.Body.String(), "<svg"), w.Body.String())
+				assert.Contains(t, w.Body.String(), `width="256"`)
+			}
+		})
+	}
+}
+
+func TestSeatNumberUnique(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		seat := &Seat{Number: "A1"}
+		require.NoError(t, s.CreateSeat(seat))
+		assert.ErrorIs(t, s.CreateSeat(&Seat{Number: "A1"}), gorm.ErrDuplicatedKey)
+
+		// a deleted seat gives its number up until it is restored
+		require.NoError(t, s.DeleteSeat(seat.ID))
+		other := &Seat{Number: "A1"}
+		require.NoError(t, s.CreateSeat(other))
+		assert.ErrorIs(t, s.RestoreSeat(seat.ID), gorm.ErrDuplicatedKey)
+
+		other.Number = "B1"
+		require.NoError(t, s.UpdateSeat(other))
+		require.NoError(t, s.RestoreSeat(seat.ID))
+		other.Number = "A1"
+		assert.ErrorIs(t, s.UpdateSeat(other), gorm.ErrDuplicatedKey)
+	})
+}
+
+func TestSeatNumberUnique_MigrateDuplicates(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ds *DataStorage) {
+		migrator, err := migrate.New(ds.mysqlDB, migrations.All())
+		require.NoError(t, err)
+		// revert the seat number index and the migrations after it
+		var reverted int
+		for _, migration := range migrations.All() {
+			if migration.Version >= 20261018170000 {
+				reverted++
+			}
+		}
+		_, err = migrator.Down(reverted)
+		require.NoError(t, err)
+		for _, number := range []string{"A1", "A1", "B1", "B1", "C1"} {
+			require.NoError(t, ds.CreateSeat(&Seat{Number: number}))
+		}
+
+		_, err = migrator.Up()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "seat numbers used by more than one seat: A1, B1,")
+
+		// the second A1 is renamed and the second B1 deleted
+		seats, err := ds.ListSeats(false)
+		require.NoError(t, err)
+		seen := map[string]bool{}
+		for _, seat := range seats {
+			seat := seat
+			switch {
+			case !seen[seat.Number]:
+				seen[seat.Number] = true
+			case seat.Number == "A1":
+				seat.Number = "A2"
+				require.NoError(t, ds.UpdateSeat(&seat))
+			default:
+				require.NoError(t, ds.DeleteSeat(seat.ID))
+			}
+		}
+		_, err = migrator.Up()
+		require.NoError(t, err)
+	})
+}
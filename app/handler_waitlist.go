@@ -0,0 +1,209 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// JoinWaitlist put the current user in line for a seat, or any seat of a zone, which is
+// fully booked over the requested time range
+func (h *Handler) JoinWaitlist(c *gin.Context) {
+	var request JoinWaitlistRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if (request.SeatNumber == "") == (request.ZoneID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either seat_number or zone_id is required"})
+		return
+	}
+
+	fromTime, err := time.ParseInLocation(timeFormat, request.FromTime, dateutil.LocVN)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from_time format"})
+		return
+	}
+
+	toTime, err := time.ParseInLocation(timeFormat, request.ToTime, dateutil.LocVN)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to_time format"})
+		return
+	}
+
+	if !fromTime.Before(toTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time range"})
+		return
+	}
+
+	now := time.Now()
+	if fromTime.Before(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from_time"})
+		return
+	}
+
+	entry := &WaitlistEntry{UserID: userIDFromContext(c), StartTime: fromTime, EndTime: toTime}
+	if request.SeatNumber != "" {
+		seat, err := h.ds.GetSeatByNumber(request.SeatNumber)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Seat not found"})
+			return
+		}
+		bookings, err := h.ds.FindActiveBookingsBySeatIDs([]uint{seat.ID}, fromTime, toTime)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
+			return
+		}
+		if len(bookings) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Seat is available"})
+			return
+		}
+		entry.SeatID = &seat.ID
+	} else {
+		filter := SeatFilter{ZoneID: request.ZoneID}
+		seats, err := h.ds.FindSeats(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
+			return
+		}
+		if len(seats) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Zone has no seats"})
+			return
+		}
+		available, err := h.ds.FindAvailableSeats(fromTime, toTime, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
+			return
+		}
+		if len(available) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A seat is available in this zone"})
+			return
+		}
+		entry.ZoneID = request.ZoneID
+	}
+
+	entries, err := h.ds.FindWaitlistEntriesByUserID(entry.UserID, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
+		return
+	}
+	for _, other := range entries {
+		if sameWaitlistTarget(other, *entry) && other.StartTime.Before(toTime) && other.EndTime.After(fromTime) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Already on the waitlist"})
+			return
+		}
+	}
+
+	if err := h.ds.CreateWaitlistEntry(entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// MyWaitlist list the waiting and offered entries of the current user
+func (h *Handler) MyWaitlist(c *gin.Context) {
+	entries, err := h.ds.FindWaitlistEntriesByUserID(userIDFromContext(c), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve waitlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// LeaveWaitlist remove a waitlist entry of the current user, the seat it was offered
+// goes to the next user in line
+func (h *Handler) LeaveWaitlist(c *gin.Context) {
+	entry, ok := h.waitlistEntryFromParam(c)
+	if !ok {
+		return
+	}
+
+	if entry.Status != WaitlistStatusWaiting && entry.Status != WaitlistStatusOffered {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Waitlist entry is " + entry.Status})
+		return
+	}
+
+	offered := entry.Status == WaitlistStatusOffered
+	if err := h.ds.UpdateWaitlistEntryStatus(entry, WaitlistStatusCancelled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave waitlist"})
+		return
+	}
+	if offered {
+		h.waitlist.PassOn(*entry, time.Now())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left the waitlist"})
+}
+
+// ClaimWaitlistOffer book the seat offered to a waitlist entry of the current user
+func (h *Handler) ClaimWaitlistOffer(c *gin.Context) {
+	entry, ok := h.waitlistEntryFromParam(c)
+	if !ok {
+		return
+	}
+
+	booking, err := h.ds.ClaimWaitlistOffer(entry, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNoWaitlistOffer):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No offer to claim"})
+		case errors.Is(err, ErrWaitlistOfferExpired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Offer has expired"})
+		default:
+			writeBookingConflictError(c, err, "Failed to claim offer")
+		}
+		return
+	}
+	h.events.Publish(*booking)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Seat booked successfully",
+		"booking_id": booking.ID,
+	})
+}
+
+// waitlistEntryFromParam load the waitlist entry identified by the :id path parameter and
+// owned by the current user, it writes the error response when not found
+func (h *Handler) waitlistEntryFromParam(c *gin.Context) (*WaitlistEntry, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid waitlist entry id"})
+		return nil, false
+	}
+
+	entry, err := h.ds.GetWaitlistEntryByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve waitlist entry"})
+		}
+		return nil, false
+	}
+
+	if entry.UserID != userIDFromContext(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+		return nil, false
+	}
+	return entry, true
+}
+
+// sameWaitlistTarget report whether both entries wait for the same seat or zone
+func sameWaitlistTarget(a, b WaitlistEntry) bool {
+	if a.SeatID != nil && b.SeatID != nil {
+		return *a.SeatID == *b.SeatID
+	}
+	if a.ZoneID != nil && b.ZoneID != nil {
+		return *a.ZoneID == *b.ZoneID
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+const testJWTSecret = "test-secret"
+
+// newTestRouter return a router with the routes of main.go served by s
+func newTestRouter(s Storage) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	var (
+		r        = gin.New()
+		events   = NewSeatEvents(s, s)
+		notifier = NewNotifier(s, s, DefaultNotificationConfig())
+		waitlist = NewWaitlist(s, s, DefaultWaitlistConfig(), notifier)
+		h        = NewHandler(s, testJWTSecret, events, waitlist, notifier)
+		m        = NewMiddleware(testJWTSecret, s)
+		checkin  = NewCheckInService(s, testJWTSecret, DefaultReleaseConfig(), events, waitlist, notifier, NewJobRunner(s, DefaultJobConfig()))
+	)
+	r.GET("/seats", h.ListAvailableSeats)
+	r.GET("/seats/availability", h.SeatAvailability)
+	r.GET("/seats/events", h.SeatEvents)
+	r.GET("/locations", h.ListLocations)
+	r.POST("/register", h.Register)
+	r.POST("/login", h.Login)
+	r.POST("/refresh", h.RefreshToken)
+
+	auth := r.Group("/", m.Authenticate())
+	auth.POST("/checkin", checkin.CheckIn)
+	auth.POST("/checkin/qr", checkin.CheckInQR)
+	auth.POST("/book-seat", h.BookSeat)
+	auth.POST("/book-seat/recurring", h.BookRecurringSeat)
+	auth.GET("/me/bookings", h.MyBookings)
+	auth.PATCH("/bookings/:id", h.UpdateBooking)
+	auth.DELETE("/bookings/:id", h.CancelBooking)
+	auth.GET("/bookings/:id/history", h.BookingHistory)
+	auth.POST("/bookings/:id/checkout", h.CheckOutBooking)
+	auth.DELETE("/booking-series/:id", h.CancelBookingSeries)
+	auth.DELETE("/booking-series/:id/bookings/:booking_id", h.CancelBookingOccurrence)
+	auth.POST("/waitlist", h.JoinWaitlist)
+	auth.GET("/me/waitlist", h.MyWaitlist)
+	auth.DELETE("/waitlist/:id", h.LeaveWaitlist)
+	auth.POST("/waitlist/:id/claim", h.ClaimWaitlistOffer)
+	auth.GET("/me/notifications", h.MyNotifications)
+	auth.POST("/me/notifications/:id/read", h.ReadNotification)
+
+	admin := auth.Group("/admin", m.RequireRole(RoleFacilityAdmin, RoleSuperAdmin))
+	admin.GET("/seats", h.ListSeats)
+	admin.POST("/seats", h.CreateSeat)
+	admin.PUT("/seats/:id", h.UpdateSeat)
+	admin.DELETE("/seats/:id", h.DeleteSeat)
+	admin.POST("/seats/:id/restore", h.RestoreSeat)
+	admin.POST("/buildings", h.CreateBuilding)
+	admin.POST("/floors", h.CreateFloor)
+	admin.POST("/zones", h.CreateZone)
+
+	superAdmin := auth.Group("/admin", m.RequireRole(RoleSuperAdmin))
+	superAdmin.PUT("/users/:id/role", h.UpdateUserRole)
+	return r
+}
+
+// newTestHandler return a handler of s with its own events, waitlist and notifier
+func newTestHandler(s Storage) *Handler {
+	notifier := NewNotifier(s, s, DefaultNotificationConfig())
+	return NewHandler(s, testJWTSecret, NewSeatEvents(s, s), NewWaitlist(s, s, DefaultWaitlistConfig(), notifier), notifier)
+}
+
+// createTestUsers create n employees user0@example.com... and return their access tokens
+func createTestUsers(t *testing.T, users UserRepository, n int) []string {
+	t.Helper()
+	tokens := make([]string, n)
+	for i := range tokens {
+		user := &User{Name: fmt.Sprintf("user %d", i), Email: fmt.Sprintf("user%d@example.com", i), Role: RoleEmployee}
+		require.NoError(t, users.Create(user))
+		token, _, err := issueTokenPair(testJWTSecret, user)
+		require.NoError(t, err)
+		tokens[i] = token
+	}
+	return tokens
+}
+
+// createTestUser create a user of role and return them with their access token
+func createTestUser(t *testing.T, users UserRepository, email, role string) (*User, string) {
+	t.Helper()
+	user := &User{Name: email, Email: email, Role: role}
+	require.NoError(t, users.Create(user))
+	token, _, err := issueTokenPair(testJWTSecret, user)
+	require.NoError(t, err)
+	return user, token
+}
+
+// bookSeat book the seat as the user of token and return the status code
+func bookSeat(r http.Handler, token, seatNumber string, from, to time.Time) int {
+	body, _ := json.Marshal(gin.H{
+		"seat_number": seatNumber,
+		"from_time":   from.Format(timeFormat),
+		"to_time":     to.Format(timeFormat),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/book-seat", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+// serveJSON send a request with body encoded as JSON and return the response
+func serveJSON(r http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if raw, ok := body.(string); ok {
+		reader = bytes.NewReader([]byte(raw))
+	} else {
+		encoded, _ := json.Marshal(body)
+		reader = bytes.NewReader(encoded)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decodeError return the error message of a response
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), w.Body.String())
+	return body.Error
+}
+
+// createTestBooking create a user and their booking of a new seat A1
+func createTestBooking(t *testing.T, s Storage) *Booking {
+	t.Helper()
+	seat := &Seat{Number: "A1"}
+	require.NoError(t, s.CreateSeat(seat))
+	user := &User{Name: "Jane", Email: "jane@example.com", Role: RoleEmployee}
+	require.NoError(t, s.Create(user))
+	start := time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC)
+	booking := &Booking{UserID: user.ID, SeatID: seat.ID, StartTime: start, EndTime: start.Add(8 * time.Hour)}
+	require.NoError(t, s.CreateBookingIfAvailable(booking))
+	return booking
+}
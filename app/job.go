@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Background jobs run through JobRunner
+const (
+	JobReleaseBooking = "release_booking"
+)
+
+type (
+	// JobConfig is the `jobs` section of config.yaml
+	JobConfig struct {
+		// Instance identify this instance as a lease holder, it must be unique among the
+		// instances sharing the database
+		Instance string `mapstructure:"instance"`
+		// LeaseTTL is how long a lease lasts without being renewed, it is how long an
+		// instance which stopped keeps its jobs from running elsewhere
+		LeaseTTL time.Duration `mapstructure:"lease_ttl"`
+	}
+
+	// JobRun is the outcome of one run of a job
+	JobRun struct {
+		StartedAt time.Time
+		Duration  time.Duration
+		Processed int
+		Err       error
+	}
+
+	// JobFunc run a job once, it returns how many records it processed
+	JobFunc func(ctx context.Context, now time.Time) (int, error)
+
+	// JobRunner run every job on one instance at a time. The instance holding the lease of
+	// a job in the database runs it and renews the lease before every run, another one
+	// takes over once the lease expires. Leases rely on the clocks of the instances being
+	// in sync to well within the lease TTL
+	JobRunner struct {
+		jobs   JobRepository
+		config JobConfig
+	}
+)
+
+// DefaultJobConfig identify the instance by its host name and process, with a random
+// suffix in case containers share those, and fail over after 3 minutes
+func DefaultJobConfig() JobConfig {
+	return JobConfig{
+		Instance: defaultJobInstance(),
+		LeaseTTL: 3 * time.Minute,
+	}
+}
+
+// Validate check the instance and lease of the config
+func (c JobConfig) Validate() error {
+	if c.Instance == "" {
+		return errors.New("jobs.instance is required")
+	}
+	if c.LeaseTTL <= 0 {
+		return errors.New("jobs.lease_ttl must be positive")
+	}
+	return nil
+}
+
+func defaultJobInstance() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+func NewJobRunner(jobs JobRepository, config JobConfig) *JobRunner {
+	return &JobRunner{jobs: jobs, config: config}
+}
+
+// Run run fn every interval while this instance holds the lease of the job, until ctx is
+// cancelled. The interval must be shorter than the lease TTL or the lease expires
+// between runs. The lease is given up on return so that another instance takes over
+// at once
+func (r *JobRunner) Run(ctx context.Context, name string, interval time.Duration, fn JobFunc) {
+	logger := log.WithFields(log.Fields{"job": name, "instance": r.config.Instance})
+	logger.Printf("start job")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	leader := false
+	for {
+		now := time.Now()
+		acquired, err := r.jobs.AcquireJobLease(name, r.config.Instance, now, r.config.LeaseTTL)
+		if err != nil {
+			// without the lease renewed another instance may be running the job already
+			logger.WithError(err).Error("acquire job lease fail")
+		}
+		if acquired != leader {
+			leader = acquired
+			if leader {
+				logger.Info("job lease acquired")
+			} else {
+				logger.Info("job lease lost")
+			}
+		}
+		if leader {
+			r.run(ctx, name, now, fn)
+		}
+
+		select {
+		case <-ctx.Done():
+			if leader {
+				if err := r.jobs.ReleaseJobLease(name, r.config.Instance, time.Now()); err != nil {
+					logger.WithError(err).Error("release job lease fail")
+				}
+			}
+			logger.Printf("stop job")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run run fn once and record its metrics
+func (r *JobRunner) run(ctx context.Context, name string, now time.Time, fn JobFunc) {
+	processed, err := fn(ctx, now)
+	run := JobRun{StartedAt: now, Duration: time.Since(now), Processed: processed, Err: err}
+	if err != nil {
+		log.WithError(err).WithField("job", name).Error("job fail")
+	}
+	if err := r.jobs.RecordJobRun(name, run); err != nil {
+		log.WithError(err).WithField("job", name).Error("record job run fail")
+	}
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireJobLease(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			now = time.Now()
+			ttl = 30 * time.Second
+		)
+		tests := []struct {
+			name   string
+			holder string
+			at     time.Time
+			want   bool
+		}{
+			{name: "first holder", holder: "a", at: now, want: true},
+			{name: "held by another", holder: "b", at: now.Add(time.Second), want: false},
+			{name: "renewed", holder: "a", at: now.Add(20 * time.Second), want: true},
+			{name: "renewed lease not expired", holder: "b", at: now.Add(40 * time.Second), want: false},
+			{name: "fail over once expired", holder: "b", at: now.Add(50 * time.Second), want: true},
+			{name: "lost", holder: "a", at: now.Add(60 * time.Second), want: false},
+		}
+		for _, tt := range tests {
+			acquired, err := s.AcquireJobLease(JobReleaseBooking, tt.holder, tt.at, ttl)
+			require.NoError(t, err, tt.name)
+			assert.Equal(t, tt.want, acquired, tt.name)
+		}
+
+		released := now.Add(61 * time.Second)
+		require.NoError(t, s.ReleaseJobLease(JobReleaseBooking, "a", released))
+		acquired, err := s.AcquireJobLease(JobReleaseBooking, "a", released, ttl)
+		require.NoError(t, err)
+		assert.False(t, acquired, "only the holder gives the lease up")
+		require.NoError(t, s.ReleaseJobLease(JobReleaseBooking, "b", released))
+		acquired, err = s.AcquireJobLease(JobReleaseBooking, "a", released, ttl)
+		require.NoError(t, err)
+		assert.True(t, acquired, "a lease given up is taken over at once")
+	})
+}
+
+func TestJobRunner(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var runs []string
+		job := func(instance string, processed int, err error) JobFunc {
+			return func(context.Context, time.Time) (int, error) {
+				runs = append(runs, instance)
+				return processed, err
+			}
+		}
+		runners := map[string]*JobRunner{
+			"a": NewJobRunner(s, JobConfig{Instance: "a", LeaseTTL: time.Minute}),
+			"b": NewJobRunner(s, JobConfig{Instance: "b", LeaseTTL: time.Minute}),
+		}
+
+		// another instance holds the lease
+		acquired, err := s.AcquireJobLease("sweep", "c", time.Now(), time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+		runners["a"].Run(ctx, "sweep", time.Second, job("a", 1, nil))
+		assert.Empty(t, runs)
+
+		require.NoError(t, s.ReleaseJobLease("sweep", "c", time.Now()))
+		runners["a"].Run(ctx, "sweep", time.Second, job("a", 3, nil))
+		// a gave the lease up when it stopped
+		runners["b"].Run(ctx, "sweep", time.Second, job("b", 0, errors.New("database is down")))
+		assert.Equal(t, []string{"a", "b"}, runs)
+
+		jobs, err := s.ListJobs()
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "sweep", jobs[0].Name)
+		assert.Equal(t, "b", jobs[0].Holder)
+		assert.Equal(t, int64(2), jobs[0].Runs)
+		assert.Equal(t, int64(1), jobs[0].Failures)
+		assert.Equal(t, int64(3), jobs[0].TotalProcessed)
+		assert.Zero(t, jobs[0].LastProcessed)
+		assert.Equal(t, "database is down", jobs[0].LastError)
+		assert.NotNil(t, jobs[0].LastRunAt)
+	})
+}
+
+func TestListJobs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := NewMemoryStorage()
+	h := newTestHandler(s)
+	r := gin.New()
+	r.GET("/admin/jobs", h.ListJobs)
+
+	now := time.Now()
+	_, err := s.AcquireJobLease(JobReleaseBooking, "a", now, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.RecordJobRun(JobReleaseBooking, JobRun{StartedAt: now, Duration: 25 * time.Millisecond, Processed: 4}))
+	_, err = s.AcquireJobLease("other", "a", now.Add(-time.Hour), time.Minute)
+	require.NoError(t, err)
+
+	w := serveJSON(r, http.MethodGet, "/admin/jobs", "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Jobs []JobStatus `json:"jobs"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Jobs, 2)
+	assert.Equal(t, "other", body.Jobs[0].Name)
+	assert.False(t, body.Jobs[0].Leased)
+	assert.Equal(t, JobReleaseBooking, body.Jobs[1].Name)
+	assert.True(t, body.Jobs[1].Leased)
+	assert.Equal(t, "a", body.Jobs[1].Holder)
+	assert.Equal(t, 4, body.Jobs[1].LastProcessed)
+	assert.Equal(t, int64(25), body.Jobs[1].LastDurationMs)
+}
@@ -0,0 +1,1405 @@
+package app
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryStorage is a Storage kept in memory with the same semantics as DataStorage,
+// it is meant for tests. Records are copied in and out so callers never share them
+type MemoryStorage struct {
+	// mu is a no-op in the view a transaction runs with, the transaction holds the lock
+	mu sync.Locker
+	*memoryRecords
+}
+
+type memoryRecords struct {
+	lastID        map[string]uint
+	users         map[uint]User
+	buildings     map[uint]Building
+	floors        map[uint]Floor
+	zones         map[uint]Zone
+	seats         map[uint]Seat
+	series        map[uint]BookingSeries
+	bookings      map[int]Booking
+	history       []BookingStatusHistory
+	waitlist      map[uint]WaitlistEntry
+	notifications map[uint]Notification
+	reminderJobs  map[uint]ReminderJob
+	jobs          map[string]Job
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		mu: &sync.Mutex{},
+		memoryRecords: &memoryRecords{
+			lastID:        map[string]uint{},
+			users:         map[uint]User{},
+			buildings:     map[uint]Building{},
+			floors:        map[uint]Floor{},
+			zones:         map[uint]Zone{},
+			seats:         map[uint]Seat{},
+			series:        map[uint]BookingSeries{},
+			bookings:      map[int]Booking{},
+			waitlist:      map[uint]WaitlistEntry{},
+			notifications: map[uint]Notification{},
+			reminderJobs:  map[uint]ReminderJob{},
+			jobs:          map[string]Job{},
+		},
+	}
+}
+
+// nextID return the next auto increment id of table
+func (ms *MemoryStorage) nextID(table string) uint {
+	ms.lastID[table]++
+	return ms.lastID[table]
+}
+
+// WithTransaction run fn with a view of the records while holding the lock, other
+// callers wait until it returns. The records are restored when fn fails
+func (ms *MemoryStorage) WithTransaction(fn func(s Storage) error) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	snapshot := ms.memoryRecords.clone()
+	if err := fn(&MemoryStorage{mu: noLock{}, memoryRecords: ms.memoryRecords}); err != nil {
+		*ms.memoryRecords = *snapshot
+		return err
+	}
+	return nil
+}
+
+// clone copy the records, the records themselves are values so a shallow copy is enough
+func (r *memoryRecords) clone() *memoryRecords {
+	return &memoryRecords{
+		lastID:        maps.Clone(r.lastID),
+		users:         maps.Clone(r.users),
+		buildings:     maps.Clone(r.buildings),
+		floors:        maps.Clone(r.floors),
+		zones:         maps.Clone(r.zones),
+		seats:         maps.Clone(r.seats),
+		series:        maps.Clone(r.series),
+		bookings:      maps.Clone(r.bookings),
+		history:       slices.Clone(r.history),
+		waitlist:      maps.Clone(r.waitlist),
+		notifications: maps.Clone(r.notifications),
+		reminderJobs:  maps.Clone(r.reminderJobs),
+		jobs:          maps.Clone(r.jobs),
+	}
+}
+
+// noLock is the lock of the view a transaction runs with
+type noLock struct{}
+
+func (noLock) Lock()   {}
+func (noLock) Unlock() {}
+
+func (ms *MemoryStorage) Create(user *User) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, u := range ms.users {
+		if u.Email == user.Email {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	if user.Role == "" {
+		user.Role = RoleEmployee
+	}
+	now := time.Now().UTC()
+	user.ID, user.CreatedAt, user.UpdatedAt = ms.nextID("users"), now, now
+	ms.users[user.ID] = *user
+	return nil
+}
+
+func (ms *MemoryStorage) GetUserByEmail(email string) (*User, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, id := range sortedKeys(ms.users) {
+		if user := ms.users[id]; user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (ms *MemoryStorage) GetUserByID(id uint) (*User, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	user, ok := ms.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+func (ms *MemoryStorage) UpdateUser(user *User) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if stored, ok := ms.users[user.ID]; ok {
+		stored.Name, stored.Role = user.Name, user.Role
+		ms.users[user.ID] = stored
+	}
+	return nil
+}
+
+func (ms *MemoryStorage) UpdateUserRole(userID uint, role string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if user, ok := ms.users[userID]; ok {
+		user.Role = role
+		ms.users[userID] = user
+	}
+	return nil
+}
+
+func (ms *MemoryStorage) RecordFailedLogin(userID uint, maxAttempts int, lockUntil time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	user, ok := ms.users[userID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.FailedLoginAttempts++
+	if user.FailedLoginAttempts >= maxAttempts {
+		lockUntil = lockUntil.UTC()
+		user.FailedLoginAttempts, user.LockedUntil = 0, &lockUntil
+	}
+	ms.users[userID] = user
+	return nil
+}
+
+func (ms *MemoryStorage) ResetFailedLogins(userID uint) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if user, ok := ms.users[userID]; ok {
+		user.FailedLoginAttempts, user.LockedUntil = 0, nil
+		ms.users[userID] = user
+	}
+	return nil
+}
+
+func (ms *MemoryStorage) GetSeatByNumber(number string) (*Seat, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, id := range sortedKeys(ms.seats) {
+		if seat := ms.seats[id]; seat.Number == number && !seat.DeletedAt.Valid {
+			return &seat, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (ms *MemoryStorage) GetSeatByID(id uint, unscoped bool) (*Seat, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	seat, ok := ms.seats[id]
+	if !ok || (seat.DeletedAt.Valid && !unscoped) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &seat, nil
+}
+
+func (ms *MemoryStorage) ListSeats(unscoped bool) ([]Seat, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var seats []Seat
+	for _, seat := range ms.seats {
+		if seat.DeletedAt.Valid && !unscoped {
+			continue
+		}
+		seats = append(seats, ms.withLocation(seat))
+	}
+	sortSeats(seats)
+	return seats, nil
+}
+
+func (ms *MemoryStorage) FindAvailableSeats(fromTime, toTime time.Time, filter SeatFilter) ([]Seat, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	booked := map[uint]bool{}
+	for _, b := range ms.bookings {
+		if !b.IsActive() {
+			continue
+		}
+		if (b.StartTime.Before(toTime) && b.EndTime.After(fromTime)) ||
+			(!b.StartTime.Before(fromTime) && !b.EndTime.After(toTime)) {
+			booked[b.SeatID] = true
+		}
+	}
+
+	var seats []Seat
+	for _, seat := range ms.seats {
+		if seat.DeletedAt.Valid || booked[seat.ID] || !ms.matchesSeatFilter(seat, filter) {
+			continue
+		}
+		seats = append(seats, ms.withLocation(seat))
+	}
+	sortSeats(seats)
+	return seats, nil
+}
+
+func (ms *MemoryStorage) FindSeats(filter SeatFilter) ([]Seat, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var seats []Seat
+	for _, seat := range ms.seats {
+		if seat.DeletedAt.Valid || !ms.matchesSeatFilter(seat, filter) {
+			continue
+		}
+		seats = append(seats, ms.withLocation(seat))
+	}
+	sortSeats(seats)
+	return seats, nil
+}
+
+// matchesSeatFilter is applySeatFilter for a single seat
+func (ms *MemoryStorage) matchesSeatFilter(seat Seat, filter SeatFilter) bool {
+	var zone Zone
+	var floor Floor
+	if seat.ZoneID != nil {
+		zone = ms.zones[*seat.ZoneID]
+		floor = ms.floors[zone.FloorID]
+	}
+
+	switch {
+	case filter.BuildingID != nil && (seat.ZoneID == nil || floor.BuildingID != *filter.BuildingID):
+		return false
+	case filter.FloorID != nil && (seat.ZoneID == nil || zone.FloorID != *filter.FloorID):
+		return false
+	case filter.ZoneID != nil && (seat.ZoneID == nil || *seat.ZoneID != *filter.ZoneID):
+		return false
+	case filter.Type != "" && seat.Type != filter.Type:
+		return false
+	case filter.DualMonitor != nil && seat.DualMonitor != *filter.DualMonitor:
+		return false
+	case filter.NearWindow != nil && seat.NearWindow != *filter.NearWindow:
+		return false
+	case filter.Accessible != nil && seat.Accessible != *filter.Accessible:
+		return false
+	}
+	return true
+}
+
+// withLocation preload the zone, floor and building of the seat
+func (ms *MemoryStorage) withLocation(seat Seat) Seat {
+	seat.Zone = nil
+	if seat.ZoneID == nil {
+		return seat
+	}
+	zone, ok := ms.zones[*seat.ZoneID]
+	if !ok {
+		return seat
+	}
+	if floor, ok := ms.floors[zone.FloorID]; ok {
+		if building, ok := ms.buildings[floor.BuildingID]; ok {
+			building.Floors = nil
+			floor.Building = &building
+		}
+		floor.Zones = nil
+		zone.Floor = &floor
+	}
+	seat.Zone = &zone
+	return seat
+}
+
+func (ms *MemoryStorage) CreateSeat(seat *Seat) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.isSeatNumberTaken(seat.Number, 0) {
+		return gorm.ErrDuplicatedKey
+	}
+	if seat.Type == "" {
+		seat.Type = SeatTypeDesk
+	}
+	now := time.Now().UTC()
+	seat.ID, seat.CreatedAt, seat.UpdatedAt = ms.nextID("seats"), now, now
+	ms.seats[seat.ID] = *seat
+	return nil
+}
+
+func (ms *MemoryStorage) UpdateSeat(seat *Seat) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if !seat.DeletedAt.Valid && ms.isSeatNumberTaken(seat.Number, seat.ID) {
+		return gorm.ErrDuplicatedKey
+	}
+	seat.UpdatedAt = time.Now().UTC()
+	stored := *seat
+	stored.Zone = nil
+	ms.seats[seat.ID] = stored
+	return nil
+}
+
+func (ms *MemoryStorage) DeleteSeat(id uint) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if seat, ok := ms.seats[id]; ok && !seat.DeletedAt.Valid {
+		seat.DeletedAt = gorm.DeletedAt{Time: time.Now().UTC(), Valid: true}
+		ms.seats[id] = seat
+	}
+	return nil
+}
+
+func (ms *MemoryStorage) RestoreSeat(id uint) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if seat, ok := ms.seats[id]; ok {
+		if ms.isSeatNumberTaken(seat.Number, id) {
+			return gorm.ErrDuplicatedKey
+		}
+		seat.DeletedAt = gorm.DeletedAt{}
+		ms.seats[id] = seat
+	}
+	return nil
+}
+
+// isSeatNumberTaken report whether a seat other than id and not deleted has the number,
+// the caller must hold ms.mu
+func (ms *MemoryStorage) isSeatNumberTaken(number string, id uint) bool {
+	for _, seat := range ms.seats {
+		if seat.ID != id && seat.Number == number && !seat.DeletedAt.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+func (ms *MemoryStorage) ListLocations() ([]Building, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var buildings []Building
+	for _, building := range ms.buildings {
+		building.Floors = nil
+		for _, floor := range ms.floors {
+			if floor.BuildingID != building.ID {
+				continue
+			}
+			floor.Zones = nil
+			for _, zone := range ms.zones {
+				if zone.FloorID == floor.ID {
+					floor.Zones = append(floor.Zones, zone)
+				}
+			}
+			sort.Slice(floor.Zones, func(i, j int) bool { return floor.Zones[i].Name < floor.Zones[j].Name })
+			building.Floors = append(building.Floors, floor)
+		}
+		sort.Slice(building.Floors, func(i, j int) bool { return building.Floors[i].Level < building.Floors[j].Level })
+		buildings = append(buildings, building)
+	}
+	sort.Slice(buildings, func(i, j int) bool { return buildings[i].Name < buildings[j].Name })
+	return buildings, nil
+}
+
+func (ms *MemoryStorage) GetBuildingByID(id uint) (*Building, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	building, ok := ms.buildings[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &building, nil
+}
+
+func (ms *MemoryStorage) GetFloorByID(id uint) (*Floor, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	floor, ok := ms.floors[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &floor, nil
+}
+
+func (ms *MemoryStorage) GetZoneByID(id uint) (*Zone, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	zone, ok := ms.zones[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &zone, nil
+}
+
+func (ms *MemoryStorage) CreateBuilding(building *Building) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, stored := range ms.buildings {
+		if stored.Name == building.Name {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	now := time.Now().UTC()
+	building.ID, building.CreatedAt, building.UpdatedAt = ms.nextID("buildings"), now, now
+	ms.buildings[building.ID] = *building
+	return nil
+}
+
+func (ms *MemoryStorage) CreateFloor(floor *Floor) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.isFloorNameTaken(floor.BuildingID, floor.Name, 0) {
+		return gorm.ErrDuplicatedKey
+	}
+	now := time.Now().UTC()
+	floor.ID, floor.CreatedAt, floor.UpdatedAt = ms.nextID("floors"), now, now
+	ms.floors[floor.ID] = *floor
+	return nil
+}
+
+func (ms *MemoryStorage) UpdateFloor(floor *Floor) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if stored, ok := ms.floors[floor.ID]; ok {
+		if ms.isFloorNameTaken(stored.BuildingID, floor.Name, floor.ID) {
+			return gorm.ErrDuplicatedKey
+		}
+		stored.Name, stored.Level = floor.Name, floor.Level
+		ms.floors[floor.ID] = stored
+	}
+	return nil
+}
+
+func (ms *MemoryStorage) CreateZone(zone *Zone) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, stored := range ms.zones {
+		if stored.FloorID == zone.FloorID && stored.Name == zone.Name {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	now := time.Now().UTC()
+	zone.ID, zone.CreatedAt, zone.UpdatedAt = ms.nextID("zones"), now, now
+	ms.zones[zone.ID] = *zone
+	return nil
+}
+
+// isFloorNameTaken report whether another floor than id of the building is named name
+func (ms *MemoryStorage) isFloorNameTaken(buildingID uint, name string, id uint) bool {
+	for _, stored := range ms.floors {
+		if stored.BuildingID == buildingID && stored.Name == name && stored.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (ms *MemoryStorage) QueryBooking(bookingId uint) (*Booking, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	booking, ok := ms.bookings[int(bookingId)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &booking, nil
+}
+
+func (ms *MemoryStorage) CreateBookingIfAvailable(booking *Booking) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	booking.ID = 0
+	if err := ms.checkBookingConflicts(booking, time.Now()); err != nil {
+		return err
+	}
+	ms.createBooking(booking)
+	return nil
+}
+
+func (ms *MemoryStorage) UpdateBookingIfAvailable(booking *Booking) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if stored, ok := ms.bookings[booking.ID]; !ok || stored.CheckedIn || !stored.IsActive() {
+		return ErrBookingNotActive
+	}
+	if err := ms.checkBookingConflicts(booking, time.Now()); err != nil {
+		return err
+	}
+	booking.Status = BookingStatusModified
+	if stored, ok := ms.bookings[booking.ID]; ok {
+		stored.SeatID = booking.SeatID
+		stored.StartTime, stored.EndTime = booking.StartTime.UTC(), booking.EndTime.UTC()
+		stored.Status = booking.Status
+		ms.bookings[booking.ID] = stored
+	}
+	ms.addBookingHistory(booking)
+	return nil
+}
+
+func (ms *MemoryStorage) CreateBookingSeriesIfAvailable(series *BookingSeries, bookings []Booking) ([]error, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	conflicts := make([]error, len(bookings))
+	for i := range bookings {
+		bookings[i].ID = 0
+		conflicts[i] = ms.checkBookingConflicts(&bookings[i], time.Now())
+	}
+
+	// occurrences of one series may overlap each other, only the first of them is booked
+	booked := 0
+	for i := range bookings {
+		if conflicts[i] != nil {
+			continue
+		}
+		for j := 0; j < i; j++ {
+			if conflicts[j] == nil && overlaps(bookings[j], bookings[i].StartTime, bookings[i].EndTime) {
+				conflicts[i] = ErrUserAlreadyBooked
+				break
+			}
+		}
+		if conflicts[i] == nil {
+			booked++
+		}
+	}
+	if booked == 0 {
+		return conflicts, ErrNoOccurrenceBooked
+	}
+
+	series.ID, series.CreatedAt = ms.nextID("booking_series"), time.Now().UTC()
+	ms.series[series.ID] = *series
+	for i := range bookings {
+		if conflicts[i] == nil {
+			bookings[i].SeriesID = &series.ID
+			ms.createBooking(&bookings[i])
+		}
+	}
+	return conflicts, nil
+}
+
+func (ms *MemoryStorage) UpdateBookingStatus(booking *Booking, status string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if stored, ok := ms.bookings[booking.ID]; !ok || !stored.IsActive() {
+		return ErrBookingNotActive
+	}
+	ms.updateBookingStatus(booking, status)
+	return nil
+}
+
+func (ms *MemoryStorage) ReseverBooking(booking *Booking) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	stored, ok := ms.bookings[booking.ID]
+	if !ok || stored.CheckedIn || !stored.IsActive() {
+		return ErrBookingNotActive
+	}
+	now := time.Now().UTC()
+	booking.CheckedIn, booking.CheckedInAt = true, &now
+	stored.CheckedIn, stored.CheckedInAt = true, &now
+	ms.bookings[booking.ID] = stored
+	ms.updateBookingStatus(booking, BookingStatusCheckedIn)
+	return nil
+}
+
+func (ms *MemoryStorage) CheckOutBooking(booking *Booking, now time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	stored, ok := ms.bookings[booking.ID]
+	if !ok || stored.Status != BookingStatusCheckedIn {
+		return ErrBookingNotActive
+	}
+	now = now.UTC()
+	booking.EndTime, booking.CheckedOutAt = now, &now
+	stored.EndTime, stored.CheckedOutAt = now, &now
+	ms.bookings[booking.ID] = stored
+	ms.updateBookingStatus(booking, BookingStatusCheckedOut)
+	return nil
+}
+
+func (ms *MemoryStorage) ReleaseBooking(config ReleaseConfig, now time.Time) ([]Booking, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var released []Booking
+	for _, id := range sortedKeys(ms.bookings) {
+		booking := ms.bookings[id]
+		if booking.CheckedIn || !booking.IsActive() {
+			continue
+		}
+		seat, ok := ms.seats[booking.SeatID]
+		if !ok {
+			continue
+		}
+		policy := config.PolicyFor(seat.ZoneID, seat.Type)
+		if !booking.StartTime.Before(now.Add(-policy.GracePeriod)) {
+			continue
+		}
+
+		ms.updateBookingStatus(&booking, BookingStatusReleased)
+		if policy.Action == ReleaseActionDelete {
+			delete(ms.bookings, booking.ID)
+		}
+		released = append(released, booking)
+	}
+	return released, nil
+}
+
+func (ms *MemoryStorage) FindUserSeatBooking(userID, seatID uint, fromTime, toTime time.Time) (*Booking, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var found *Booking
+	for _, id := range sortedKeys(ms.bookings) {
+		b := ms.bookings[id]
+		if b.UserID != userID || b.SeatID != seatID || !b.IsActive() || !b.StartTime.Before(toTime) || !b.EndTime.After(fromTime) {
+			continue
+		}
+		if found == nil || b.StartTime.Before(found.StartTime) {
+			found = &b
+		}
+	}
+	if found == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return found, nil
+}
+
+func (ms *MemoryStorage) FindActiveBookingsBySeatIDs(seatIDs []uint, fromTime, toTime time.Time) ([]Booking, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	wanted := map[uint]bool{}
+	for _, id := range seatIDs {
+		wanted[id] = true
+	}
+	var bookings []Booking
+	for _, id := range sortedKeys(ms.bookings) {
+		b := ms.bookings[id]
+		if wanted[b.SeatID] && b.IsActive() && b.StartTime.Before(toTime) && b.EndTime.After(fromTime) {
+			bookings = append(bookings, b)
+		}
+	}
+	sort.SliceStable(bookings, func(i, j int) bool { return bookings[i].StartTime.Before(bookings[j].StartTime) })
+	return bookings, nil
+}
+
+func (ms *MemoryStorage) FindBookingHistory(bookingID int) ([]BookingStatusHistory, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var history []BookingStatusHistory
+	for _, h := range ms.history {
+		if h.BookingID == bookingID {
+			history = append(history, h)
+		}
+	}
+	return history, nil
+}
+
+func (ms *MemoryStorage) FindBookingsByUserID(userID uint, filter string, after *bookingCursor, limit int, now time.Time) ([]BookingView, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ascending := filter == BookingFilterUpcoming
+	var views []BookingView
+	for _, booking := range ms.bookings {
+		if booking.UserID != userID {
+			continue
+		}
+		switch filter {
+		case BookingFilterUpcoming:
+			if !booking.EndTime.After(now) || !booking.IsActive() {
+				continue
+			}
+		case BookingFilterPast:
+			if booking.EndTime.After(now) {
+				continue
+			}
+		case BookingFilterCheckedIn:
+			if !booking.CheckedIn {
+				continue
+			}
+		}
+		if after != nil && !isAfterCursor(booking, *after, ascending) {
+			continue
+		}
+
+		seat, ok := ms.seats[booking.SeatID]
+		if !ok {
+			continue
+		}
+		views = append(views, ms.bookingView(booking, seat))
+	}
+
+	sort.Slice(views, func(i, j int) bool {
+		a, b := views[i], views[j]
+		if !a.StartTime.Equal(b.StartTime) {
+			return a.StartTime.Before(b.StartTime) == ascending
+		}
+		return (a.ID < b.ID) == ascending
+	})
+	if len(views) > limit {
+		views = views[:limit]
+	}
+	return views, nil
+}
+
+// isAfterCursor report whether booking comes after the cursor in the listing order
+func isAfterCursor(booking Booking, after bookingCursor, ascending bool) bool {
+	if booking.StartTime.Equal(after.StartTime) {
+		if ascending {
+			return booking.ID > after.ID
+		}
+		return booking.ID < after.ID
+	}
+	return booking.StartTime.After(after.StartTime) == ascending
+}
+
+// bookingView join the booking with its seat and location
+func (ms *MemoryStorage) bookingView(booking Booking, seat Seat) BookingView {
+	view := BookingView{
+		ID:           booking.ID,
+		SeriesID:     booking.SeriesID,
+		SeatID:       booking.SeatID,
+		SeatNumber:   seat.Number,
+		StartTime:    booking.StartTime,
+		EndTime:      booking.EndTime,
+		CheckedIn:    booking.CheckedIn,
+		Status:       booking.Status,
+		CheckedInAt:  booking.CheckedInAt,
+		CheckedOutAt: booking.CheckedOutAt,
+	}
+	if seat.ZoneID == nil {
+		return view
+	}
+	zone, ok := ms.zones[*seat.ZoneID]
+	if !ok {
+		return view
+	}
+	view.ZoneID, view.ZoneName = &zone.ID, &zone.Name
+	floor, ok := ms.floors[zone.FloorID]
+	if !ok {
+		return view
+	}
+	view.FloorID, view.FloorName = &floor.ID, &floor.Name
+	if building, ok := ms.buildings[floor.BuildingID]; ok {
+		view.BuildingID, view.BuildingName = &building.ID, &building.Name
+	}
+	return view
+}
+
+func (ms *MemoryStorage) ExportBookings(filter BookingExportFilter, fn func(row BookingExportRow) error) error {
+	ms.mu.Lock()
+	var rows []BookingExportRow
+	for _, booking := range ms.bookings {
+		user, ok := ms.users[booking.UserID]
+		if !ok {
+			continue
+		}
+		seat, ok := ms.seats[booking.SeatID]
+		if !ok {
+			continue
+		}
+		switch {
+		case filter.From != nil && booking.StartTime.Before(*filter.From),
+			filter.To != nil && !booking.StartTime.Before(*filter.To),
+			filter.UserID != nil && booking.UserID != *filter.UserID,
+			filter.SeatNumber != "" && seat.Number != filter.SeatNumber:
+			continue
+		}
+		rows = append(rows, BookingExportRow{
+			ID:           booking.ID,
+			UserID:       user.ID,
+			UserEmail:    user.Email,
+			UserName:     user.Name,
+			SeatID:       seat.ID,
+			SeatNumber:   seat.Number,
+			StartTime:    booking.StartTime,
+			EndTime:      booking.EndTime,
+			Status:       booking.Status,
+			CheckedIn:    booking.CheckedIn,
+			CheckedInAt:  booking.CheckedInAt,
+			CheckedOutAt: booking.CheckedOutAt,
+		})
+	}
+	ms.mu.Unlock()
+
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].StartTime.Equal(rows[j].StartTime) {
+			return rows[i].StartTime.Before(rows[j].StartTime)
+		}
+		return rows[i].ID < rows[j].ID
+	})
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ms *MemoryStorage) ReportBookings(from, to time.Time, fn func(row ReportBooking) error) error {
+	inRange := func(start time.Time) bool {
+		return !start.Before(from) && start.Before(to)
+	}
+
+	ms.mu.Lock()
+	var rows []ReportBooking
+	for _, id := range sortedKeys(ms.bookings) {
+		booking := ms.bookings[id]
+		if inRange(booking.StartTime) {
+			rows = append(rows, ReportBooking{
+				ID:           booking.ID,
+				SeatID:       booking.SeatID,
+				StartTime:    booking.StartTime,
+				EndTime:      booking.EndTime,
+				Status:       booking.Status,
+				CheckedIn:    booking.CheckedIn,
+				CheckedInAt:  booking.CheckedInAt,
+				CheckedOutAt: booking.CheckedOutAt,
+			})
+		}
+	}
+	for _, h := range ms.history {
+		if _, ok := ms.bookings[h.BookingID]; ok || h.Status != BookingStatusReleased || !inRange(h.StartTime) {
+			continue
+		}
+		rows = append(rows, ReportBooking{
+			ID:        h.BookingID,
+			SeatID:    h.SeatID,
+			StartTime: h.StartTime,
+			EndTime:   h.EndTime,
+			Status:    h.Status,
+		})
+	}
+	ms.mu.Unlock()
+
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ms *MemoryStorage) CountUpcomingBookingsBySeatID(seatID uint, now time.Time) (int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var count int64
+	for _, booking := range ms.bookings {
+		if booking.SeatID == seatID && booking.IsActive() && booking.EndTime.After(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (ms *MemoryStorage) GetBookingSeriesByID(id uint) (*BookingSeries, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	series, ok := ms.series[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &series, nil
+}
+
+func (ms *MemoryStorage) CancelUpcomingBookingsBySeriesID(seriesID uint, now time.Time) ([]Booking, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var cancelled []Booking
+	for _, id := range sortedKeys(ms.bookings) {
+		booking := ms.bookings[id]
+		if booking.SeriesID == nil || *booking.SeriesID != seriesID || !booking.IsActive() || !booking.StartTime.After(now) {
+			continue
+		}
+		ms.updateBookingStatus(&booking, BookingStatusCancelled)
+		cancelled = append(cancelled, booking)
+	}
+	sort.SliceStable(cancelled, func(i, j int) bool {
+		return cancelled[i].StartTime.Before(cancelled[j].StartTime)
+	})
+	return cancelled, nil
+}
+
+func (ms *MemoryStorage) CreateWaitlistEntry(entry *WaitlistEntry) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	entry.ID, entry.Status, entry.CreatedAt = ms.nextID("waitlist_entries"), WaitlistStatusWaiting, time.Now().UTC()
+	entry.StartTime, entry.EndTime = entry.StartTime.UTC(), entry.EndTime.UTC()
+	ms.waitlist[entry.ID] = *entry
+	return nil
+}
+
+func (ms *MemoryStorage) GetWaitlistEntryByID(id uint) (*WaitlistEntry, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	entry, ok := ms.waitlist[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &entry, nil
+}
+
+func (ms *MemoryStorage) FindWaitlistEntriesByUserID(userID uint, now time.Time) ([]WaitlistEntry, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var entries []WaitlistEntry
+	for _, id := range sortedKeys(ms.waitlist) {
+		entry := ms.waitlist[id]
+		if entry.UserID == userID && entry.EndTime.After(now) &&
+			(entry.Status == WaitlistStatusWaiting || entry.Status == WaitlistStatusOffered) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (ms *MemoryStorage) UpdateWaitlistEntryStatus(entry *WaitlistEntry, status string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	entry.Status = status
+	if stored, ok := ms.waitlist[entry.ID]; ok {
+		stored.Status = status
+		ms.waitlist[entry.ID] = stored
+	}
+	return nil
+}
+
+func (ms *MemoryStorage) OfferWaitlistSeat(seat *Seat, fromTime, toTime, now, expiresAt time.Time) ([]WaitlistEntry, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var offered []WaitlistEntry
+	for _, id := range sortedKeys(ms.waitlist) {
+		entry := ms.waitlist[id]
+		if entry.Status != WaitlistStatusWaiting || !entry.StartTime.Before(toTime) ||
+			!entry.EndTime.After(fromTime) || !entry.EndTime.After(now) {
+			continue
+		}
+		wantsSeat := entry.SeatID != nil && *entry.SeatID == seat.ID
+		wantsZone := entry.ZoneID != nil && seat.ZoneID != nil && *entry.ZoneID == *seat.ZoneID
+		if !wantsSeat && !wantsZone {
+			continue
+		}
+
+		// a user is offered one seat at a time
+		var userOffered bool
+		for _, offer := range ms.waitlistOffers(entry.StartTime, entry.EndTime, now) {
+			userOffered = userOffered || offer.UserID == entry.UserID
+		}
+		if userOffered || ms.checkBookingConflicts(entry.booking(seat.ID, now), now) != nil {
+			continue
+		}
+
+		seatID, expires := seat.ID, expiresAt.UTC()
+		entry.Status, entry.OfferedSeatID, entry.OfferExpiresAt = WaitlistStatusOffered, &seatID, &expires
+		ms.waitlist[entry.ID] = entry
+		offered = append(offered, entry)
+	}
+	return offered, nil
+}
+
+func (ms *MemoryStorage) ExpireWaitlistOffers(now time.Time) ([]WaitlistEntry, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var expired []WaitlistEntry
+	for _, id := range sortedKeys(ms.waitlist) {
+		entry := ms.waitlist[id]
+		if entry.Status != WaitlistStatusOffered || entry.OfferExpiresAt.After(now) {
+			continue
+		}
+		entry.Status = WaitlistStatusExpired
+		ms.waitlist[entry.ID] = entry
+		expired = append(expired, entry)
+	}
+	return expired, nil
+}
+
+func (ms *MemoryStorage) ClaimWaitlistOffer(entry *WaitlistEntry, now time.Time) (*Booking, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	stored, ok := ms.waitlist[entry.ID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := stored.claimable(now); err != nil {
+		return nil, err
+	}
+
+	booking := stored.booking(*stored.OfferedSeatID, now)
+	if err := ms.checkBookingConflicts(booking, now); err != nil {
+		return nil, err
+	}
+	ms.createBooking(booking)
+
+	bookingID := booking.ID
+	stored.Status, stored.BookingID = WaitlistStatusClaimed, &bookingID
+	ms.waitlist[stored.ID] = stored
+	*entry = stored
+	return booking, nil
+}
+
+func (ms *MemoryStorage) CreateNotifications(notifications []Notification) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.createNotifications(notifications)
+	return nil
+}
+
+// createNotifications insert the notifications, the caller must hold the lock
+func (ms *MemoryStorage) createNotifications(notifications []Notification) {
+	for i := range notifications {
+		n := &notifications[i]
+		n.ID, n.CreatedAt = ms.nextID("notifications"), time.Now().UTC()
+		if n.Status == "" {
+			n.Status = NotificationStatusPending
+		}
+		n.NextAttemptAt = n.NextAttemptAt.UTC()
+		ms.notifications[n.ID] = *n
+	}
+}
+
+func (ms *MemoryStorage) ClaimDueNotifications(now time.Time, lease time.Duration, limit int) ([]Notification, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var due []Notification
+	for _, id := range sortedKeys(ms.notifications) {
+		n := ms.notifications[id]
+		if n.Status == NotificationStatusPending && !n.NextAttemptAt.After(now) {
+			due = append(due, n)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	until := now.Add(lease).UTC()
+	for i := range due {
+		due[i].NextAttemptAt = until
+		ms.notifications[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (ms *MemoryStorage) UpdateNotificationDelivery(notification *Notification) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	stored, ok := ms.notifications[notification.ID]
+	if !ok {
+		return nil
+	}
+	stored.Status, stored.Attempts = notification.Status, notification.Attempts
+	stored.NextAttemptAt, stored.LastError = notification.NextAttemptAt.UTC(), notification.LastError
+	stored.SentAt = notification.SentAt
+	ms.notifications[stored.ID] = stored
+	return nil
+}
+
+func (ms *MemoryStorage) FindInAppNotifications(userID uint, limit int) ([]Notification, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var notifications []Notification
+	keys := sortedKeys(ms.notifications)
+	for i := len(keys) - 1; i >= 0 && len(notifications) < limit; i-- {
+		n := ms.notifications[keys[i]]
+		if n.UserID == userID && n.Channel == NotificationChannelInApp && n.Status == NotificationStatusSent {
+			notifications = append(notifications, n)
+		}
+	}
+	return notifications, nil
+}
+
+func (ms *MemoryStorage) MarkNotificationRead(userID, id uint, now time.Time) (*Notification, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	n, ok := ms.notifications[id]
+	if !ok || n.UserID != userID || n.Channel != NotificationChannelInApp || n.Status != NotificationStatusSent {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if n.ReadAt == nil {
+		readAt := now.UTC()
+		n.ReadAt = &readAt
+		ms.notifications[id] = n
+	}
+	return &n, nil
+}
+
+func (ms *MemoryStorage) FindUnscheduledReminders(fromTime, toTime time.Time) ([]releaseCandidate, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var candidates []releaseCandidate
+	for _, id := range sortedKeys(ms.bookings) {
+		booking := ms.bookings[id]
+		if booking.CheckedIn || !booking.IsActive() || booking.StartTime.Before(fromTime) || !booking.StartTime.Before(toTime) {
+			continue
+		}
+		seat, ok := ms.seats[booking.SeatID]
+		if !ok || ms.hasReminderJob(booking) {
+			continue
+		}
+		candidates = append(candidates, releaseCandidate{Booking: booking, ZoneID: seat.ZoneID, SeatType: seat.Type})
+	}
+	return candidates, nil
+}
+
+func (ms *MemoryStorage) CreateReminderJobs(jobs []ReminderJob) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for i := range jobs {
+		job := &jobs[i]
+		duplicate := false
+		for _, j := range ms.reminderJobs {
+			if j.BookingID == job.BookingID && j.Kind == job.Kind && j.StartTime.Equal(job.StartTime) {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		job.ID, job.CreatedAt = ms.nextID("reminder_jobs"), time.Now().UTC()
+		if job.Status == "" {
+			job.Status = ReminderStatusPending
+		}
+		job.StartTime, job.ReleaseAt, job.RunAt = job.StartTime.UTC(), job.ReleaseAt.UTC(), job.RunAt.UTC()
+		ms.reminderJobs[job.ID] = *job
+	}
+	return nil
+}
+
+func (ms *MemoryStorage) FindDueReminderJobs(now time.Time, limit int) ([]ReminderJob, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var jobs []ReminderJob
+	for _, id := range sortedKeys(ms.reminderJobs) {
+		job := ms.reminderJobs[id]
+		if job.Status == ReminderStatusPending && !job.RunAt.After(now) {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].RunAt.Before(jobs[j].RunAt) })
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+func (ms *MemoryStorage) CompleteReminderJob(job *ReminderJob, status string, notifications []Notification) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	stored, ok := ms.reminderJobs[job.ID]
+	if !ok || stored.Status != ReminderStatusPending {
+		return false, nil
+	}
+	stored.Status = status
+	ms.reminderJobs[job.ID] = stored
+	ms.createNotifications(notifications)
+	job.Status = status
+	return true, nil
+}
+
+// hasReminderJob report whether a reminder job is planned for the booking's start time,
+// the caller must hold the lock
+func (ms *MemoryStorage) hasReminderJob(booking Booking) bool {
+	for _, job := range ms.reminderJobs {
+		if job.BookingID == booking.ID && job.StartTime.Equal(booking.StartTime) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ms *MemoryStorage) AcquireJobLease(name, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	job, ok := ms.jobs[name]
+	if !ok {
+		job = Job{Name: name}
+	}
+	if ok && job.Holder != holder && job.LeaseExpiresAt.After(now) {
+		return false, nil
+	}
+	job.Holder, job.LeaseExpiresAt = holder, now.Add(ttl).UTC()
+	ms.jobs[name] = job
+	return true, nil
+}
+
+func (ms *MemoryStorage) ReleaseJobLease(name, holder string, now time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if job, ok := ms.jobs[name]; ok && job.Holder == holder {
+		job.LeaseExpiresAt = now.UTC()
+		ms.jobs[name] = job
+	}
+	return nil
+}
+
+func (ms *MemoryStorage) RecordJobRun(name string, run JobRun) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	job, ok := ms.jobs[name]
+	if !ok {
+		return nil
+	}
+	startedAt := run.StartedAt.UTC()
+	job.LastRunAt, job.LastDurationMs, job.LastProcessed, job.LastError = &startedAt, run.Duration.Milliseconds(), run.Processed, ""
+	job.Runs++
+	job.TotalProcessed += int64(run.Processed)
+	if run.Err != nil {
+		job.Failures++
+		job.LastError = run.Err.Error()
+	}
+	ms.jobs[name] = job
+	return nil
+}
+
+func (ms *MemoryStorage) ListJobs() ([]Job, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	jobs := make([]Job, 0, len(ms.jobs))
+	for _, job := range ms.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs, nil
+}
+
+// checkBookingConflicts is DataStorage.checkBookingConflicts, the caller must hold the lock
+func (ms *MemoryStorage) checkBookingConflicts(booking *Booking, now time.Time) error {
+	var seatBooked bool
+	for _, b := range ms.bookings {
+		if b.ID == booking.ID || !b.IsActive() || !overlaps(b, booking.StartTime, booking.EndTime) {
+			continue
+		}
+		if b.UserID == booking.UserID {
+			return ErrUserAlreadyBooked
+		}
+		if b.SeatID == booking.SeatID {
+			seatBooked = true
+		}
+	}
+	if seatBooked {
+		return ErrSeatAlreadyBooked
+	}
+	for _, entry := range ms.waitlistOffers(booking.StartTime, booking.EndTime, now) {
+		if *entry.OfferedSeatID == booking.SeatID && entry.UserID != booking.UserID {
+			return ErrSeatAlreadyBooked
+		}
+	}
+	return nil
+}
+
+// waitlistOffers return the offers which overlap [startTime, endTime) and have not expired
+// at now, the caller must hold the lock
+func (ms *MemoryStorage) waitlistOffers(startTime, endTime, now time.Time) []WaitlistEntry {
+	var offers []WaitlistEntry
+	for _, id := range sortedKeys(ms.waitlist) {
+		entry := ms.waitlist[id]
+		if entry.Status == WaitlistStatusOffered && entry.OfferExpiresAt.After(now) &&
+			entry.StartTime.Before(endTime) && entry.EndTime.After(startTime) {
+			offers = append(offers, entry)
+		}
+	}
+	return offers
+}
+
+// overlaps match the overlap condition of FindOverlapBookingsBySeatID, bookings touching
+// at their ends do not overlap
+func overlaps(booking Booking, startTime, endTime time.Time) bool {
+	return booking.StartTime.Before(endTime) && booking.EndTime.After(startTime)
+}
+
+// createBooking insert the booking with its first status history entry, the caller must hold the lock
+func (ms *MemoryStorage) createBooking(booking *Booking) {
+	booking.ID = int(ms.nextID("bookings"))
+	booking.Status = BookingStatusBooked
+	booking.CreatedAt = time.Now().UTC()
+	booking.StartTime, booking.EndTime = booking.StartTime.UTC(), booking.EndTime.UTC()
+	ms.bookings[booking.ID] = *booking
+	ms.addBookingHistory(booking)
+}
+
+// updateBookingStatus change the stored status and record it, the caller must hold the lock
+func (ms *MemoryStorage) updateBookingStatus(booking *Booking, status string) {
+	booking.Status = status
+	if stored, ok := ms.bookings[booking.ID]; ok {
+		stored.Status = status
+		ms.bookings[booking.ID] = stored
+	}
+	ms.addBookingHistory(booking)
+}
+
+func (ms *MemoryStorage) addBookingHistory(booking *Booking) {
+	ms.history = append(ms.history, BookingStatusHistory{
+		ID:        ms.nextID("booking_status_histories"),
+		BookingID: booking.ID,
+		Status:    booking.Status,
+		SeatID:    booking.SeatID,
+		StartTime: booking.StartTime.UTC(),
+		EndTime:   booking.EndTime.UTC(),
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// sortSeats order the seats by number like the seat listings of DataStorage
+func sortSeats(seats []Seat) {
+	sort.Slice(seats, func(i, j int) bool { return seats[i].Number < seats[j].Number })
+}
+
+// sortedKeys return the keys of m in ascending order, the order rows are read by primary key
+func sortedKeys[K int | uint, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStorage_WithTransaction(t *testing.T) {
+	s := NewMemoryStorage()
+	errRollback := errors.New("rollback")
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- s.WithTransaction(func(tx Storage) error {
+			if err := tx.CreateSeat(&Seat{Number: "A1"}); err != nil {
+				return err
+			}
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			return errRollback
+		})
+	}()
+
+	<-started
+	// the write waits for the transaction, its rollback must not undo it
+	require.NoError(t, s.CreateSeat(&Seat{Number: "B1"}))
+	assert.ErrorIs(t, <-done, errRollback)
+
+	seats, err := s.ListSeats(true)
+	require.NoError(t, err)
+	require.Len(t, seats, 1)
+	assert.Equal(t, "B1", seats[0].Number)
+}
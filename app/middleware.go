@@ -1,12 +1,78 @@
 package app
 
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
 const (
 	contextKeyUserID = "userID"
+	contextKeyRole   = "role"
 )
 
 type Middleware struct {
+	jwtSecret string
+	users     UserRepository
+}
+
+func NewMiddleware(jwtSecret string, users UserRepository) *Middleware {
+	return &Middleware{
+		jwtSecret: jwtSecret,
+		users:     users,
+	}
+}
+
+// Authenticate reject requests without a valid access token and store the user id in the context
+func (m *Middleware) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || tokenString == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing access token"})
+			return
+		}
+
+		subject, err := parseToken(m.jwtSecret, tokenString, tokenTypeAccess)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid access token"})
+			return
+		}
+
+		c.Set(contextKeyUserID, subject.UserID)
+		c.Set(contextKeyRole, subject.Role)
+		c.Next()
+	}
+}
+
+// RequireRole reject authenticated requests whose role is not one of roles, it must run after Authenticate.
+// The role is read from the user rather than the token so that a demoted or deleted user loses access at once
+func (m *Middleware) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := m.users.GetUserByID(userIDFromContext(c))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid access token"})
+			} else {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+			}
+			return
+		}
+
+		c.Set(contextKeyRole, user.Role)
+		for _, r := range roles {
+			if r == user.Role {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+	}
 }
 
-func NewMiddleware(jwtSecret string) *Middleware {
-	return &Middleware{}
+// userIDFromContext return the user id stored by Authenticate
+func userIDFromContext(c *gin.Context) uint {
+	return c.GetUint(contextKeyUserID)
 }
@@ -6,23 +6,277 @@ import (
 	"gorm.io/gorm"
 )
 
+// User roles
+const (
+	RoleEmployee      = "employee"
+	RoleFacilityAdmin = "facility_admin"
+	RoleSuperAdmin    = "super_admin"
+)
+
 type User struct {
 	gorm.Model
-	Name  string
-	Email string `gorm:"unique"`
+	Name                string
+	Email               string     `gorm:"size:255;unique"`
+	Role                string     `gorm:"size:32;default:employee"`
+	PasswordHash        string     `json:"-"`
+	FailedLoginAttempts int        `json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+}
+
+// Seat types
+const (
+	SeatTypeDesk         = "desk"
+	SeatTypeStandingDesk = "standing_desk"
+	SeatTypeBooth        = "booth"
+)
+
+type Building struct {
+	gorm.Model
+	Name   string  `json:"name"`
+	Floors []Floor `json:"floors,omitempty"`
+}
+
+type Floor struct {
+	gorm.Model
+	BuildingID uint      `json:"building_id"`
+	Building   *Building `json:"building,omitempty"`
+	Name       string    `json:"name"`
+	Level      int       `json:"level"`
+	Zones      []Zone    `json:"zones,omitempty"`
+}
+
+type Zone struct {
+	gorm.Model
+	FloorID uint   `json:"floor_id"`
+	Floor   *Floor `json:"floor,omitempty"`
+	Name    string `json:"name"`
 }
 
 type Seat struct {
 	gorm.Model
-	Number string `json:"number"`
+	Number      string `json:"number"`
+	ZoneID      *uint  `json:"zone_id"`
+	Zone        *Zone  `json:"zone,omitempty"`
+	Type        string `json:"type" gorm:"size:32;default:desk"`
+	DualMonitor bool   `json:"dual_monitor"`
+	NearWindow  bool   `json:"near_window"`
+	Accessible  bool   `json:"accessible"`
 }
 
+// Booking statuses
+const (
+	BookingStatusBooked    = "booked"
+	BookingStatusModified  = "modified"
+	BookingStatusCancelled = "cancelled"
+	BookingStatusReleased  = "released"
+	BookingStatusCheckedIn = "checked_in"
+	// a checked out booking ended early and no longer holds its seat
+	BookingStatusCheckedOut = "checked_out"
+)
+
+// Filters of the user's booking list
+const (
+	BookingFilterAll       = ""
+	BookingFilterUpcoming  = "upcoming"
+	BookingFilterPast      = "past"
+	BookingFilterCheckedIn = "checked_in"
+)
+
+// activeBookingStatuses are the statuses of bookings which still hold their seat
+var activeBookingStatuses = []string{BookingStatusBooked, BookingStatusModified, BookingStatusCheckedIn}
+
 type Booking struct {
 	ID        int       `json:"id"`
 	UserID    uint      `json:"user_id"`
 	SeatID    uint      `json:"seat_id"`
+	SeriesID  *uint     `json:"series_id,omitempty"`
 	StartTime time.Time `json:"start_time"`
 	EndTime   time.Time `json:"end_time"`
 	CheckedIn bool      `json:"checked_in"`
+	Status    string    `json:"status" gorm:"size:32;default:booked;index"`
+	CreatedAt time.Time `json:"created_at"`
+	// CheckedInAt and CheckedOutAt are when the user actually arrived and left
+	CheckedInAt  *time.Time `json:"checked_in_at,omitempty"`
+	CheckedOutAt *time.Time `json:"checked_out_at,omitempty"`
+}
+
+// IsActive report whether the booking still holds its seat
+func (b *Booking) IsActive() bool {
+	for _, status := range activeBookingStatuses {
+		if b.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// BeforeSave store the time range in UTC
+func (b *Booking) BeforeSave(*gorm.DB) error {
+	b.StartTime, b.EndTime = b.StartTime.UTC(), b.EndTime.UTC()
+	return nil
+}
+
+// BookingStatusHistory record every status change of a booking with the seat and time range at that moment
+type BookingStatusHistory struct {
+	ID        uint      `json:"id"`
+	BookingID int       `json:"booking_id" gorm:"index"`
+	Status    string    `json:"status"`
+	SeatID    uint      `json:"seat_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// BeforeSave store the time range in UTC
+func (h *BookingStatusHistory) BeforeSave(*gorm.DB) error {
+	h.StartTime, h.EndTime = h.StartTime.UTC(), h.EndTime.UTC()
+	return nil
+}
+
+// BookingSeries group the bookings created from one recurrence rule
+type BookingSeries struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	SeatID    uint      `json:"seat_id"`
+	Rule      string    `json:"rule"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Waitlist entry statuses
+const (
+	WaitlistStatusWaiting   = "waiting"
+	WaitlistStatusOffered   = "offered"
+	WaitlistStatusClaimed   = "claimed"
+	WaitlistStatusExpired   = "expired"
+	WaitlistStatusCancelled = "cancelled"
+)
+
+// WaitlistEntry is a user waiting for a seat, or any seat of a zone, to free up over a
+// time range. An offered entry holds OfferedSeatID for the user until OfferExpiresAt
+type WaitlistEntry struct {
+	ID             uint       `json:"id"`
+	UserID         uint       `json:"user_id" gorm:"index"`
+	SeatID         *uint      `json:"seat_id"`
+	ZoneID         *uint      `json:"zone_id"`
+	StartTime      time.Time  `json:"start_time"`
+	EndTime        time.Time  `json:"end_time"`
+	Status         string     `json:"status" gorm:"size:32;default:waiting;index"`
+	OfferedSeatID  *uint      `json:"offered_seat_id,omitempty"`
+	OfferExpiresAt *time.Time `json:"offer_expires_at,omitempty"`
+	BookingID      *int       `json:"booking_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// BeforeSave store the time range in UTC
+func (e *WaitlistEntry) BeforeSave(*gorm.DB) error {
+	e.StartTime, e.EndTime = e.StartTime.UTC(), e.EndTime.UTC()
+	return nil
+}
+
+// Notification channels
+const (
+	NotificationChannelInApp   = "in_app"
+	NotificationChannelEmail   = "email"
+	NotificationChannelWebhook = "webhook"
+)
+
+// Notification kinds
+const (
+	NotificationBookingCancelled = "booking_cancelled"
+	NotificationBookingReleased  = "booking_released"
+	NotificationWaitlistOffer    = "waitlist_offer"
+	NotificationBookingReminder  = "booking_reminder"
+	NotificationReleaseWarning   = "release_warning"
+)
+
+// Notification delivery statuses, a failed notification has used all its attempts
+const (
+	NotificationStatusPending = "pending"
+	NotificationStatusSent    = "sent"
+	NotificationStatusFailed  = "failed"
+)
+
+// Notification is a message to a user queued in the outbox until its channel delivers it,
+// the sent in-app notifications are the user's inbox
+type Notification struct {
+	ID            uint       `json:"id"`
+	UserID        uint       `json:"user_id" gorm:"index"`
+	Channel       string     `json:"channel" gorm:"size:32"`
+	Kind          string     `json:"kind" gorm:"size:64"`
+	Subject       string     `json:"subject"`
+	Body          string     `json:"body"`
+	BookingID     *int       `json:"booking_id,omitempty"`
+	Status        string     `json:"-" gorm:"size:32;default:pending;index"`
+	Attempts      int        `json:"-"`
+	NextAttemptAt time.Time  `json:"-" gorm:"index"`
+	LastError     string     `json:"-"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	ReadAt        *time.Time `json:"read_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Reminder job kinds, a start reminder runs before the booking starts and a release
+// warning before the booking is released for not being checked in
+const (
+	ReminderKindStart   = "start"
+	ReminderKindRelease = "release"
+)
+
+// Reminder job statuses, a skipped job found its booking checked in, cancelled or moved
+const (
+	ReminderStatusPending = "pending"
+	ReminderStatusSent    = "sent"
+	ReminderStatusSkipped = "skipped"
+)
+
+// ReminderJob is a reminder of a booking due at RunAt. Jobs are planned for the
+// StartTime of the booking, a booking moved to another time gets new ones
+type ReminderJob struct {
+	ID        uint
+	BookingID int       `gorm:"uniqueIndex:idx_reminder_jobs_booking"`
+	Kind      string    `gorm:"size:32;uniqueIndex:idx_reminder_jobs_booking"`
+	StartTime time.Time `gorm:"uniqueIndex:idx_reminder_jobs_booking"`
+	ReleaseAt time.Time
+	RunAt     time.Time `gorm:"index:idx_reminder_jobs_due,priority:2"`
+	Status    string    `gorm:"size:32;default:pending;index:idx_reminder_jobs_due,priority:1"`
+	CreatedAt time.Time
+}
+
+// BeforeSave store the times in UTC
+func (j *ReminderJob) BeforeSave(*gorm.DB) error {
+	j.StartTime, j.ReleaseAt, j.RunAt = j.StartTime.UTC(), j.ReleaseAt.UTC(), j.RunAt.UTC()
+	return nil
+}
+
+// Job is a background job run by one instance at a time, the Holder of its lease, with
+// the metrics of its runs
+type Job struct {
+	Name           string     `json:"name" gorm:"primaryKey;size:64"`
+	Holder         string     `json:"holder" gorm:"size:128"`
+	LeaseExpiresAt time.Time  `json:"lease_expires_at"`
+	LastRunAt      *time.Time `json:"last_run_at"`
+	LastDurationMs int64      `json:"last_duration_ms"`
+	LastProcessed  int        `json:"last_processed"`
+	LastError      string     `json:"last_error,omitempty"`
+	Runs           int64      `json:"runs"`
+	Failures       int64      `json:"failures"`
+	TotalProcessed int64      `json:"total_processed"`
+}
+
+// IsValidRole report whether role is one of the known user roles
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleEmployee, RoleFacilityAdmin, RoleSuperAdmin:
+		return true
+	}
+	return false
+}
+
+// IsValidSeatType report whether seatType is one of the known seat types
+func IsValidSeatType(seatType string) bool {
+	switch seatType {
+	case SeatTypeDesk, SeatTypeStandingDesk, SeatTypeBooth:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,322 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// notificationLease is how long a claimed notification is left to its dispatcher before
+	// another one may claim it again
+	notificationLease = 5 * time.Minute
+	// maxNotificationRetryDelay cap the exponential backoff of the retries
+	maxNotificationRetryDelay = time.Hour
+)
+
+type (
+	// NotificationConfig is the `notifications` section of config.yaml
+	NotificationConfig struct {
+		// Channels every notification is sent over, in_app, email and/or webhook
+		Channels     []string      `mapstructure:"channels"`
+		PollInterval time.Duration `mapstructure:"poll_interval"`
+		BatchSize    int           `mapstructure:"batch_size"`
+		// MaxAttempts is how many times a notification is sent before it is given up,
+		// RetryDelay is doubled after every failed attempt
+		MaxAttempts int           `mapstructure:"max_attempts"`
+		RetryDelay  time.Duration `mapstructure:"retry_delay"`
+		SendTimeout time.Duration `mapstructure:"send_timeout"`
+		Email       EmailConfig   `mapstructure:"email"`
+		Webhook     WebhookConfig `mapstructure:"webhook"`
+	}
+
+	// Notifier queue the notifications of booking changes in the outbox, one per channel
+	Notifier struct {
+		seats         SeatRepository
+		notifications NotificationRepository
+		channels      []string
+	}
+
+	// NotificationDispatcher send the queued notifications over their channel and retry
+	// the failed ones with an exponential backoff
+	NotificationDispatcher struct {
+		users         UserRepository
+		notifications NotificationRepository
+		channels      map[string]NotificationChannel
+		config        NotificationConfig
+	}
+)
+
+// DefaultNotificationConfig send in-app notifications only
+func DefaultNotificationConfig() NotificationConfig {
+	return NotificationConfig{
+		Channels:     []string{NotificationChannelInApp},
+		PollInterval: 10 * time.Second,
+		BatchSize:    25,
+		MaxAttempts:  5,
+		RetryDelay:   30 * time.Second,
+		SendTimeout:  10 * time.Second,
+	}
+}
+
+// Validate check the durations, limits and channels of the config
+func (c NotificationConfig) Validate() error {
+	if c.PollInterval <= 0 || c.RetryDelay <= 0 || c.SendTimeout <= 0 {
+		return errors.New("notifications: poll_interval, retry_delay and send_timeout must be positive")
+	}
+	if c.BatchSize <= 0 || c.MaxAttempts <= 0 {
+		return errors.New("notifications: batch_size and max_attempts must be positive")
+	}
+	// a batch sent after its lease passed is claimed and sent again by another dispatcher
+	if time.Duration(c.BatchSize)*c.SendTimeout >= notificationLease {
+		return fmt.Errorf("notifications: batch_size * send_timeout must be shorter than the claim lease of %s", notificationLease)
+	}
+	for _, channel := range c.Channels {
+		switch channel {
+		case NotificationChannelInApp:
+		case NotificationChannelEmail:
+			if c.Email.Addr == "" || c.Email.From == "" {
+				return errors.New("notifications.email: addr and from are required")
+			}
+		case NotificationChannelWebhook:
+			if c.Webhook.URL == "" {
+				return errors.New("notifications.webhook: url is required")
+			}
+		default:
+			return fmt.Errorf("notifications: unknown channel %q", channel)
+		}
+	}
+	return nil
+}
+
+func NewNotifier(seats SeatRepository, notifications NotificationRepository, config NotificationConfig) *Notifier {
+	return &Notifier{
+		seats:         seats,
+		notifications: notifications,
+		channels:      config.Channels,
+	}
+}
+
+// BookingCancelled tell the user their booking has been cancelled
+func (n *Notifier) BookingCancelled(booking Booking) {
+	n.notify(booking.UserID, NotificationBookingCancelled, &booking.ID, "Booking cancelled",
+		fmt.Sprintf("Your booking of seat %s on %s has been cancelled.",
+			n.seatNumber(booking.SeatID), formatTimeRange(booking.StartTime, booking.EndTime)))
+}
+
+// BookingReleased tell the user their booking has been released because they did not check in
+func (n *Notifier) BookingReleased(booking Booking) {
+	n.notify(booking.UserID, NotificationBookingReleased, &booking.ID, "Booking released",
+		fmt.Sprintf("Your booking of seat %s on %s has been released because you did not check in.",
+			n.seatNumber(booking.SeatID), formatTimeRange(booking.StartTime, booking.EndTime)))
+}
+
+// WaitlistOffered tell the user the seat they waited for is held for them until the offer expires
+func (n *Notifier) WaitlistOffered(entry WaitlistEntry) {
+	if entry.OfferedSeatID == nil || entry.OfferExpiresAt == nil {
+		return
+	}
+	n.notify(entry.UserID, NotificationWaitlistOffer, nil, "A seat is available",
+		fmt.Sprintf("Seat %s is available on %s. Claim it before %s or it goes to the next person in line.",
+			n.seatNumber(*entry.OfferedSeatID), formatTimeRange(entry.StartTime, entry.EndTime),
+			entry.OfferExpiresAt.In(dateutil.LocVN).Format(timeFormat)))
+}
+
+// Reminders return the notifications of the reminder job, for the caller to queue along
+// with the completion of the job
+func (n *Notifier) Reminders(booking Booking, job ReminderJob) []Notification {
+	var (
+		seat      = n.seatNumber(booking.SeatID)
+		timeRange = formatTimeRange(booking.StartTime, booking.EndTime)
+		deadline  = job.ReleaseAt.In(dateutil.LocVN).Format("15:04")
+	)
+	if job.Kind == ReminderKindRelease {
+		return n.build(booking.UserID, NotificationReleaseWarning, &booking.ID, "Check in now",
+			fmt.Sprintf("Your booking of seat %s on %s has not been checked in. It will be released at %s.",
+				seat, timeRange, deadline))
+	}
+	return n.build(booking.UserID, NotificationBookingReminder, &booking.ID, "Booking starts soon",
+		fmt.Sprintf("Your booking of seat %s on %s starts soon. Check in before %s or it will be released.",
+			seat, timeRange, deadline))
+}
+
+// notify queue the notification for every channel, a failure is logged as the change it
+// notifies has been made already
+func (n *Notifier) notify(userID uint, kind string, bookingID *int, subject, body string) {
+	if err := n.notifications.CreateNotifications(n.build(userID, kind, bookingID, subject, body)); err != nil {
+		log.WithError(err).WithFields(log.Fields{"user_id": userID, "kind": kind}).Error("queue notification fail")
+	}
+}
+
+// build return the notification for every channel, due now
+func (n *Notifier) build(userID uint, kind string, bookingID *int, subject, body string) []Notification {
+	now := time.Now().UTC()
+	notifications := make([]Notification, len(n.channels))
+	for i, channel := range n.channels {
+		notifications[i] = Notification{
+			UserID:        userID,
+			Channel:       channel,
+			Kind:          kind,
+			Subject:       subject,
+			Body:          body,
+			BookingID:     bookingID,
+			Status:        NotificationStatusPending,
+			NextAttemptAt: now,
+		}
+	}
+	return notifications
+}
+
+// seatNumber return the number of the seat, deleted seats included
+func (n *Notifier) seatNumber(seatID uint) string {
+	seat, err := n.seats.GetSeatByID(seatID, true)
+	if err != nil {
+		return fmt.Sprintf("#%d", seatID)
+	}
+	return seat.Number
+}
+
+// formatTimeRange format a time range in the office time zone, the end date is omitted
+// when it is the start date
+func formatTimeRange(from, to time.Time) string {
+	from, to = from.In(dateutil.LocVN), to.In(dateutil.LocVN)
+	if from.Format(dateutil.FormatYYYYMMDDDash) == to.Format(dateutil.FormatYYYYMMDDDash) {
+		return from.Format(timeFormat) + " - " + to.Format("15:04")
+	}
+	return from.Format(timeFormat) + " - " + to.Format(timeFormat)
+}
+
+func NewNotificationDispatcher(users UserRepository, notifications NotificationRepository, config NotificationConfig) *NotificationDispatcher {
+	channels := map[string]NotificationChannel{}
+	for _, channel := range config.Channels {
+		switch channel {
+		case NotificationChannelInApp:
+			channels[channel] = InAppChannel{}
+		case NotificationChannelEmail:
+			channels[channel] = NewEmailChannel(config.Email)
+		case NotificationChannelWebhook:
+			channels[channel] = NewWebhookChannel(config.Webhook)
+		}
+	}
+	return &NotificationDispatcher{
+		users:         users,
+		notifications: notifications,
+		channels:      channels,
+		config:        config,
+	}
+}
+
+// Run dispatch the due notifications every poll interval until ctx is cancelled
+func (d *NotificationDispatcher) Run(ctx context.Context) {
+	log.Printf("start notification dispatcher")
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := d.Dispatch(ctx, time.Now())
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.WithError(err).Error("dispatch notifications fail")
+		}
+		if sent > 0 {
+			log.Infof("sent %d notifications", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("stop notification dispatcher")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch send the notifications due at now batch by batch, it returns how many were sent
+func (d *NotificationDispatcher) Dispatch(ctx context.Context, now time.Time) (int, error) {
+	sent := 0
+	for {
+		due, err := d.notifications.ClaimDueNotifications(now, notificationLease, d.config.BatchSize)
+		if err != nil {
+			return sent, err
+		}
+		for i := range due {
+			if ctx.Err() != nil {
+				// the rest is claimed again once the lease has passed
+				return sent, ctx.Err()
+			}
+			if d.deliver(ctx, &due[i], now) {
+				sent++
+			}
+		}
+		if len(due) < d.config.BatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// deliver send the notification and record the attempt, it reports whether it was sent
+func (d *NotificationDispatcher) deliver(ctx context.Context, notification *Notification, now time.Time) bool {
+	channel, ok := d.channels[notification.Channel]
+	if !ok {
+		d.record(notification, now, fmt.Errorf("channel %q is not configured", notification.Channel), true)
+		return false
+	}
+
+	user, err := d.users.GetUserByID(notification.UserID)
+	if err != nil {
+		d.record(notification, now, err, errors.Is(err, gorm.ErrRecordNotFound))
+		return false
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, d.config.SendTimeout)
+	err = channel.Send(sendCtx, user, notification)
+	cancel()
+	if err != nil && ctx.Err() != nil {
+		// shutting down, the attempt does not count
+		return false
+	}
+	d.record(notification, now, err, false)
+	return err == nil
+}
+
+// record save the outcome of a delivery attempt, a failed notification is tried again
+// after the backoff until it has used its attempts or the failure is permanent
+func (d *NotificationDispatcher) record(notification *Notification, now time.Time, err error, permanent bool) {
+	notification.Attempts++
+	if err == nil {
+		sentAt := now.UTC()
+		notification.Status, notification.SentAt, notification.LastError = NotificationStatusSent, &sentAt, ""
+	} else {
+		notification.LastError = err.Error()
+		if permanent || notification.Attempts >= d.config.MaxAttempts {
+			notification.Status = NotificationStatusFailed
+		} else {
+			notification.NextAttemptAt = now.Add(d.retryDelay(notification.Attempts))
+		}
+		log.WithError(err).WithFields(log.Fields{
+			"notification_id": notification.ID,
+			"channel":         notification.Channel,
+			"attempts":        notification.Attempts,
+		}).Warn("send notification fail")
+	}
+
+	if err := d.notifications.UpdateNotificationDelivery(notification); err != nil {
+		log.WithError(err).WithField("notification_id", notification.ID).Error("record notification fail")
+	}
+}
+
+// retryDelay return the delay before the attempt following the given number of attempts
+func (d *NotificationDispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.config.RetryDelay
+	for i := 1; i < attempts && delay < maxNotificationRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxNotificationRetryDelay {
+		delay = maxNotificationRetryDelay
+	}
+	return delay
+}
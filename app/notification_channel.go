@@ -0,0 +1,175 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type (
+	// NotificationChannel deliver notifications to users over one medium, Send returns an
+	// error when the notification should be tried again later
+	NotificationChannel interface {
+		Send(ctx context.Context, user *User, notification *Notification) error
+	}
+
+	// EmailConfig is the `notifications.email` section of config.yaml
+	EmailConfig struct {
+		// Addr is the host:port of the SMTP server, STARTTLS is used when it offers it
+		Addr     string `mapstructure:"addr"`
+		From     string `mapstructure:"from"`
+		Username string `mapstructure:"username"`
+		Password string `mapstructure:"password"`
+	}
+
+	// WebhookConfig is the `notifications.webhook` section of config.yaml
+	WebhookConfig struct {
+		URL string `mapstructure:"url"`
+	}
+
+	// InAppChannel keep the notifications in the outbox, the sent ones are the user's inbox
+	InAppChannel struct{}
+
+	// EmailChannel send the notifications as plain text emails
+	EmailChannel struct {
+		config EmailConfig
+	}
+
+	// WebhookChannel post the notifications as JSON to a URL, the payload has a `text`
+	// field so that it can be a Slack incoming webhook
+	WebhookChannel struct {
+		config WebhookConfig
+		client *http.Client
+	}
+
+	// webhookPayload is the body posted by WebhookChannel
+	webhookPayload struct {
+		Text      string    `json:"text"`
+		ID        uint      `json:"id"`
+		Kind      string    `json:"kind"`
+		UserID    uint      `json:"user_id"`
+		UserEmail string    `json:"user_email"`
+		Subject   string    `json:"subject"`
+		Body      string    `json:"body"`
+		BookingID *int      `json:"booking_id,omitempty"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+)
+
+func (InAppChannel) Send(context.Context, *User, *Notification) error {
+	return nil
+}
+
+func NewEmailChannel(config EmailConfig) *EmailChannel {
+	return &EmailChannel{config: config}
+}
+
+func (c *EmailChannel) Send(ctx context.Context, user *User, notification *Notification) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.config.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	host, _, _ := net.SplitHostPort(c.config.Addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if c.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.config.Username, c.config.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(c.config.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(user.Email); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(c.message(user, notification)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message format the notification as a plain text email to the user
+func (c *EmailChannel) message(user *User, notification *Notification) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", c.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", user.Email)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(notification.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+func NewWebhookChannel(config WebhookConfig) *WebhookChannel {
+	return &WebhookChannel{config: config, client: &http.Client{}}
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, user *User, notification *Notification) error {
+	body, err := json.Marshal(webhookPayload{
+		Text:      notification.Subject + "\n" + notification.Body,
+		ID:        notification.ID,
+		Kind:      notification.Kind,
+		UserID:    user.ID,
+		UserEmail: user.Email,
+		Subject:   notification.Subject,
+		Body:      notification.Body,
+		BookingID: notification.BookingID,
+		CreatedAt: notification.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,371 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// fakeSMTPServer accept SMTP sessions on a local port and collect the messages sent
+	fakeSMTPServer struct {
+		addr     string
+		messages chan fakeSMTPMessage
+	}
+
+	fakeSMTPMessage struct {
+		from string
+		to   []string
+		data string
+	}
+)
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	srv := &fakeSMTPServer{addr: ln.Addr().String(), messages: make(chan fakeSMTPMessage, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 localhost fake SMTP")
+
+	var msg fakeSMTPMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			_ = tp.PrintfLine("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			msg = fakeSMTPMessage{from: strings.Trim(line[len("MAIL FROM:"):], "<> ")}
+			_ = tp.PrintfLine("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			_ = tp.PrintfLine("250 OK")
+		case command == "DATA":
+			_ = tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			s.messages <- msg
+			_ = tp.PrintfLine("250 OK")
+		case command == "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func TestNotificationConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  func(c *NotificationConfig)
+		wantErr bool
+	}{
+		{
+			name:   "default",
+			config: func(c *NotificationConfig) {},
+		},
+		{
+			name: "every channel",
+			config: func(c *NotificationConfig) {
+				c.Channels = []string{NotificationChannelInApp, NotificationChannelEmail, NotificationChannelWebhook}
+				c.Email = EmailConfig{Addr: "localhost:25", From: "booking@example.com"}
+				c.Webhook = WebhookConfig{URL: "http://localhost/hook"}
+			},
+		},
+		{
+			name:    "email without server",
+			config:  func(c *NotificationConfig) { c.Channels = []string{NotificationChannelEmail} },
+			wantErr: true,
+		},
+		{
+			name:    "webhook without url",
+			config:  func(c *NotificationConfig) { c.Channels = []string{NotificationChannelWebhook} },
+			wantErr: true,
+		},
+		{
+			name:    "unknown channel",
+			config:  func(c *NotificationConfig) { c.Channels = []string{"sms"} },
+			wantErr: true,
+		},
+		{
+			name:    "no attempt",
+			config:  func(c *NotificationConfig) { c.MaxAttempts = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "batch outlives its lease",
+			config:  func(c *NotificationConfig) { c.BatchSize, c.SendTimeout = 100, 10*time.Second },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultNotificationConfig()
+			tt.config(&config)
+			if tt.wantErr {
+				assert.Error(t, config.Validate())
+			} else {
+				assert.NoError(t, config.Validate())
+			}
+		})
+	}
+}
+
+func TestNotificationDispatcher_Email(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		smtpServer := newFakeSMTPServer(t)
+		config := DefaultNotificationConfig()
+		config.Channels = []string{NotificationChannelEmail}
+		config.Email = EmailConfig{Addr: smtpServer.addr, From: "booking@example.com"}
+		require.NoError(t, config.Validate())
+
+		booking := createTestBooking(t, s)
+		NewNotifier(s, s, config).BookingCancelled(*booking)
+
+		dispatcher := NewNotificationDispatcher(s, s, config)
+		sent, err := dispatcher.Dispatch(context.Background(), time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+
+		select {
+		case msg := <-smtpServer.messages:
+			assert.Equal(t, "booking@example.com", msg.from)
+			assert.Equal(t, []string{"jane@example.com"}, msg.to)
+			assert.Contains(t, msg.data, "Subject: Booking cancelled\n")
+			assert.Contains(t, msg.data, "Your booking of seat A1 on 2026-10-19 09:00 - 17:00 has been cancelled.")
+		case <-time.After(5 * time.Second):
+			t.Fatal("no email received")
+		}
+
+		sent, err = dispatcher.Dispatch(context.Background(), time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, sent, "a sent notification is not sent again")
+	})
+}
+
+func TestNotificationDispatcher_WebhookRetry(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var calls atomic.Int32
+		payloads := make(chan webhookPayload, 10)
+		webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload webhookPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err == nil {
+				payloads <- payload
+			}
+			// the receiver is down for the first two attempts
+			if calls.Add(1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer webhook.Close()
+
+		config := DefaultNotificationConfig()
+		config.Channels = []string{NotificationChannelWebhook}
+		config.Webhook = WebhookConfig{URL: webhook.URL}
+		config.RetryDelay = time.Minute
+
+		booking := createTestBooking(t, s)
+		NewNotifier(s, s, config).BookingReleased(*booking)
+		dispatcher := NewNotificationDispatcher(s, s, config)
+
+		now := time.Now()
+		tests := []struct {
+			name      string
+			at        time.Time
+			wantSent  int
+			wantCalls int32
+		}{
+			{name: "first attempt fails", at: now, wantSent: 0, wantCalls: 1},
+			{name: "not due before the retry delay", at: now.Add(30 * time.Second), wantSent: 0, wantCalls: 1},
+			{name: "second attempt fails", at: now.Add(time.Minute), wantSent: 0, wantCalls: 2},
+			{name: "retry delay is doubled", at: now.Add(2 * time.Minute), wantSent: 0, wantCalls: 2},
+			{name: "third attempt succeeds", at: now.Add(3 * time.Minute), wantSent: 1, wantCalls: 3},
+			{name: "sent once", at: now.Add(time.Hour), wantSent: 0, wantCalls: 3},
+		}
+		for _, tt := range tests {
+			sent, err := dispatcher.Dispatch(context.Background(), tt.at)
+			require.NoError(t, err, tt.name)
+			assert.Equal(t, tt.wantSent, sent, tt.name)
+			assert.Equal(t, tt.wantCalls, calls.Load(), tt.name)
+		}
+
+		payload := <-payloads
+		assert.Equal(t, NotificationBookingReleased, payload.Kind)
+		assert.Equal(t, "jane@example.com", payload.UserEmail)
+		require.NotNil(t, payload.BookingID)
+		assert.Equal(t, booking.ID, *payload.BookingID)
+		assert.True(t, strings.HasPrefix(payload.Text, "Booking released\n"), payload.Text)
+	})
+}
+
+func TestNotificationDispatcher_GiveUp(t *testing.T) {
+	s := NewMemoryStorage()
+	var calls atomic.Int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer webhook.Close()
+
+	config := DefaultNotificationConfig()
+	config.Channels = []string{NotificationChannelWebhook}
+	config.Webhook = WebhookConfig{URL: webhook.URL}
+	config.MaxAttempts = 2
+
+	NewNotifier(s, s, config).BookingCancelled(*createTestBooking(t, s))
+	dispatcher := NewNotificationDispatcher(s, s, config)
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		_, err := dispatcher.Dispatch(context.Background(), now.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), calls.Load())
+
+	stored := s.notifications[1]
+	assert.Equal(t, NotificationStatusFailed, stored.Status)
+	assert.Equal(t, 2, stored.Attempts)
+	assert.Equal(t, "webhook responded 500 Internal Server Error", stored.LastError)
+}
+
+func TestNotificationHandlers(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 2)
+			from  = time.Now().In(dateutil.LocVN).Add(time.Hour).Truncate(time.Minute)
+		)
+		require.NoError(t, s.CreateSeat(&Seat{Number: "A1"}))
+		require.Equal(t, http.StatusOK, bookSeat(r, users[0], "A1", from, from.Add(time.Hour)))
+		w := serveJSON(r, http.MethodDelete, "/bookings/1", users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		inbox := func(token string) []Notification {
+			w := serveJSON(r, http.MethodGet, "/me/notifications", token, nil)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var body struct {
+				Notifications []Notification `json:"notifications"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			return body.Notifications
+		}
+		assert.Empty(t, inbox(users[0]), "queued notifications are not in the inbox yet")
+
+		sent, err := NewNotificationDispatcher(s, s, DefaultNotificationConfig()).Dispatch(context.Background(), time.Now())
+		require.NoError(t, err)
+		require.Equal(t, 1, sent)
+
+		notifications := inbox(users[0])
+		require.Len(t, notifications, 1)
+		assert.Equal(t, NotificationBookingCancelled, notifications[0].Kind)
+		assert.Equal(t, "Booking cancelled", notifications[0].Subject)
+		assert.Nil(t, notifications[0].ReadAt)
+		assert.Empty(t, inbox(users[1]))
+
+		path := fmt.Sprintf("/me/notifications/%d/read", notifications[0].ID)
+		w = serveJSON(r, http.MethodPost, path, users[1], nil)
+		require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+		w = serveJSON(r, http.MethodPost, path, users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.NotNil(t, inbox(users[0])[0].ReadAt)
+	})
+}
+
+func TestNotificationHandlers_SeriesCancelled(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 1)
+			from  = time.Now().In(dateutil.LocVN).Add(time.Hour).Truncate(time.Minute)
+		)
+		require.NoError(t, s.CreateSeat(&Seat{Number: "A1"}))
+		request := gin.H{
+			"seat_number": "A1",
+			"from_time":   from.Format(timeFormat),
+			"to_time":     from.Add(time.Hour).Format(timeFormat),
+			"rrule":       "FREQ=DAILY;COUNT=2",
+		}
+		w := serveJSON(r, http.MethodPost, "/book-seat/recurring", users[0], request)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var series struct {
+			SeriesID uint `json:"series_id"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+		w = serveJSON(r, http.MethodDelete, fmt.Sprintf("/booking-series/%d", series.SeriesID), users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		sent, err := NewNotificationDispatcher(s, s, DefaultNotificationConfig()).Dispatch(context.Background(), time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, 2, sent, "every cancelled occurrence is notified")
+		w = serveJSON(r, http.MethodGet, "/me/notifications", users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var body struct {
+			Notifications []Notification `json:"notifications"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		require.Len(t, body.Notifications, 2)
+		for _, notification := range body.Notifications {
+			assert.Equal(t, NotificationBookingCancelled, notification.Kind)
+		}
+	})
+}
+
+func TestCheckinService_ReleaseBookingNotify(t *testing.T) {
+	s := NewMemoryStorage()
+	seat := &Seat{Number: "A1"}
+	require.NoError(t, s.CreateSeat(seat))
+	booking := &Booking{UserID: 1, SeatID: seat.ID, StartTime: time.Now().Add(-time.Hour), EndTime: time.Now().Add(time.Hour)}
+	require.NoError(t, s.CreateBookingIfAvailable(booking))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	notifier := NewNotifier(s, s, DefaultNotificationConfig())
+	waitlist := NewWaitlist(s, s, DefaultWaitlistConfig(), notifier)
+	NewCheckInService(s, testJWTSecret, DefaultReleaseConfig(), NewSeatEvents(s, s), waitlist, notifier, NewJobRunner(s, DefaultJobConfig())).ReleaseBooking(ctx)
+
+	due, err := s.ClaimDueNotifications(time.Now(), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, NotificationBookingReleased, due[0].Kind)
+	assert.Equal(t, booking.UserID, due[0].UserID)
+	assert.Equal(t, NotificationChannelInApp, due[0].Channel)
+}
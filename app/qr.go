@@ -0,0 +1,67 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// QR code image formats
+const (
+	QRFormatPNG = "png"
+	QRFormatSVG = "svg"
+)
+
+// QR code sizes in pixels
+const (
+	defaultQRSize = 256
+	minQRSize     = 64
+	maxQRSize     = 2048
+)
+
+var (
+	ErrUnsupportedQRFormat = errors.New("format must be png or svg")
+	ErrInvalidQRSize       = fmt.Errorf("size must be between %d and %d", minQRSize, maxQRSize)
+)
+
+// encodeQR render content as a square PNG or SVG QR code of size pixels and return
+// the image with its content type
+func encodeQR(content, format string, size int) ([]byte, string, error) {
+	if size < minQRSize || size > maxQRSize {
+		return nil, "", ErrInvalidQRSize
+	}
+
+	qr, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, "", err
+	}
+	switch format {
+	case QRFormatPNG:
+		image, err := qr.PNG(size)
+		return image, "image/png", err
+	case QRFormatSVG:
+		return qrSVG(qr.Bitmap(), size), "image/svg+xml", nil
+	}
+	return nil, "", ErrUnsupportedQRFormat
+}
+
+// qrSVG draw every dark module of the bitmap as a 1x1 square of the view box
+func qrSVG(bitmap [][]bool, size int) []byte {
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, len(bitmap), len(bitmap))
+	svg.WriteString(`<rect width="100%" height="100%" fill="#fff"/>`)
+	fmt.Fprintf(&svg, `<path fill="#000" d="%s"/></svg>`, path.String())
+	return []byte(svg.String())
+}
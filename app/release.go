@@ -0,0 +1,148 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Release actions applied to no-show bookings
+const (
+	ReleaseActionMark   = "mark_released"
+	ReleaseActionDelete = "delete"
+)
+
+type (
+	// ReleasePolicy decide when and how a no-show booking frees its seat
+	ReleasePolicy struct {
+		GracePeriod time.Duration `mapstructure:"grace_period"`
+		Action      string        `mapstructure:"action"`
+	}
+
+	// ReleasePolicyOverride replace the default policy for the seats it matches,
+	// empty GracePeriod or Action fall back to the default one
+	ReleasePolicyOverride struct {
+		ZoneID        *uint  `mapstructure:"zone_id"`
+		SeatType      string `mapstructure:"seat_type"`
+		ReleasePolicy `mapstructure:",squash"`
+	}
+
+	// ReleaseConfig is the `release` section of config.yaml
+	ReleaseConfig struct {
+		ReleasePolicy `mapstructure:",squash"`
+		PollInterval  time.Duration `mapstructure:"poll_interval"`
+		// BatchSize is how many no-show candidates are looked at per transaction
+		BatchSize int `mapstructure:"batch_size"`
+		// Overrides are matched in order, the first one matching the seat applies
+		Overrides []ReleasePolicyOverride `mapstructure:"overrides"`
+	}
+
+	// releaseCandidate is a no-show booking with the seat attributes policies match on
+	releaseCandidate struct {
+		Booking
+		ZoneID   *uint
+		SeatType string
+	}
+)
+
+// DefaultReleaseConfig release bookings not checked in 10 minutes after they start
+func DefaultReleaseConfig() ReleaseConfig {
+	return ReleaseConfig{
+		ReleasePolicy: ReleasePolicy{
+			GracePeriod: 10 * time.Minute,
+			Action:      ReleaseActionMark,
+		},
+		PollInterval: time.Minute,
+		BatchSize:    500,
+	}
+}
+
+// Validate check the durations and actions of the config
+func (c ReleaseConfig) Validate() error {
+	if c.PollInterval <= 0 {
+		return errors.New("release.poll_interval must be positive")
+	}
+	if c.BatchSize <= 0 {
+		return errors.New("release.batch_size must be positive")
+	}
+	if err := c.ReleasePolicy.validate(); err != nil {
+		return fmt.Errorf("release: %w", err)
+	}
+	for i, override := range c.Overrides {
+		if override.ZoneID == nil && override.SeatType == "" {
+			return fmt.Errorf("release.overrides[%d]: zone_id or seat_type is required", i)
+		}
+		if override.SeatType != "" && !IsValidSeatType(override.SeatType) {
+			return fmt.Errorf("release.overrides[%d]: unknown seat_type %q", i, override.SeatType)
+		}
+		if override.Action != "" || override.GracePeriod != 0 {
+			if err := c.resolve(override.ReleasePolicy).validate(); err != nil {
+				return fmt.Errorf("release.overrides[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// PolicyFor return the policy of the seat, the first matching override wins
+func (c ReleaseConfig) PolicyFor(zoneID *uint, seatType string) ReleasePolicy {
+	for _, override := range c.Overrides {
+		if override.matches(zoneID, seatType) {
+			return c.resolve(override.ReleasePolicy)
+		}
+	}
+	return c.ReleasePolicy
+}
+
+// minGracePeriod return the shortest grace period of every policy
+func (c ReleaseConfig) minGracePeriod() time.Duration {
+	grace := c.GracePeriod
+	for _, override := range c.Overrides {
+		if p := c.resolve(override.ReleasePolicy); p.GracePeriod < grace {
+			grace = p.GracePeriod
+		}
+	}
+	return grace
+}
+
+// maxGracePeriod return the longest grace period of every policy
+func (c ReleaseConfig) maxGracePeriod() time.Duration {
+	grace := c.GracePeriod
+	for _, override := range c.Overrides {
+		if p := c.resolve(override.ReleasePolicy); p.GracePeriod > grace {
+			grace = p.GracePeriod
+		}
+	}
+	return grace
+}
+
+// resolve fill the empty fields of p with the default policy
+func (c ReleaseConfig) resolve(p ReleasePolicy) ReleasePolicy {
+	if p.GracePeriod == 0 {
+		p.GracePeriod = c.GracePeriod
+	}
+	if p.Action == "" {
+		p.Action = c.Action
+	}
+	return p
+}
+
+func (o ReleasePolicyOverride) matches(zoneID *uint, seatType string) bool {
+	if o.ZoneID != nil && (zoneID == nil || *zoneID != *o.ZoneID) {
+		return false
+	}
+	if o.SeatType != "" && o.SeatType != seatType {
+		return false
+	}
+	return true
+}
+
+func (p ReleasePolicy) validate() error {
+	if p.GracePeriod < 0 {
+		return errors.New("grace_period must not be negative")
+	}
+	if p.Action != ReleaseActionMark && p.Action != ReleaseActionDelete {
+		return fmt.Errorf("action must be %s or %s", ReleaseActionMark, ReleaseActionDelete)
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"code-challenge-backend/migrations"
+	"code-challenge-backend/pkg/migrate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func TestReleaseConfig_PolicyFor(t *testing.T) {
+	config := ReleaseConfig{
+		ReleasePolicy: ReleasePolicy{GracePeriod: 10 * time.Minute, Action: ReleaseActionMark},
+		PollInterval:  time.Minute,
+		Overrides: []ReleasePolicyOverride{
+			{ZoneID: uintPtr(1), ReleasePolicy: ReleasePolicy{GracePeriod: 30 * time.Minute}},
+			{SeatType: SeatTypeBooth, ReleasePolicy: ReleasePolicy{GracePeriod: 5 * time.Minute, Action: ReleaseActionDelete}},
+		},
+	}
+	type args struct {
+		zoneID   *uint
+		seatType string
+	}
+	tests := []struct {
+		name string
+		args args
+		want ReleasePolicy
+	}{
+		{
+			name: "default policy",
+			args: args{zoneID: uintPtr(2), seatType: SeatTypeDesk},
+			want: ReleasePolicy{GracePeriod: 10 * time.Minute, Action: ReleaseActionMark},
+		},
+		{
+			name: "zone override inherits action",
+			args: args{zoneID: uintPtr(1), seatType: SeatTypeDesk},
+			want: ReleasePolicy{GracePeriod: 30 * time.Minute, Action: ReleaseActionMark},
+		},
+		{
+			name: "first matching override wins",
+			args: args{zoneID: uintPtr(1), seatType: SeatTypeBooth},
+			want: ReleasePolicy{GracePeriod: 30 * time.Minute, Action: ReleaseActionMark},
+		},
+		{
+			name: "seat type override without zone",
+			args: args{seatType: SeatTypeBooth},
+			want: ReleasePolicy{GracePeriod: 5 * time.Minute, Action: ReleaseActionDelete},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.PolicyFor(tt.args.zoneID, tt.args.seatType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReleaseConfig.PolicyFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *ReleaseConfig)
+		wantErr bool
+	}{
+		{
+			name:   "default config",
+			modify: func(*ReleaseConfig) {},
+		},
+		{
+			name:    "zero poll interval",
+			modify:  func(c *ReleaseConfig) { c.PollInterval = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "zero batch size",
+			modify:  func(c *ReleaseConfig) { c.BatchSize = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "unknown action",
+			modify:  func(c *ReleaseConfig) { c.Action = "archive" },
+			wantErr: true,
+		},
+		{
+			name: "override without selector",
+			modify: func(c *ReleaseConfig) {
+				c.Overrides = []ReleasePolicyOverride{{ReleasePolicy: ReleasePolicy{GracePeriod: time.Minute}}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "override with unknown seat type",
+			modify: func(c *ReleaseConfig) {
+				c.Overrides = []ReleasePolicyOverride{{SeatType: "sofa"}}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultReleaseConfig()
+			tt.modify(&config)
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("ReleaseConfig.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDataStorage_ReleaseBooking(t *testing.T) {
+	forEachBackend(t, testDataStorageReleaseBooking)
+}
+
+func testDataStorageReleaseBooking(t *testing.T, ds *DataStorage) {
+	var (
+		now    = time.Now()
+		desk   = &Seat{Number: "A1", Type: SeatTypeDesk}
+		booth  = &Seat{Number: "B1", Type: SeatTypeBooth}
+		config = DefaultReleaseConfig()
+	)
+	config.Overrides = []ReleasePolicyOverride{
+		{SeatType: SeatTypeBooth, ReleasePolicy: ReleasePolicy{GracePeriod: 5 * time.Minute, Action: ReleaseActionDelete}},
+	}
+	require.NoError(t, ds.CreateSeat(desk))
+	require.NoError(t, ds.CreateSeat(booth))
+
+	bookings := map[string]*Booking{
+		"desk within grace": {UserID: 1, SeatID: desk.ID, StartTime: now.Add(-7 * time.Minute), EndTime: now.Add(time.Hour)},
+		"desk past grace":   {UserID: 2, SeatID: desk.ID, StartTime: now.Add(-11 * time.Minute), EndTime: now.Add(-8 * time.Minute)},
+		"booth past grace":  {UserID: 3, SeatID: booth.ID, StartTime: now.Add(-7 * time.Minute), EndTime: now.Add(time.Hour)},
+		"desk checked in":   {UserID: 4, SeatID: desk.ID, StartTime: now.Add(-2 * time.Hour), EndTime: now.Add(-time.Hour)},
+		"booth not started": {UserID: 5, SeatID: booth.ID, StartTime: now.Add(2 * time.Hour), EndTime: now.Add(3 * time.Hour)},
+	}
+	for _, booking := range bookings {
+		require.NoError(t, ds.CreateBooking(booking))
+	}
+	require.NoError(t, ds.ReseverBooking(bookings["desk checked in"]))
+
+	released, err := ds.ReleaseBooking(config, now)
+	require.NoError(t, err)
+	assert.Len(t, released, 2)
+	for _, booking := range released {
+		assert.Equal(t, BookingStatusReleased, booking.Status)
+	}
+
+	wantStatus := map[string]string{
+		"desk within grace": BookingStatusBooked,
+		"desk past grace":   BookingStatusReleased,
+		"desk checked in":   BookingStatusCheckedIn,
+		"booth not started": BookingStatusBooked,
+	}
+	for name, status := range wantStatus {
+		booking, err := ds.QueryBooking(uint(bookings[name].ID))
+		require.NoError(t, err, name)
+		assert.Equal(t, status, booking.Status, name)
+	}
+
+	_, err = ds.QueryBooking(uint(bookings["booth past grace"].ID))
+	assert.Error(t, err, "booth booking should be deleted")
+	history, err := ds.FindBookingHistory(bookings["booth past grace"].ID)
+	require.NoError(t, err)
+	assert.Equal(t, BookingStatusReleased, history[len(history)-1].Status)
+}
+
+func TestReleaseBooking_StaleStatusChange(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		now := time.Now()
+		seat := &Seat{Number: "A1", Type: SeatTypeDesk}
+		require.NoError(t, s.CreateSeat(seat))
+		booking := &Booking{UserID: 1, SeatID: seat.ID, StartTime: now.Add(-20 * time.Minute), EndTime: now.Add(time.Hour)}
+		require.NoError(t, s.CreateBookingIfAvailable(booking))
+
+		// the handlers read the booking before the sweeper releases it
+		stale := *booking
+		released, err := s.ReleaseBooking(DefaultReleaseConfig(), now)
+		require.NoError(t, err)
+		require.Len(t, released, 1)
+
+		checkIn := stale
+		assert.ErrorIs(t, s.ReseverBooking(&checkIn), ErrBookingNotActive)
+		move := stale
+		move.StartTime, move.EndTime = now.Add(time.Hour), now.Add(2*time.Hour)
+		assert.ErrorIs(t, s.UpdateBookingIfAvailable(&move), ErrBookingNotActive)
+		cancel := stale
+		assert.ErrorIs(t, s.UpdateBookingStatus(&cancel, BookingStatusCancelled), ErrBookingNotActive)
+		checkOut := stale
+		checkOut.Status, checkOut.CheckedIn = BookingStatusCheckedIn, true
+		assert.ErrorIs(t, s.CheckOutBooking(&checkOut, now), ErrBookingNotActive)
+
+		got, err := s.QueryBooking(uint(booking.ID))
+		require.NoError(t, err)
+		assert.Equal(t, BookingStatusReleased, got.Status)
+		assert.False(t, got.CheckedIn)
+		history, err := s.FindBookingHistory(booking.ID)
+		require.NoError(t, err)
+		assert.Equal(t, BookingStatusReleased, history[len(history)-1].Status)
+	})
+}
+
+func TestDataStorage_ReleaseBookingBatches(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ds *DataStorage) {
+		building := &Building{Name: "HQ"}
+		require.NoError(t, ds.CreateBuilding(building))
+		floor := &Floor{BuildingID: building.ID, Name: "Ground"}
+		require.NoError(t, ds.CreateFloor(floor))
+		quiet := &Zone{FloorID: floor.ID, Name: "Quiet"}
+		require.NoError(t, ds.CreateZone(quiet))
+
+		var (
+			now     = time.Now()
+			desk    = &Seat{Number: "A1", Type: SeatTypeDesk}
+			booth   = &Seat{Number: "B1", Type: SeatTypeBooth}
+			lenient = &Seat{Number: "Q1", Type: SeatTypeDesk, ZoneID: &quiet.ID}
+			config  = DefaultReleaseConfig()
+		)
+		config.BatchSize = 2
+		config.Overrides = []ReleasePolicyOverride{
+			{ZoneID: &quiet.ID, ReleasePolicy: ReleasePolicy{GracePeriod: time.Hour}},
+			{SeatType: SeatTypeBooth, ReleasePolicy: ReleasePolicy{GracePeriod: 5 * time.Minute, Action: ReleaseActionDelete}},
+		}
+		for _, seat := range []*Seat{desk, booth, lenient} {
+			require.NoError(t, ds.CreateSeat(seat))
+		}
+
+		var bookings []*Booking
+		book := func(seat *Seat, startedAgo time.Duration) *Booking {
+			start := now.Add(-startedAgo)
+			booking := &Booking{UserID: uint(len(bookings) + 1), SeatID: seat.ID, StartTime: start, EndTime: start.Add(2 * time.Hour)}
+			require.NoError(t, ds.CreateBooking(booking))
+			bookings = append(bookings, booking)
+			return booking
+		}
+		// the first batch holds no-shows still within the grace period of their zone
+		kept := []*Booking{book(lenient, 50*time.Minute), book(lenient, 40*time.Minute)}
+		for i := 0; i < 3; i++ {
+			book(desk, time.Duration(30-i)*time.Minute)
+			book(booth, time.Duration(20-i)*time.Minute)
+		}
+
+		released, err := ds.ReleaseBooking(config, now)
+		require.NoError(t, err)
+		assert.Len(t, released, 6)
+		for _, booking := range kept {
+			stored, err := ds.QueryBooking(uint(booking.ID))
+			require.NoError(t, err)
+			assert.Equal(t, BookingStatusBooked, stored.Status)
+		}
+		for _, booking := range released {
+			assert.Equal(t, BookingStatusReleased, booking.Status)
+			history, err := ds.FindBookingHistory(booking.ID)
+			require.NoError(t, err)
+			assert.Equal(t, BookingStatusReleased, history[len(history)-1].Status)
+
+			stored, err := ds.QueryBooking(uint(booking.ID))
+			if booking.SeatID == booth.ID {
+				assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "booth bookings are deleted")
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, BookingStatusReleased, stored.Status)
+			}
+		}
+
+		released, err = ds.ReleaseBooking(config, now)
+		require.NoError(t, err)
+		assert.Empty(t, released, "released bookings are not released again")
+	})
+}
+
+// benchmarkHistoricalBookings is how many past bookings the release sweep benchmark
+// runs among
+const benchmarkHistoricalBookings = 1_000_000
+
+// BenchmarkDataStorage_ReleaseBooking measure a sweep releasing 100 no-shows among
+// 1M historical bookings, most of them checked in and some cancelled or released
+func BenchmarkDataStorage_ReleaseBooking(b *testing.B) {
+	ds := NewDataStorage(DatabaseConfig{
+		Driver: DriverSQLite,
+		DSN:    filepath.Join(b.TempDir(), "bench.db") + "?_txlock=immediate",
+	})
+	defer ds.Close()
+	migrator, err := migrate.New(ds.mysqlDB, migrations.All())
+	require.NoError(b, err)
+	_, err = migrator.Up()
+	require.NoError(b, err)
+
+	const seats = 200
+	for i := 0; i < seats; i++ {
+		require.NoError(b, ds.CreateSeat(&Seat{Number: fmt.Sprintf("S%d", i)}))
+	}
+
+	now := time.Now().UTC().Truncate(time.Minute)
+	history := make([]Booking, 0, 90)
+	err = ds.mysqlDB.Transaction(func(tx *gorm.DB) error {
+		for i := 0; i < benchmarkHistoricalBookings; i++ {
+			// one booking a seat a day going back in time
+			start := now.Add(-time.Duration(i/seats+1) * 24 * time.Hour)
+			booking := Booking{
+				UserID:    uint(i%1000 + 1),
+				SeatID:    uint(i%seats + 1),
+				StartTime: start,
+				EndTime:   start.Add(8 * time.Hour),
+				CheckedIn: true,
+				Status:    BookingStatusCheckedOut,
+				CreatedAt: start,
+			}
+			switch i % 10 {
+			case 0:
+				booking.CheckedIn, booking.Status = false, BookingStatusCancelled
+			case 1:
+				booking.CheckedIn, booking.Status = false, BookingStatusReleased
+			}
+			history = append(history, booking)
+			if len(history) == cap(history) {
+				if err := tx.Create(&history).Error; err != nil {
+					return err
+				}
+				history = history[:0]
+			}
+		}
+		if len(history) == 0 {
+			return nil
+		}
+		return tx.Create(&history).Error
+	})
+	require.NoError(b, err)
+
+	config := DefaultReleaseConfig()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for j := 0; j < 100; j++ {
+			start := now.Add(-time.Hour).Add(-time.Duration(i*100+j) * time.Second)
+			require.NoError(b, ds.CreateBooking(&Booking{UserID: uint(j + 1), SeatID: uint(j%seats + 1), StartTime: start, EndTime: start.Add(time.Hour)}))
+		}
+		b.StartTimer()
+
+		released, err := ds.ReleaseBooking(config, now)
+		require.NoError(b, err)
+		require.Len(b, released, 100)
+	}
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type (
+	// ReminderConfig is the `reminders` section of config.yaml
+	ReminderConfig struct {
+		// BeforeStart is how long before its start a booking is reminded of, BeforeRelease
+		// how long before its release deadline a booking not checked in is warned
+		BeforeStart   time.Duration `mapstructure:"before_start"`
+		BeforeRelease time.Duration `mapstructure:"before_release"`
+		PollInterval  time.Duration `mapstructure:"poll_interval"`
+		// ScheduleAhead is how long before they start the jobs of a booking are planned
+		ScheduleAhead time.Duration `mapstructure:"schedule_ahead"`
+		BatchSize     int           `mapstructure:"batch_size"`
+	}
+
+	// ReminderScheduler plan the reminder jobs of the upcoming bookings and run them once
+	// due. Jobs are rows of the database so they survive restarts, and several schedulers
+	// can run side by side
+	ReminderScheduler struct {
+		bookings  BookingRepository
+		reminders ReminderRepository
+		notifier  *Notifier
+		release   ReleaseConfig
+		config    ReminderConfig
+	}
+)
+
+// DefaultReminderConfig remind bookings 15 minutes before they start and warn them
+// 5 minutes before they are released
+func DefaultReminderConfig() ReminderConfig {
+	return ReminderConfig{
+		BeforeStart:   15 * time.Minute,
+		BeforeRelease: 5 * time.Minute,
+		PollInterval:  time.Minute,
+		ScheduleAhead: 24 * time.Hour,
+		BatchSize:     100,
+	}
+}
+
+// Validate check the durations and limits of the config
+func (c ReminderConfig) Validate() error {
+	if c.BeforeStart <= 0 || c.BeforeRelease <= 0 || c.PollInterval <= 0 {
+		return errors.New("reminders: before_start, before_release and poll_interval must be positive")
+	}
+	if c.ScheduleAhead <= c.BeforeStart {
+		return errors.New("reminders: schedule_ahead must be longer than before_start")
+	}
+	if c.BatchSize <= 0 {
+		return errors.New("reminders: batch_size must be positive")
+	}
+	return nil
+}
+
+func NewReminderScheduler(bookings BookingRepository, reminders ReminderRepository, notifier *Notifier, release ReleaseConfig, config ReminderConfig) *ReminderScheduler {
+	return &ReminderScheduler{
+		bookings:  bookings,
+		reminders: reminders,
+		notifier:  notifier,
+		release:   release,
+		config:    config,
+	}
+}
+
+// Run plan and run the reminder jobs every poll interval until ctx is cancelled
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	log.Printf("start reminder scheduler")
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		if _, err := s.Schedule(now); err != nil {
+			log.WithError(err).Error("schedule reminders fail")
+		}
+		reminded, err := s.Remind(now)
+		if err != nil {
+			log.WithError(err).Error("remind bookings fail")
+		}
+		if reminded > 0 {
+			log.Infof("reminded %d bookings", reminded)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("stop reminder scheduler")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Schedule plan a start reminder and a release warning for the bookings starting within
+// ScheduleAhead which have none yet, it returns how many jobs were planned. Bookings
+// which may still be within their grace period are planned too, so that the ones made
+// or moved at the last minute are warned
+func (s *ReminderScheduler) Schedule(now time.Time) (int, error) {
+	candidates, err := s.reminders.FindUnscheduledReminders(now.Add(-s.release.maxGracePeriod()), now.Add(s.config.ScheduleAhead))
+	if err != nil {
+		return 0, err
+	}
+
+	jobs := make([]ReminderJob, 0, 2*len(candidates))
+	for _, candidate := range candidates {
+		releaseAt := candidate.StartTime.Add(s.release.PolicyFor(candidate.ZoneID, candidate.SeatType).GracePeriod)
+		jobs = append(jobs,
+			ReminderJob{
+				BookingID: candidate.ID,
+				Kind:      ReminderKindStart,
+				StartTime: candidate.StartTime,
+				ReleaseAt: releaseAt,
+				RunAt:     candidate.StartTime.Add(-s.config.BeforeStart),
+			},
+			ReminderJob{
+				BookingID: candidate.ID,
+				Kind:      ReminderKindRelease,
+				StartTime: candidate.StartTime,
+				ReleaseAt: releaseAt,
+				RunAt:     releaseAt.Add(-s.config.BeforeRelease),
+			},
+		)
+	}
+	if err := s.reminders.CreateReminderJobs(jobs); err != nil {
+		return 0, err
+	}
+	return len(jobs), nil
+}
+
+// Remind run the jobs due at now batch by batch, it returns how many reminders were sent
+func (s *ReminderScheduler) Remind(now time.Time) (int, error) {
+	reminded := 0
+	for {
+		due, err := s.reminders.FindDueReminderJobs(now, s.config.BatchSize)
+		if err != nil {
+			return reminded, err
+		}
+		for i := range due {
+			sent, err := s.run(&due[i], now)
+			if err != nil {
+				return reminded, err
+			}
+			if sent {
+				reminded++
+			}
+		}
+		if len(due) < s.config.BatchSize {
+			return reminded, nil
+		}
+	}
+}
+
+// run complete the job, it is skipped when its booking has been checked in, cancelled or
+// moved, or is past the time the job is useful for. It reports whether the reminder was
+// sent by this scheduler
+func (s *ReminderScheduler) run(job *ReminderJob, now time.Time) (bool, error) {
+	booking, err := s.bookings.QueryBooking(uint(job.BookingID))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	// the release warning takes over from the start reminder once the booking has started
+	expiresAt := job.ReleaseAt
+	if job.Kind == ReminderKindStart {
+		expiresAt = job.StartTime
+	}
+	if booking == nil || booking.CheckedIn || !booking.IsActive() || !booking.StartTime.Equal(job.StartTime) || !now.Before(expiresAt) {
+		_, err := s.reminders.CompleteReminderJob(job, ReminderStatusSkipped, nil)
+		return false, err
+	}
+	return s.reminders.CompleteReminderJob(job, ReminderStatusSent, s.notifier.Reminders(*booking, *job))
+}
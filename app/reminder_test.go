@@ -0,0 +1,186 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReminderConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  func(c *ReminderConfig)
+		wantErr bool
+	}{
+		{
+			name:   "default",
+			config: func(c *ReminderConfig) {},
+		},
+		{
+			name:    "no start reminder",
+			config:  func(c *ReminderConfig) { c.BeforeStart = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "planned after the reminder is due",
+			config:  func(c *ReminderConfig) { c.ScheduleAhead = c.BeforeStart },
+			wantErr: true,
+		},
+		{
+			name:    "no batch",
+			config:  func(c *ReminderConfig) { c.BatchSize = 0 },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultReminderConfig()
+			tt.config(&config)
+			if tt.wantErr {
+				assert.Error(t, config.Validate())
+			} else {
+				assert.NoError(t, config.Validate())
+			}
+		})
+	}
+}
+
+// queuedKinds return the kinds of the notifications of the booking queued since the last call
+func queuedKinds(t *testing.T, s Storage, booking *Booking) []string {
+	t.Helper()
+	due, err := s.ClaimDueNotifications(time.Now().Add(time.Hour), 24*time.Hour, 100)
+	require.NoError(t, err)
+	var kinds []string
+	for _, n := range due {
+		if n.BookingID != nil && *n.BookingID == booking.ID {
+			kinds = append(kinds, n.Kind)
+		}
+	}
+	return kinds
+}
+
+func TestReminderScheduler(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		seat := &Seat{Number: "A1"}
+		require.NoError(t, s.CreateSeat(seat))
+		user := &User{Name: "Jane", Email: "jane@example.com", Role: RoleEmployee}
+		require.NoError(t, s.Create(user))
+
+		var (
+			now      = time.Now().Truncate(time.Minute)
+			start    = now.Add(time.Hour)
+			config   = DefaultReminderConfig()
+			release  = DefaultReleaseConfig()
+			notifier = NewNotifier(s, s, DefaultNotificationConfig())
+			// two instances sharing the database
+			schedulers = []*ReminderScheduler{
+				NewReminderScheduler(s, s, notifier, release, config),
+				NewReminderScheduler(s, s, notifier, release, config),
+			}
+		)
+		booking := &Booking{UserID: user.ID, SeatID: seat.ID, StartTime: start, EndTime: start.Add(8 * time.Hour)}
+		require.NoError(t, s.CreateBookingIfAvailable(booking))
+
+		planned, err := schedulers[0].Schedule(now)
+		require.NoError(t, err)
+		assert.Equal(t, 2, planned)
+		planned, err = schedulers[1].Schedule(now)
+		require.NoError(t, err)
+		assert.Zero(t, planned, "the jobs are planned once")
+
+		remind := func(at time.Time) int {
+			total := 0
+			for _, scheduler := range schedulers {
+				reminded, err := scheduler.Remind(at)
+				require.NoError(t, err)
+				total += reminded
+			}
+			return total
+		}
+		assert.Zero(t, remind(now))
+		assert.Equal(t, 1, remind(start.Add(-config.BeforeStart)))
+		assert.Equal(t, []string{NotificationBookingReminder}, queuedKinds(t, s, booking))
+
+		deadline := start.Add(release.GracePeriod)
+		assert.Zero(t, remind(deadline.Add(-config.BeforeRelease).Add(-time.Second)))
+		assert.Equal(t, 1, remind(deadline.Add(-config.BeforeRelease)))
+		assert.Zero(t, remind(deadline))
+		assert.Equal(t, []string{NotificationReleaseWarning}, queuedKinds(t, s, booking))
+	})
+}
+
+func TestReminderScheduler_Skip(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		seats := []*Seat{{Number: "A1"}, {Number: "A2"}, {Number: "A3"}}
+		for _, seat := range seats {
+			require.NoError(t, s.CreateSeat(seat))
+		}
+		users := make([]*User, len(seats))
+		for i := range users {
+			users[i] = &User{Name: "user", Email: seats[i].Number + "@example.com", Role: RoleEmployee}
+			require.NoError(t, s.Create(users[i]))
+		}
+
+		var (
+			now       = time.Now().Truncate(time.Minute)
+			start     = now.Add(time.Hour)
+			config    = DefaultReminderConfig()
+			scheduler = NewReminderScheduler(s, s, NewNotifier(s, s, DefaultNotificationConfig()), DefaultReleaseConfig(), config)
+			bookings  = make([]*Booking, len(seats))
+		)
+		for i := range bookings {
+			bookings[i] = &Booking{UserID: users[i].ID, SeatID: seats[i].ID, StartTime: start, EndTime: start.Add(time.Hour)}
+			require.NoError(t, s.CreateBookingIfAvailable(bookings[i]))
+		}
+		planned, err := scheduler.Schedule(now)
+		require.NoError(t, err)
+		require.Equal(t, 6, planned)
+
+		checkedIn, moved, cancelled := bookings[0], bookings[1], bookings[2]
+		require.NoError(t, s.ReseverBooking(checkedIn))
+		require.NoError(t, s.UpdateBookingStatus(cancelled, BookingStatusCancelled))
+		moved.StartTime, moved.EndTime = start.Add(2*time.Hour), start.Add(3*time.Hour)
+		require.NoError(t, s.UpdateBookingIfAvailable(moved))
+
+		planned, err = scheduler.Schedule(now)
+		require.NoError(t, err)
+		assert.Equal(t, 2, planned, "the moved booking is planned again")
+
+		reminded, err := scheduler.Remind(start.Add(-config.BeforeStart))
+		require.NoError(t, err)
+		assert.Zero(t, reminded)
+		reminded, err = scheduler.Remind(moved.StartTime.Add(-config.BeforeStart))
+		require.NoError(t, err)
+		assert.Equal(t, 1, reminded)
+		assert.Equal(t, []string{NotificationBookingReminder}, queuedKinds(t, s, moved))
+	})
+}
+
+func TestReminderScheduler_CompleteOnce(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		now := time.Now()
+		require.NoError(t, s.CreateReminderJobs([]ReminderJob{{BookingID: 1, Kind: ReminderKindStart, StartTime: now, ReleaseAt: now, RunAt: now}}))
+		jobs, err := s.FindDueReminderJobs(now, 10)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+
+		notification := Notification{UserID: 1, Channel: NotificationChannelInApp, Kind: NotificationBookingReminder, NextAttemptAt: now}
+		first, second := jobs[0], jobs[0]
+		completed, err := s.CompleteReminderJob(&first, ReminderStatusSent, []Notification{notification})
+		require.NoError(t, err)
+		assert.True(t, completed)
+		completed, err = s.CompleteReminderJob(&second, ReminderStatusSent, []Notification{notification})
+		require.NoError(t, err)
+		assert.False(t, completed)
+
+		due, err := s.ClaimDueNotifications(now, time.Minute, 10)
+		require.NoError(t, err)
+		assert.Len(t, due, 1, "the notification is queued once")
+		jobs, err = s.FindDueReminderJobs(now, 10)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+	})
+}
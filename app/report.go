@@ -0,0 +1,392 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+)
+
+// Report buckets
+const (
+	ReportBucketDay   = "day"
+	ReportBucketWeek  = "week"
+	ReportBucketMonth = "month"
+)
+
+// maxReportMonths is the longest range a report can cover
+const maxReportMonths = 12
+
+var ErrInvalidReportBucket = errors.New("bucket must be day, week or month")
+
+type (
+	// ReportFilter select the bookings starting in [From, To), both are midnights in the
+	// office time zone
+	ReportFilter struct {
+		From   time.Time
+		To     time.Time
+		Bucket string
+	}
+
+	// ReportPeriod is a range of days, To is inclusive
+	ReportPeriod struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+
+	// UsageStats describe the bookings of a period. Cancelled bookings are ignored, the
+	// no-shows are the bookings released because nobody checked in. Utilization is the
+	// share of the seat-days held by a booking, a seat-day is one seat for one day.
+	// AverageOccupiedMinutes is the actual time between check-in and check-out, or the
+	// end of the booking, of the checked in bookings
+	UsageStats struct {
+		Bookings               int     `json:"bookings"`
+		NoShows                int     `json:"no_shows"`
+		NoShowRate             float64 `json:"no_show_rate"`
+		BookedSeatDays         int     `json:"booked_seat_days"`
+		SeatDays               int     `json:"seat_days"`
+		Utilization            float64 `json:"utilization"`
+		AverageBookingMinutes  float64 `json:"average_booking_minutes"`
+		AverageOccupiedMinutes float64 `json:"average_occupied_minutes"`
+	}
+
+	UtilizationBucket struct {
+		ReportPeriod
+		UsageStats
+	}
+
+	SeatUtilization struct {
+		SeatID uint   `json:"seat_id"`
+		Number string `json:"number"`
+		Zone   string `json:"zone"`
+		UsageStats
+	}
+
+	ZoneUtilization struct {
+		ZoneID *uint  `json:"zone_id"`
+		Zone   string `json:"zone"`
+		Seats  int    `json:"seats"`
+		UsageStats
+	}
+
+	// HourUtilization count the bookings held during an hour of the day, Utilization
+	// is the average share of the seats booked at that hour
+	HourUtilization struct {
+		Hour        int     `json:"hour"`
+		Bookings    int     `json:"bookings"`
+		Utilization float64 `json:"utilization"`
+	}
+
+	// UtilizationReport is computed by BuildUtilizationReport. The seats are the ones
+	// not deleted plus the deleted ones booked in the period, each is counted every day
+	UtilizationReport struct {
+		ReportPeriod
+		Bucket   string              `json:"bucket"`
+		Total    UsageStats          `json:"total"`
+		Buckets  []UtilizationBucket `json:"buckets"`
+		Seats    []SeatUtilization   `json:"seats"`
+		Zones    []ZoneUtilization   `json:"zones"`
+		Hours    []HourUtilization   `json:"hours"`
+		PeakHour *int                `json:"peak_hour"`
+	}
+
+	// usageCounter accumulate the UsageStats of a period
+	usageCounter struct {
+		bookings        int
+		noShows         int
+		bookedSeatDays  int
+		seatDays        int
+		minutes         float64
+		occupied        int
+		occupiedMinutes float64
+	}
+)
+
+// ParseReportFilter parse the filter of a report, from and to are dates formatted as
+// dateutil.FormatYYYYMMDDDash and to is inclusive. The current month of today is
+// reported by default, by day
+func ParseReportFilter(from, to, bucket string, today time.Time) (ReportFilter, error) {
+	today = today.In(dateutil.LocVN)
+	filter := ReportFilter{
+		From:   dateutil.FirstOfMonth(today),
+		Bucket: bucket,
+	}
+	last := dateutil.EndOfMonth(today)
+
+	var err error
+	if from != "" {
+		if filter.From, err = parseDateParam("from", from); err != nil {
+			return filter, err
+		}
+	}
+	if to != "" {
+		if last, err = parseDateParam("to", to); err != nil {
+			return filter, err
+		}
+	}
+	months, err := dateutil.MonthDuration(filter.From, last)
+	if err != nil {
+		return filter, fmt.Errorf("from date is after to date")
+	}
+	if months > maxReportMonths {
+		return filter, fmt.Errorf("a report covers at most %d months", maxReportMonths)
+	}
+	filter.To = last.AddDate(0, 0, 1)
+
+	switch filter.Bucket {
+	case "":
+		filter.Bucket = ReportBucketDay
+	case ReportBucketDay, ReportBucketWeek, ReportBucketMonth:
+	default:
+		return filter, ErrInvalidReportBucket
+	}
+	return filter, nil
+}
+
+// parseDateParam parse a date formatted as dateutil.FormatYYYYMMDDDash in the office time zone
+func parseDateParam(name, value string) (time.Time, error) {
+	date, err := time.ParseInLocation(dateutil.FormatYYYYMMDDDash, value, dateutil.LocVN)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s date %q", name, value)
+	}
+	return date, nil
+}
+
+// reportBuckets split [from, to) in days, weeks starting on Monday or calendar months,
+// the first and last buckets are cut to the range
+func reportBuckets(from, to time.Time, bucket string) []ReportFilter {
+	var buckets []ReportFilter
+	for start := from; start.Before(to); {
+		var end time.Time
+		switch bucket {
+		case ReportBucketWeek:
+			daysToMonday := (8 - int(start.Weekday())) % 7
+			if daysToMonday == 0 {
+				daysToMonday = 7
+			}
+			end = start.AddDate(0, 0, daysToMonday)
+		case ReportBucketMonth:
+			end = dateutil.EndOfMonth(start).AddDate(0, 0, 1)
+		default:
+			end = start.AddDate(0, 0, 1)
+		}
+		if end.After(to) {
+			end = to
+		}
+		buckets = append(buckets, ReportFilter{From: start, To: end, Bucket: bucket})
+		start = end
+	}
+	return buckets
+}
+
+// BuildUtilizationReport compute the utilization of the seats, zones and buckets of
+// the filter. Bookings are counted in the bucket of their start day
+func BuildUtilizationReport(s Storage, filter ReportFilter) (*UtilizationReport, error) {
+	seats, err := s.ListSeats(true)
+	if err != nil {
+		return nil, err
+	}
+	seatIndex := map[uint]int{}
+	for i := range seats {
+		seatIndex[seats[i].ID] = i
+	}
+
+	var (
+		days       = int(filter.To.Sub(filter.From).Hours() / 24)
+		buckets    = reportBuckets(filter.From, filter.To, filter.Bucket)
+		dayBuckets = make([]int, days)
+		total      usageCounter
+		bucketUse  = make([]usageCounter, len(buckets))
+		seatUse    = make([]usageCounter, len(seats))
+		booked     = map[uint]map[int]bool{}
+		hours      [24]int
+	)
+	for i, bucket := range buckets {
+		first := int(bucket.From.Sub(filter.From).Hours() / 24)
+		last := int(bucket.To.Sub(filter.From).Hours() / 24)
+		for day := first; day < last; day++ {
+			dayBuckets[day] = i
+		}
+	}
+	dayOf := func(t time.Time) int {
+		return int(math.Floor(t.Sub(filter.From).Hours() / 24))
+	}
+
+	err = s.ReportBookings(filter.From, filter.To, func(row ReportBooking) error {
+		if row.Status == BookingStatusCancelled {
+			return nil
+		}
+		counters := []*usageCounter{&total, &bucketUse[dayBuckets[dayOf(row.StartTime)]]}
+		if i, ok := seatIndex[row.SeatID]; ok {
+			counters = append(counters, &seatUse[i])
+		}
+		noShow := row.Status == BookingStatusReleased && !row.CheckedIn
+		for _, counter := range counters {
+			counter.add(row, noShow)
+		}
+		if noShow {
+			return nil
+		}
+
+		if booked[row.SeatID] == nil {
+			booked[row.SeatID] = map[int]bool{}
+		}
+		for day := dayOf(row.StartTime); day < days && filter.From.AddDate(0, 0, day).Before(row.EndTime); day++ {
+			booked[row.SeatID][day] = true
+		}
+		for hour := row.StartTime.Truncate(time.Hour); hour.Before(row.EndTime); hour = hour.Add(time.Hour) {
+			hours[hour.In(dateutil.LocVN).Hour()]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UtilizationReport{
+		ReportPeriod: newReportPeriod(filter.From, filter.To),
+		Bucket:       filter.Bucket,
+		Buckets:      []UtilizationBucket{},
+		Seats:        []SeatUtilization{},
+		Zones:        []ZoneUtilization{},
+		Hours:        []HourUtilization{},
+	}
+
+	var (
+		zones     = map[string]*ZoneUtilization{}
+		zoneUse   = map[string]*usageCounter{}
+		seatCount int
+	)
+	for i, seat := range seats {
+		if seat.DeletedAt.Valid && seatUse[i].bookings == 0 && len(booked[seat.ID]) == 0 {
+			continue
+		}
+		seatCount++
+		seatUse[i].seatDays = days
+		seatUse[i].bookedSeatDays = len(booked[seat.ID])
+		for day := range booked[seat.ID] {
+			total.bookedSeatDays++
+			bucketUse[dayBuckets[day]].bookedSeatDays++
+		}
+
+		path := seatZonePath(seat)
+		report.Seats = append(report.Seats, SeatUtilization{
+			SeatID:     seat.ID,
+			Number:     seat.Number,
+			Zone:       path,
+			UsageStats: seatUse[i].stats(),
+		})
+
+		if zones[path] == nil {
+			zones[path] = &ZoneUtilization{ZoneID: seat.ZoneID, Zone: path}
+			zoneUse[path] = &usageCounter{}
+		}
+		zones[path].Seats++
+		zoneUse[path].merge(seatUse[i])
+	}
+
+	total.seatDays = seatCount * days
+	report.Total = total.stats()
+	for i, bucket := range buckets {
+		bucketUse[i].seatDays = seatCount * int(bucket.To.Sub(bucket.From).Hours()/24)
+		report.Buckets = append(report.Buckets, UtilizationBucket{
+			ReportPeriod: newReportPeriod(bucket.From, bucket.To),
+			UsageStats:   bucketUse[i].stats(),
+		})
+	}
+	for _, path := range sortedStringKeys(zones) {
+		zones[path].UsageStats = zoneUse[path].stats()
+		report.Zones = append(report.Zones, *zones[path])
+	}
+	for hour, bookings := range hours {
+		report.Hours = append(report.Hours, HourUtilization{
+			Hour:        hour,
+			Bookings:    bookings,
+			Utilization: ratio(bookings, total.seatDays),
+		})
+		if bookings > 0 && (report.PeakHour == nil || bookings > hours[*report.PeakHour]) {
+			peak := hour
+			report.PeakHour = &peak
+		}
+	}
+	return report, nil
+}
+
+func newReportPeriod(from, to time.Time) ReportPeriod {
+	return ReportPeriod{
+		From: dateutil.ToFormat(from, dateutil.FormatYYYYMMDDDash),
+		To:   dateutil.ToFormat(to.AddDate(0, 0, -1), dateutil.FormatYYYYMMDDDash),
+	}
+}
+
+// seatZonePath return the path of the seat's zone, "-" for a seat without location
+func seatZonePath(seat Seat) string {
+	if seat.Zone == nil || seat.Zone.Floor == nil || seat.Zone.Floor.Building == nil {
+		return "-"
+	}
+	return locationPath(seat.Zone.Floor.Building.Name, seat.Zone.Floor.Name, seat.Zone.Name)
+}
+
+func (u *usageCounter) add(row ReportBooking, noShow bool) {
+	u.bookings++
+	if noShow {
+		u.noShows++
+	}
+	u.minutes += row.EndTime.Sub(row.StartTime).Minutes()
+	if row.CheckedInAt != nil {
+		left := row.EndTime
+		if row.CheckedOutAt != nil {
+			left = *row.CheckedOutAt
+		}
+		u.occupied++
+		u.occupiedMinutes += math.Max(left.Sub(*row.CheckedInAt).Minutes(), 0)
+	}
+}
+
+func (u *usageCounter) merge(other usageCounter) {
+	u.bookings += other.bookings
+	u.noShows += other.noShows
+	u.bookedSeatDays += other.bookedSeatDays
+	u.seatDays += other.seatDays
+	u.minutes += other.minutes
+	u.occupied += other.occupied
+	u.occupiedMinutes += other.occupiedMinutes
+}
+
+func (u usageCounter) stats() UsageStats {
+	stats := UsageStats{
+		Bookings:       u.bookings,
+		NoShows:        u.noShows,
+		NoShowRate:     ratio(u.noShows, u.bookings),
+		BookedSeatDays: u.bookedSeatDays,
+		SeatDays:       u.seatDays,
+		Utilization:    ratio(u.bookedSeatDays, u.seatDays),
+	}
+	if u.bookings > 0 {
+		stats.AverageBookingMinutes = math.Round(u.minutes/float64(u.bookings)*100) / 100
+	}
+	if u.occupied > 0 {
+		stats.AverageOccupiedMinutes = math.Round(u.occupiedMinutes/float64(u.occupied)*100) / 100
+	}
+	return stats
+}
+
+// ratio return n/d rounded to 4 decimals, 0 when d is 0
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(d)*10000) / 10000
+}
+
+func sortedStringKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
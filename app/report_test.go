@@ -0,0 +1,275 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reportDate(day string) time.Time {
+	date, _ := time.ParseInLocation(dateutil.FormatYYYYMMDDDash, day, dateutil.LocVN)
+	return date
+}
+
+func TestParseReportFilter(t *testing.T) {
+	today := time.Date(2026, 10, 18, 15, 0, 0, 0, dateutil.LocVN)
+	type args struct {
+		from, to, bucket string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    ReportFilter
+		wantErr bool
+	}{
+		{
+			name: "current month by day by default",
+			args: args{},
+			want: ReportFilter{From: reportDate("2026-10-01"), To: reportDate("2026-11-01"), Bucket: ReportBucketDay},
+		},
+		{
+			name: "to date is inclusive",
+			args: args{from: "2026-01-01", to: "2026-12-31", bucket: ReportBucketMonth},
+			want: ReportFilter{From: reportDate("2026-01-01"), To: reportDate("2027-01-01"), Bucket: ReportBucketMonth},
+		},
+		{
+			name:    "longer than 12 months",
+			args:    args{from: "2026-01-01", to: "2027-01-01"},
+			wantErr: true,
+		},
+		{
+			name:    "from after to",
+			args:    args{from: "2026-10-02", to: "2026-10-01"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid date",
+			args:    args{to: "31/10/2026"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid bucket",
+			args:    args{bucket: "year"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReportFilter(tt.args.from, tt.args.to, tt.args.bucket, today)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.From.Equal(got.From), got.From)
+			assert.True(t, tt.want.To.Equal(got.To), got.To)
+			assert.Equal(t, tt.want.Bucket, got.Bucket)
+		})
+	}
+}
+
+func Test_reportBuckets(t *testing.T) {
+	type args struct {
+		from, to string
+		bucket   string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []ReportPeriod
+	}{
+		{
+			name: "days",
+			args: args{from: "2026-10-30", to: "2026-11-02", bucket: ReportBucketDay},
+			want: []ReportPeriod{{"2026-10-30", "2026-10-30"}, {"2026-10-31", "2026-10-31"}, {"2026-11-01", "2026-11-01"}},
+		},
+		{
+			name: "weeks start on monday",
+			args: args{from: "2026-10-14", to: "2026-10-28", bucket: ReportBucketWeek},
+			want: []ReportPeriod{{"2026-10-14", "2026-10-18"}, {"2026-10-19", "2026-10-25"}, {"2026-10-26", "2026-10-27"}},
+		},
+		{
+			name: "months",
+			args: args{from: "2026-10-15", to: "2026-12-11", bucket: ReportBucketMonth},
+			want: []ReportPeriod{{"2026-10-15", "2026-10-31"}, {"2026-11-01", "2026-11-30"}, {"2026-12-01", "2026-12-10"}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var got []ReportPeriod
+			for _, bucket := range reportBuckets(reportDate(tt.args.from), reportDate(tt.args.to), tt.args.bucket) {
+				got = append(got, newReportPeriod(bucket.From, bucket.To))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBuildUtilizationReport(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		_, err := Seed(s, Fixtures{
+			Buildings: []BuildingFixture{{
+				Name:   "HQ",
+				Floors: []FloorFixture{{Name: "Ground", Level: 0, Zones: []string{"North"}}},
+			}},
+			Seats: []SeatFixture{{Number: "A1", Building: "HQ", Floor: "Ground", Zone: "North"}, {Number: "A2"}},
+		}, false)
+		require.NoError(t, err)
+		a1, err := s.GetSeatByNumber("A1")
+		require.NoError(t, err)
+		a2, err := s.GetSeatByNumber("A2")
+		require.NoError(t, err)
+		jane := &User{Name: "Jane", Email: "jane@example.com", Role: RoleEmployee}
+		john := &User{Name: "John", Email: "john@example.com", Role: RoleEmployee}
+		require.NoError(t, s.Create(jane))
+		require.NoError(t, s.Create(john))
+
+		at := func(day, clock string) time.Time {
+			t, _ := time.ParseInLocation(dateutil.FormatYYYYMMDDHHMMDash, day+" "+clock, dateutil.LocVN)
+			return t
+		}
+		bookings := []*Booking{
+			{UserID: jane.ID, SeatID: a1.ID, StartTime: at("2026-10-19", "09:00"), EndTime: at("2026-10-19", "17:00")},
+			{UserID: john.ID, SeatID: a2.ID, StartTime: at("2026-10-19", "08:00"), EndTime: at("2026-10-19", "10:00")},
+			{UserID: jane.ID, SeatID: a1.ID, StartTime: at("2026-10-20", "10:00"), EndTime: at("2026-10-20", "12:00")},
+			{UserID: john.ID, SeatID: a2.ID, StartTime: at("2026-10-20", "09:00"), EndTime: at("2026-10-20", "10:00")},
+		}
+		for _, booking := range bookings {
+			require.NoError(t, s.CreateBookingIfAvailable(booking))
+		}
+		require.NoError(t, s.UpdateBookingStatus(bookings[2], BookingStatusCancelled))
+		// the second booking is a no-show deleted by the release
+		config := DefaultReleaseConfig()
+		config.Action = ReleaseActionDelete
+		released, err := s.ReleaseBooking(config, at("2026-10-19", "08:30"))
+		require.NoError(t, err)
+		require.Len(t, released, 1)
+
+		filter, err := ParseReportFilter("2026-10-19", "2026-10-20", ReportBucketDay, time.Now())
+		require.NoError(t, err)
+		report, err := BuildUtilizationReport(s, filter)
+		require.NoError(t, err)
+
+		assert.Equal(t, ReportPeriod{From: "2026-10-19", To: "2026-10-20"}, report.ReportPeriod)
+		assert.Equal(t, UsageStats{
+			Bookings: 3, NoShows: 1, NoShowRate: 0.3333, BookedSeatDays: 2, SeatDays: 4, Utilization: 0.5,
+			AverageBookingMinutes: 220,
+		}, report.Total)
+		assert.Equal(t, []UtilizationBucket{
+			{
+				ReportPeriod: ReportPeriod{From: "2026-10-19", To: "2026-10-19"},
+				UsageStats: UsageStats{
+					Bookings: 2, NoShows: 1, NoShowRate: 0.5, BookedSeatDays: 1, SeatDays: 2, Utilization: 0.5,
+					AverageBookingMinutes: 300,
+				},
+			},
+			{
+				ReportPeriod: ReportPeriod{From: "2026-10-20", To: "2026-10-20"},
+				UsageStats:   UsageStats{Bookings: 1, BookedSeatDays: 1, SeatDays: 2, Utilization: 0.5, AverageBookingMinutes: 60},
+			},
+		}, report.Buckets)
+		assert.Equal(t, []SeatUtilization{
+			{
+				SeatID: a1.ID, Number: "A1", Zone: "HQ/Ground/North",
+				UsageStats: UsageStats{Bookings: 1, BookedSeatDays: 1, SeatDays: 2, Utilization: 0.5, AverageBookingMinutes: 480},
+			},
+			{
+				SeatID: a2.ID, Number: "A2", Zone: "-",
+				UsageStats: UsageStats{
+					Bookings: 2, NoShows: 1, NoShowRate: 0.5, BookedSeatDays: 1, SeatDays: 2, Utilization: 0.5,
+					AverageBookingMinutes: 90,
+				},
+			},
+		}, report.Seats)
+		require.Len(t, report.Zones, 2)
+		assert.Equal(t, "-", report.Zones[0].Zone)
+		assert.Equal(t, "HQ/Ground/North", report.Zones[1].Zone)
+		assert.Equal(t, a1.ZoneID, report.Zones[1].ZoneID)
+		assert.Equal(t, report.Seats[0].UsageStats, report.Zones[1].UsageStats)
+
+		require.Len(t, report.Hours, 24)
+		assert.Equal(t, HourUtilization{Hour: 9, Bookings: 2, Utilization: 0.5}, report.Hours[9])
+		assert.Equal(t, HourUtilization{Hour: 16, Bookings: 1, Utilization: 0.25}, report.Hours[16])
+		assert.Equal(t, 0, report.Hours[8].Bookings, "no-shows are not counted in the peak hours")
+		require.NotNil(t, report.PeakHour)
+		assert.Equal(t, 9, *report.PeakHour)
+	})
+}
+
+func Test_usageCounter_occupied(t *testing.T) {
+	start := time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		t := start.Add(time.Duration(minutes) * time.Minute)
+		return &t
+	}
+	tests := []struct {
+		name string
+		rows []ReportBooking
+		want UsageStats
+	}{
+		{
+			name: "not checked in",
+			rows: []ReportBooking{{StartTime: start, EndTime: *at(60), Status: BookingStatusBooked}},
+			want: UsageStats{Bookings: 1, AverageBookingMinutes: 60},
+		},
+		{
+			name: "checked in until the end",
+			rows: []ReportBooking{{StartTime: start, EndTime: *at(60), Status: BookingStatusCheckedIn, CheckedIn: true, CheckedInAt: at(10)}},
+			want: UsageStats{Bookings: 1, AverageBookingMinutes: 60, AverageOccupiedMinutes: 50},
+		},
+		{
+			name: "checked out early",
+			rows: []ReportBooking{
+				{StartTime: start, EndTime: *at(30), Status: BookingStatusCheckedOut, CheckedIn: true, CheckedInAt: at(0), CheckedOutAt: at(30)},
+				{StartTime: start, EndTime: *at(120), Status: BookingStatusBooked},
+			},
+			want: UsageStats{Bookings: 2, AverageBookingMinutes: 75, AverageOccupiedMinutes: 30},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var u usageCounter
+			for _, row := range tt.rows {
+				u.add(row, false)
+			}
+			assert.Equal(t, tt.want, u.stats())
+		})
+	}
+}
+
+func TestReportHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := NewMemoryStorage()
+	h := newTestHandler(s)
+	r := gin.New()
+	r.GET("/admin/reports/utilization", h.UtilizationReport)
+	r.GET("/admin/reports/peak-hours", h.PeakHoursReport)
+
+	w := serveJSON(r, http.MethodGet, "/admin/reports/utilization?from=2026-01-01&to=2026-03-31&bucket=month", "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{
+		"from": "2026-01-01", "to": "2026-03-31", "bucket": "month",
+		"total": {"bookings": 0, "no_shows": 0, "no_show_rate": 0, "booked_seat_days": 0, "seat_days": 0, "utilization": 0, "average_booking_minutes": 0, "average_occupied_minutes": 0},
+		"buckets": [
+			{"from": "2026-01-01", "to": "2026-01-31", "bookings": 0, "no_shows": 0, "no_show_rate": 0, "booked_seat_days": 0, "seat_days": 0, "utilization": 0, "average_booking_minutes": 0, "average_occupied_minutes": 0},
+			{"from": "2026-02-01", "to": "2026-02-28", "bookings": 0, "no_shows": 0, "no_show_rate": 0, "booked_seat_days": 0, "seat_days": 0, "utilization": 0, "average_booking_minutes": 0, "average_occupied_minutes": 0},
+			{"from": "2026-03-01", "to": "2026-03-31", "bookings": 0, "no_shows": 0, "no_show_rate": 0, "booked_seat_days": 0, "seat_days": 0, "utilization": 0, "average_booking_minutes": 0, "average_occupied_minutes": 0}
+		]
+	}`, w.Body.String())
+
+	w = serveJSON(r, http.MethodGet, "/admin/reports/peak-hours", "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"peak_hour":null`)
+
+	w = serveJSON(r, http.MethodGet, "/admin/reports/utilization?bucket=year", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ErrInvalidReportBucket.Error(), decodeError(t, w))
+}
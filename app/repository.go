@@ -0,0 +1,127 @@
+package app
+
+import "time"
+
+type (
+	// UserRepository store the user accounts
+	UserRepository interface {
+		Create(user *User) error
+		GetUserByEmail(email string) (*User, error)
+		GetUserByID(id uint) (*User, error)
+		UpdateUser(user *User) error
+		UpdateUserRole(userID uint, role string) error
+		RecordFailedLogin(userID uint, maxAttempts int, lockUntil time.Time) error
+		ResetFailedLogins(userID uint) error
+	}
+
+	// SeatRepository store the seats, deleted seats are kept and can be restored
+	SeatRepository interface {
+		GetSeatByNumber(number string) (*Seat, error)
+		GetSeatByID(id uint, unscoped bool) (*Seat, error)
+		ListSeats(unscoped bool) ([]Seat, error)
+		FindAvailableSeats(fromTime, toTime time.Time, filter SeatFilter) ([]Seat, error)
+		FindSeats(filter SeatFilter) ([]Seat, error)
+		CreateSeat(seat *Seat) error
+		UpdateSeat(seat *Seat) error
+		DeleteSeat(id uint) error
+		RestoreSeat(id uint) error
+	}
+
+	// LocationRepository store the buildings, floors and zones seats belong to
+	LocationRepository interface {
+		ListLocations() ([]Building, error)
+		GetBuildingByID(id uint) (*Building, error)
+		GetFloorByID(id uint) (*Floor, error)
+		GetZoneByID(id uint) (*Zone, error)
+		CreateBuilding(building *Building) error
+		CreateFloor(floor *Floor) error
+		UpdateFloor(floor *Floor) error
+		CreateZone(zone *Zone) error
+	}
+
+	// BookingRepository store the bookings, their series and status history. The
+	// IfAvailable writes fail with ErrUserAlreadyBooked or ErrSeatAlreadyBooked when
+	// an active booking overlaps, the check and the write are atomic
+	BookingRepository interface {
+		QueryBooking(bookingId uint) (*Booking, error)
+		CreateBookingIfAvailable(booking *Booking) error
+		UpdateBookingIfAvailable(booking *Booking) error
+		CreateBookingSeriesIfAvailable(series *BookingSeries, bookings []Booking) ([]error, error)
+		UpdateBookingStatus(booking *Booking, status string) error
+		ReseverBooking(booking *Booking) error
+		CheckOutBooking(booking *Booking, now time.Time) error
+		ReleaseBooking(config ReleaseConfig, now time.Time) ([]Booking, error)
+		FindBookingHistory(bookingID int) ([]BookingStatusHistory, error)
+		FindUserSeatBooking(userID, seatID uint, fromTime, toTime time.Time) (*Booking, error)
+		FindActiveBookingsBySeatIDs(seatIDs []uint, fromTime, toTime time.Time) ([]Booking, error)
+		FindBookingsByUserID(userID uint, filter string, after *bookingCursor, limit int, now time.Time) ([]BookingView, error)
+		ExportBookings(filter BookingExportFilter, fn func(row BookingExportRow) error) error
+		ReportBookings(from, to time.Time, fn func(row ReportBooking) error) error
+		CountUpcomingBookingsBySeatID(seatID uint, now time.Time) (int64, error)
+		GetBookingSeriesByID(id uint) (*BookingSeries, error)
+		CancelUpcomingBookingsBySeriesID(seriesID uint, now time.Time) ([]Booking, error)
+	}
+
+	// WaitlistRepository store the waitlist, entries are served in the order they were
+	// created. An unexpired offer holds its seat, the IfAvailable writes of other users
+	// fail with ErrSeatAlreadyBooked when they overlap it
+	WaitlistRepository interface {
+		CreateWaitlistEntry(entry *WaitlistEntry) error
+		GetWaitlistEntryByID(id uint) (*WaitlistEntry, error)
+		FindWaitlistEntriesByUserID(userID uint, now time.Time) ([]WaitlistEntry, error)
+		UpdateWaitlistEntryStatus(entry *WaitlistEntry, status string) error
+		OfferWaitlistSeat(seat *Seat, fromTime, toTime, now, expiresAt time.Time) ([]WaitlistEntry, error)
+		ExpireWaitlistOffers(now time.Time) ([]WaitlistEntry, error)
+		ClaimWaitlistOffer(entry *WaitlistEntry, now time.Time) (*Booking, error)
+	}
+
+	// NotificationRepository store the notification outbox. Due notifications are claimed
+	// for a lease so that concurrent dispatchers do not send them twice
+	NotificationRepository interface {
+		CreateNotifications(notifications []Notification) error
+		ClaimDueNotifications(now time.Time, lease time.Duration, limit int) ([]Notification, error)
+		UpdateNotificationDelivery(notification *Notification) error
+		FindInAppNotifications(userID uint, limit int) ([]Notification, error)
+		MarkNotificationRead(userID, id uint, now time.Time) (*Notification, error)
+	}
+
+	// ReminderRepository store the reminder jobs of the bookings. A booking has one job
+	// of each kind per start time, and a job is completed once only so that concurrent
+	// schedulers do not remind twice
+	ReminderRepository interface {
+		FindUnscheduledReminders(fromTime, toTime time.Time) ([]releaseCandidate, error)
+		CreateReminderJobs(jobs []ReminderJob) error
+		FindDueReminderJobs(now time.Time, limit int) ([]ReminderJob, error)
+		CompleteReminderJob(job *ReminderJob, status string, notifications []Notification) (bool, error)
+	}
+
+	// JobRepository store the leases and metrics of the background jobs. A lease is held by
+	// one instance until it expires, taking and renewing it is atomic
+	JobRepository interface {
+		AcquireJobLease(name, holder string, now time.Time, ttl time.Duration) (bool, error)
+		ReleaseJobLease(name, holder string, now time.Time) error
+		RecordJobRun(name string, run JobRun) error
+		ListJobs() ([]Job, error)
+	}
+
+	// Storage is everything the handlers read and write, a missing record is reported
+	// as gorm.ErrRecordNotFound by every implementation. WithTransaction run fn with a
+	// Storage whose writes are all undone when fn returns an error, fn must only use
+	// that Storage
+	Storage interface {
+		UserRepository
+		SeatRepository
+		LocationRepository
+		BookingRepository
+		WaitlistRepository
+		NotificationRepository
+		ReminderRepository
+		JobRepository
+		WithTransaction(fn func(s Storage) error) error
+	}
+)
+
+var (
+	_ Storage = (*DataStorage)(nil)
+	_ Storage = (*MemoryStorage)(nil)
+)
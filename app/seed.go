@@ -0,0 +1,444 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Seed change actions
+const (
+	SeedActionCreate = "create"
+	SeedActionUpdate = "update"
+)
+
+type (
+	// SeedChange is one difference between the fixtures and the database
+	SeedChange struct {
+		Action string
+		Kind   string
+		Key    string
+		Fields []string
+	}
+
+	// seeder upsert fixtures, locations are indexed by their path of names
+	seeder struct {
+		s         Storage
+		dryRun    bool
+		changes   []SeedChange
+		buildings map[string]*Building
+		floors    map[string]*Floor
+		zones     map[string]*Zone
+		zonePaths map[uint]string
+	}
+)
+
+func (c SeedChange) String() string {
+	sign := "+"
+	if c.Action == SeedActionUpdate {
+		sign = "~"
+	}
+	s := sign + " " + c.Kind + " " + c.Key
+	if len(c.Fields) > 0 {
+		s += " " + strings.Join(c.Fields, ", ")
+	}
+	return s
+}
+
+// Seed upsert the fixtures in one transaction and return the changes it made, with
+// dryRun nothing is written and the changes are the ones a run would make. Nothing is
+// written when it fails. Seeding the same fixtures again makes no change
+func Seed(s Storage, fixtures Fixtures, dryRun bool) ([]SeedChange, error) {
+	if err := fixtures.Validate(); err != nil {
+		return nil, err
+	}
+
+	var changes []SeedChange
+	err := s.WithTransaction(func(s Storage) error {
+		sd, err := newSeeder(s, dryRun)
+		if err != nil {
+			return err
+		}
+		if err := sd.checkSeatZones(fixtures); err != nil {
+			return err
+		}
+
+		if err := sd.seedLocations(fixtures.Buildings); err != nil {
+			return err
+		}
+		if err := sd.seedSeats(fixtures.Seats); err != nil {
+			return err
+		}
+		if err := sd.seedUsers(fixtures.Users); err != nil {
+			return err
+		}
+		changes = sd.changes
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Validate check the fixtures on their own, references to zones are checked by Seed
+func (f Fixtures) Validate() error {
+	for _, building := range f.Buildings {
+		if building.Name == "" {
+			return errors.New("building name is required")
+		}
+		levels := map[string]int{}
+		for _, floor := range building.Floors {
+			if floor.Name == "" {
+				return fmt.Errorf("building %s: floor name is required", building.Name)
+			}
+			if level, ok := levels[floor.Name]; ok && level != floor.Level {
+				return fmt.Errorf("floor %s: conflicting levels %d and %d", locationPath(building.Name, floor.Name), level, floor.Level)
+			}
+			levels[floor.Name] = floor.Level
+			for _, zone := range floor.Zones {
+				if zone == "" {
+					return fmt.Errorf("floor %s: zone name is required", locationPath(building.Name, floor.Name))
+				}
+			}
+		}
+	}
+
+	numbers := map[string]bool{}
+	for _, seat := range f.Seats {
+		if err := seat.validate(); err != nil {
+			return err
+		}
+		if numbers[seat.Number] {
+			return fmt.Errorf("seat %s: duplicated", seat.Number)
+		}
+		numbers[seat.Number] = true
+	}
+
+	emails := map[string]bool{}
+	for _, user := range f.Users {
+		switch {
+		case user.Email == "":
+			return errors.New("user email is required")
+		case emails[user.Email]:
+			return fmt.Errorf("user %s: duplicated", user.Email)
+		case user.Role != "" && !IsValidRole(user.Role):
+			return fmt.Errorf("user %s: invalid role %q", user.Email, user.Role)
+		case user.Password != "" && user.PasswordEnv != "":
+			return fmt.Errorf("user %s: password and password_env are exclusive", user.Email)
+		case user.password() != "" && (len(user.password()) < 8 || len(user.password()) > 72):
+			return fmt.Errorf("user %s: password must be 8 to 72 characters", user.Email)
+		}
+		emails[user.Email] = true
+	}
+	return nil
+}
+
+func (f SeatFixture) validate() error {
+	switch {
+	case f.Number == "":
+		return errors.New("seat number is required")
+	case f.Type != "" && !IsValidSeatType(f.Type):
+		return fmt.Errorf("seat %s: invalid type %q", f.Number, f.Type)
+	case (f.Building != "" || f.Floor != "" || f.Zone != "") && (f.Building == "" || f.Floor == "" || f.Zone == ""):
+		return fmt.Errorf("seat %s: building, floor and zone are required together", f.Number)
+	}
+	return nil
+}
+
+// zonePath return the path of the seat's zone, "-" for a seat without location
+func (f SeatFixture) zonePath() string {
+	if f.Zone == "" {
+		return "-"
+	}
+	return locationPath(f.Building, f.Floor, f.Zone)
+}
+
+// password return the password of the user, read from PasswordEnv when it is set
+func (f UserFixture) password() string {
+	if f.PasswordEnv != "" {
+		return os.Getenv(f.PasswordEnv)
+	}
+	return f.Password
+}
+
+func newSeeder(s Storage, dryRun bool) (*seeder, error) {
+	sd := &seeder{
+		s:         s,
+		dryRun:    dryRun,
+		buildings: map[string]*Building{},
+		floors:    map[string]*Floor{},
+		zones:     map[string]*Zone{},
+		zonePaths: map[uint]string{},
+	}
+	if err := sd.loadLocations(); err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+func (sd *seeder) loadLocations() error {
+	buildings, err := sd.s.ListLocations()
+	if err != nil {
+		return err
+	}
+	for i := range buildings {
+		building := &buildings[i]
+		sd.buildings[building.Name] = building
+		for j := range building.Floors {
+			floor := &building.Floors[j]
+			sd.floors[locationPath(building.Name, floor.Name)] = floor
+			for k := range floor.Zones {
+				zone := &floor.Zones[k]
+				path := locationPath(building.Name, floor.Name, zone.Name)
+				sd.zones[path] = zone
+				sd.zonePaths[zone.ID] = path
+			}
+		}
+	}
+	return nil
+}
+
+// checkSeatZones fail before anything is written when a seat is in a zone which
+// is neither in the database nor in the fixtures
+func (sd *seeder) checkSeatZones(fixtures Fixtures) error {
+	paths := map[string]bool{}
+	for _, building := range fixtures.Buildings {
+		for _, floor := range building.Floors {
+			for _, zone := range floor.Zones {
+				paths[locationPath(building.Name, floor.Name, zone)] = true
+			}
+		}
+	}
+	for _, seat := range fixtures.Seats {
+		if seat.Zone == "" {
+			continue
+		}
+		path := seat.zonePath()
+		if _, ok := sd.zones[path]; !ok && !paths[path] {
+			return fmt.Errorf("seat %s: zone %s not found", seat.Number, path)
+		}
+	}
+	return nil
+}
+
+func (sd *seeder) seedLocations(fixtures []BuildingFixture) error {
+	for _, fixture := range fixtures {
+		building, ok := sd.buildings[fixture.Name]
+		if !ok {
+			building = &Building{Name: fixture.Name}
+			sd.record(SeedActionCreate, "building", fixture.Name)
+			if err := sd.write(func() error { return sd.s.CreateBuilding(building) }); err != nil {
+				return err
+			}
+			sd.buildings[fixture.Name] = building
+		}
+
+		for _, floorFixture := range fixture.Floors {
+			floorPath := locationPath(fixture.Name, floorFixture.Name)
+			floor, ok := sd.floors[floorPath]
+			if !ok {
+				floor = &Floor{BuildingID: building.ID, Name: floorFixture.Name, Level: floorFixture.Level}
+				sd.record(SeedActionCreate, "floor", floorPath, fmt.Sprintf("level=%d", floor.Level))
+				if err := sd.write(func() error { return sd.s.CreateFloor(floor) }); err != nil {
+					return err
+				}
+				sd.floors[floorPath] = floor
+			} else if floor.Level != floorFixture.Level {
+				sd.record(SeedActionUpdate, "floor", floorPath, fmt.Sprintf("level: %d -> %d", floor.Level, floorFixture.Level))
+				floor.Level = floorFixture.Level
+				if err := sd.write(func() error { return sd.s.UpdateFloor(floor) }); err != nil {
+					return err
+				}
+			}
+
+			for _, name := range floorFixture.Zones {
+				zonePath := locationPath(fixture.Name, floorFixture.Name, name)
+				if _, ok := sd.zones[zonePath]; ok {
+					continue
+				}
+				zone := &Zone{FloorID: floor.ID, Name: name}
+				sd.record(SeedActionCreate, "zone", zonePath)
+				if err := sd.write(func() error { return sd.s.CreateZone(zone) }); err != nil {
+					return err
+				}
+				sd.zones[zonePath] = zone
+				sd.zonePaths[zone.ID] = zonePath
+			}
+		}
+	}
+	return nil
+}
+
+func (sd *seeder) seedSeats(fixtures []SeatFixture) error {
+	seats, err := sd.s.ListSeats(true)
+	if err != nil {
+		return err
+	}
+	// a seat number may have been reused after its seat was deleted, the live seat wins
+	byNumber := map[string]*Seat{}
+	for i := range seats {
+		if current, ok := byNumber[seats[i].Number]; !ok || current.DeletedAt.Valid {
+			byNumber[seats[i].Number] = &seats[i]
+		}
+	}
+
+	for _, fixture := range fixtures {
+		var (
+			zoneID   *uint
+			zonePath = fixture.zonePath()
+		)
+		if fixture.Zone != "" {
+			zoneID = &sd.zones[zonePath].ID
+		}
+		seatType := fixture.Type
+		if seatType == "" {
+			seatType = SeatTypeDesk
+		}
+
+		seat, ok := byNumber[fixture.Number]
+		if !ok {
+			seat = &Seat{
+				Number:      fixture.Number,
+				ZoneID:      zoneID,
+				Type:        seatType,
+				DualMonitor: fixture.DualMonitor,
+				NearWindow:  fixture.NearWindow,
+				Accessible:  fixture.Accessible,
+			}
+			sd.record(SeedActionCreate, "seat", fixture.Number, "zone="+zonePath, "type="+seatType)
+			if err := sd.write(func() error { return sd.s.CreateSeat(seat) }); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var fields []string
+		if current := sd.seatZonePath(seat); current != zonePath {
+			fields = append(fields, fmt.Sprintf("zone: %s -> %s", current, zonePath))
+		}
+		if seat.Type != seatType {
+			fields = append(fields, fmt.Sprintf("type: %s -> %s", seat.Type, seatType))
+		}
+		for _, field := range []struct {
+			name            string
+			current, wanted bool
+		}{
+			{"dual_monitor", seat.DualMonitor, fixture.DualMonitor},
+			{"near_window", seat.NearWindow, fixture.NearWindow},
+			{"accessible", seat.Accessible, fixture.Accessible},
+		} {
+			if field.current != field.wanted {
+				fields = append(fields, fmt.Sprintf("%s: %t -> %t", field.name, field.current, field.wanted))
+			}
+		}
+		deleted := seat.DeletedAt.Valid
+		if deleted {
+			fields = append(fields, "restored")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		sd.record(SeedActionUpdate, "seat", fixture.Number, fields...)
+		seat.Zone, seat.ZoneID, seat.Type = nil, zoneID, seatType
+		seat.DualMonitor, seat.NearWindow, seat.Accessible = fixture.DualMonitor, fixture.NearWindow, fixture.Accessible
+		err := sd.write(func() error {
+			if deleted {
+				if err := sd.s.RestoreSeat(seat.ID); err != nil {
+					return err
+				}
+				seat.DeletedAt = gorm.DeletedAt{}
+			}
+			return sd.s.UpdateSeat(seat)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sd *seeder) seatZonePath(seat *Seat) string {
+	if seat.ZoneID == nil {
+		return "-"
+	}
+	if path, ok := sd.zonePaths[*seat.ZoneID]; ok {
+		return path
+	}
+	return fmt.Sprintf("#%d", *seat.ZoneID)
+}
+
+func (sd *seeder) seedUsers(fixtures []UserFixture) error {
+	for _, fixture := range fixtures {
+		user, err := sd.s.GetUserByEmail(fixture.Email)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err != nil {
+			password := fixture.password()
+			switch {
+			case password == "" && fixture.PasswordEnv != "":
+				return fmt.Errorf("user %s: %s is required to create the user", fixture.Email, fixture.PasswordEnv)
+			case password == "":
+				return fmt.Errorf("user %s: password is required to create the user", fixture.Email)
+			}
+			role := fixture.Role
+			if role == "" {
+				role = RoleEmployee
+			}
+			sd.record(SeedActionCreate, "user", fixture.Email, "role="+role)
+			err := sd.write(func() error {
+				passwordHash, err := hashPassword(password)
+				if err != nil {
+					return err
+				}
+				return sd.s.Create(&User{Email: fixture.Email, Name: fixture.Name, Role: role, PasswordHash: passwordHash})
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// the password of an existing user is never reset, empty fields are kept
+		var fields []string
+		if fixture.Name != "" && fixture.Name != user.Name {
+			fields = append(fields, fmt.Sprintf("name: %s -> %s", user.Name, fixture.Name))
+			user.Name = fixture.Name
+		}
+		if fixture.Role != "" && fixture.Role != user.Role {
+			fields = append(fields, fmt.Sprintf("role: %s -> %s", user.Role, fixture.Role))
+			user.Role = fixture.Role
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		sd.record(SeedActionUpdate, "user", fixture.Email, fields...)
+		if err := sd.write(func() error { return sd.s.UpdateUser(user) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sd *seeder) record(action, kind, key string, fields ...string) {
+	sd.changes = append(sd.changes, SeedChange{Action: action, Kind: kind, Key: key, Fields: fields})
+}
+
+// write run fn unless this is a dry run
+func (sd *seeder) write(fn func() error) error {
+	if sd.dryRun {
+		return nil
+	}
+	return fn()
+}
+
+// locationPath join the names of a building, floor and zone
+func locationPath(names ...string) string {
+	return strings.Join(names, "/")
+}
@@ -0,0 +1,341 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFixtureFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func changeStrings(changes []SeedChange) []string {
+	result := []string{}
+	for _, change := range changes {
+		result = append(result, change.String())
+	}
+	return result
+}
+
+func TestLoadFixtures(t *testing.T) {
+	yamlPath := writeFixtureFile(t, "seed.yaml", `
+buildings:
+  - name: HQ
+    floors:
+      - name: Ground
+        level: 0
+        zones: [North]
+users:
+  - email: admin@example.com
+    role: super_admin
+    password: password1
+  - email: facilities@example.com
+    role: facility_admin
+    password_env: SEED_FACILITIES_PASSWORD
+`)
+	floorsPath := writeFixtureFile(t, "floors.csv", "building,floor,level,zone\nHQ,Ground,0,South\nHQ,First,1,\n")
+	seatsPath := writeFixtureFile(t, "seats.csv", "number,building,floor,zone,type,dual_monitor\nA1,HQ,Ground,North,booth,true\nA2,,,,,\n")
+	badPath := writeFixtureFile(t, "seats.csv", "number,dual_monitor\nA1,maybe\n")
+
+	type args struct {
+		paths []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    Fixtures
+		wantErr bool
+	}{
+		{
+			name: "yaml and csv files are merged",
+			args: args{paths: []string{yamlPath, floorsPath, seatsPath}},
+			want: Fixtures{
+				Buildings: []BuildingFixture{{
+					Name: "HQ",
+					Floors: []FloorFixture{
+						{Name: "Ground", Level: 0, Zones: []string{"North", "South"}},
+						{Name: "First", Level: 1},
+					},
+				}},
+				Seats: []SeatFixture{
+					{Number: "A1", Building: "HQ", Floor: "Ground", Zone: "North", Type: SeatTypeBooth, DualMonitor: true},
+					{Number: "A2"},
+				},
+				Users: []UserFixture{
+					{Email: "admin@example.com", Role: RoleSuperAdmin, Password: "password1"},
+					{Email: "facilities@example.com", Role: RoleFacilityAdmin, PasswordEnv: "SEED_FACILITIES_PASSWORD"},
+				},
+			},
+		},
+		{
+			name:    "invalid boolean",
+			args:    args{paths: []string{badPath}},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported file",
+			args:    args{paths: []string{"seed.json"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadFixtures(tt.args.paths...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFixtures_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		fixtures Fixtures
+		wantErr  bool
+	}{
+		{
+			name: "valid",
+			fixtures: Fixtures{
+				Seats: []SeatFixture{{Number: "A1", Building: "HQ", Floor: "Ground", Zone: "North"}},
+				Users: []UserFixture{{Email: "a@example.com", Password: "password1"}},
+			},
+		},
+		{
+			name:     "duplicated seat",
+			fixtures: Fixtures{Seats: []SeatFixture{{Number: "A1"}, {Number: "A1"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "partial seat location",
+			fixtures: Fixtures{Seats: []SeatFixture{{Number: "A1", Zone: "North"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid seat type",
+			fixtures: Fixtures{Seats: []SeatFixture{{Number: "A1", Type: "sofa"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid role",
+			fixtures: Fixtures{Users: []UserFixture{{Email: "a@example.com", Role: "root"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "short password",
+			fixtures: Fixtures{Users: []UserFixture{{Email: "a@example.com", Password: "short"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "password and password_env",
+			fixtures: Fixtures{Users: []UserFixture{{Email: "a@example.com", Password: "password1", PasswordEnv: "SEED_PASSWORD"}}},
+			wantErr:  true,
+		},
+		{
+			name: "conflicting floor levels",
+			fixtures: Fixtures{Buildings: []BuildingFixture{{
+				Name:   "HQ",
+				Floors: []FloorFixture{{Name: "Ground", Level: 0}, {Name: "Ground", Level: 1}},
+			}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fixtures.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSeed(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		fixtures := Fixtures{
+			Buildings: []BuildingFixture{{
+				Name:   "HQ",
+				Floors: []FloorFixture{{Name: "Ground", Level: 0, Zones: []string{"North"}}},
+			}},
+			Seats: []SeatFixture{
+				{Number: "A1", Building: "HQ", Floor: "Ground", Zone: "North", DualMonitor: true},
+				{Number: "A2"},
+			},
+			Users: []UserFixture{{Email: "admin@example.com", Name: "Admin", Role: RoleSuperAdmin, Password: "password1"}},
+		}
+		wantCreate := []string{
+			"+ building HQ",
+			"+ floor HQ/Ground level=0",
+			"+ zone HQ/Ground/North",
+			"+ seat A1 zone=HQ/Ground/North, type=desk",
+			"+ seat A2 zone=-, type=desk",
+			"+ user admin@example.com role=super_admin",
+		}
+
+		changes, err := Seed(s, fixtures, true)
+		require.NoError(t, err)
+		assert.Equal(t, wantCreate, changeStrings(changes))
+		seats, err := s.ListSeats(true)
+		require.NoError(t, err)
+		assert.Empty(t, seats, "dry run must not write")
+
+		changes, err = Seed(s, fixtures, false)
+		require.NoError(t, err)
+		assert.Equal(t, wantCreate, changeStrings(changes))
+
+		changes, err = Seed(s, fixtures, false)
+		require.NoError(t, err)
+		assert.Empty(t, changes, "seeding again must not change anything")
+
+		seat, err := s.GetSeatByNumber("A1")
+		require.NoError(t, err)
+		require.NotNil(t, seat.ZoneID)
+		user, err := s.GetUserByEmail("admin@example.com")
+		require.NoError(t, err)
+		assert.True(t, checkPassword(user.PasswordHash, "password1"))
+
+		// changed fixtures update the records, a deleted seat is restored
+		require.NoError(t, s.DeleteSeat(seat.ID))
+		fixtures.Buildings[0].Floors[0].Level = 1
+		fixtures.Seats[0].Type = SeatTypeBooth
+		fixtures.Seats[0].DualMonitor = false
+		fixtures.Seats[1] = SeatFixture{Number: "A2", Building: "HQ", Floor: "Ground", Zone: "North"}
+		fixtures.Users[0].Role = RoleFacilityAdmin
+		fixtures.Users[0].Password = "password2"
+		wantUpdate := []string{
+			"~ floor HQ/Ground level: 0 -> 1",
+			"~ seat A1 type: desk -> booth, dual_monitor: true -> false, restored",
+			"~ seat A2 zone: - -> HQ/Ground/North",
+			"~ user admin@example.com role: super_admin -> facility_admin",
+		}
+
+		changes, err = Seed(s, fixtures, true)
+		require.NoError(t, err)
+		assert.Equal(t, wantUpdate, changeStrings(changes))
+
+		changes, err = Seed(s, fixtures, false)
+		require.NoError(t, err)
+		assert.Equal(t, wantUpdate, changeStrings(changes))
+
+		changes, err = Seed(s, fixtures, false)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+
+		seat, err = s.GetSeatByNumber("A1")
+		require.NoError(t, err)
+		assert.Equal(t, SeatTypeBooth, seat.Type)
+		assert.False(t, seat.DualMonitor)
+		user, err = s.GetUserByEmail("admin@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, RoleFacilityAdmin, user.Role)
+		assert.True(t, checkPassword(user.PasswordHash, "password1"), "password of an existing user must be kept")
+	})
+}
+
+func TestSeed_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		fixtures Fixtures
+	}{
+		{
+			name:     "unknown zone",
+			fixtures: Fixtures{Seats: []SeatFixture{{Number: "A1", Building: "HQ", Floor: "Ground", Zone: "North"}}},
+		},
+		{
+			name:     "new user without password",
+			fixtures: Fixtures{Users: []UserFixture{{Email: "a@example.com"}}},
+		},
+		{
+			name:     "new user without the password variable",
+			fixtures: Fixtures{Users: []UserFixture{{Email: "a@example.com", PasswordEnv: "SEED_UNSET_PASSWORD"}}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Seed(NewMemoryStorage(), tt.fixtures, false)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// failingSeatStorage fail to create the seat number failNumber, in and out of transactions
+type failingSeatStorage struct {
+	Storage
+	failNumber string
+}
+
+func (s failingSeatStorage) CreateSeat(seat *Seat) error {
+	if seat.Number == s.failNumber {
+		return errors.New("create seat failed")
+	}
+	return s.Storage.CreateSeat(seat)
+}
+
+func (s failingSeatStorage) WithTransaction(fn func(s Storage) error) error {
+	return s.Storage.WithTransaction(func(tx Storage) error {
+		return fn(failingSeatStorage{Storage: tx, failNumber: s.failNumber})
+	})
+}
+
+func TestSeed_WriteError(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		fixtures := Fixtures{
+			Buildings: []BuildingFixture{{
+				Name:   "HQ",
+				Floors: []FloorFixture{{Name: "Ground", Level: 0, Zones: []string{"North"}}},
+			}},
+			Seats: []SeatFixture{{Number: "A1"}, {Number: "A2"}},
+		}
+		changes, err := Seed(failingSeatStorage{Storage: s, failNumber: "A2"}, fixtures, false)
+		require.Error(t, err)
+		assert.Nil(t, changes)
+
+		buildings, err := s.ListLocations()
+		require.NoError(t, err)
+		assert.Empty(t, buildings, "a failed seed must not write anything")
+		seats, err := s.ListSeats(true)
+		require.NoError(t, err)
+		assert.Empty(t, seats, "a failed seed must not write anything")
+	})
+}
+
+func TestSeed_ExampleFixtures(t *testing.T) {
+	fixtures, err := LoadFixtures("../fixtures/seed.yaml", "../fixtures/seats.csv")
+	require.NoError(t, err)
+
+	// the admins are only created with the passwords from the environment
+	t.Setenv("SEED_ADMIN_PASSWORD", "")
+	t.Setenv("SEED_FACILITIES_PASSWORD", "")
+	_, err = Seed(NewMemoryStorage(), fixtures, false)
+	require.ErrorContains(t, err, "SEED_ADMIN_PASSWORD is required")
+
+	t.Setenv("SEED_ADMIN_PASSWORD", "admin-password")
+	t.Setenv("SEED_FACILITIES_PASSWORD", "facilities-password")
+	s := NewMemoryStorage()
+	changes, err := Seed(s, fixtures, false)
+	require.NoError(t, err)
+	assert.NotEmpty(t, changes)
+
+	seats, err := s.ListSeats(false)
+	require.NoError(t, err)
+	assert.Len(t, seats, len(fixtures.Seats))
+	admin, err := s.GetUserByEmail("admin@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, RoleSuperAdmin, admin.Role)
+	assert.True(t, checkPassword(admin.PasswordHash, "admin-password"))
+}
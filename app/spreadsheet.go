@@ -0,0 +1,247 @@
+package app
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Spreadsheet formats
+const (
+	SpreadsheetCSV  = "csv"
+	SpreadsheetXLSX = "xlsx"
+)
+
+const (
+	// maxSpreadsheetRows is the number of rows read from a spreadsheet, header included
+	maxSpreadsheetRows = 10000
+	// maxSpreadsheetUnzipSize bound the size of the unzipped parts of a XLSX file
+	maxSpreadsheetUnzipSize = 100 << 20
+)
+
+var (
+	ErrUnsupportedSpreadsheet = errors.New("unsupported spreadsheet format, use csv or xlsx")
+	ErrInvalidSpreadsheet     = errors.New("invalid spreadsheet")
+	errTooManyRows            = fmt.Errorf("more than %d rows", maxSpreadsheetRows)
+)
+
+// spreadsheetContentTypes are the content types of the spreadsheet formats
+var spreadsheetContentTypes = map[string]string{
+	SpreadsheetCSV:  "text/csv",
+	SpreadsheetXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+type (
+	// spreadsheetWriter write a spreadsheet row by row, Close must be called to complete it
+	spreadsheetWriter interface {
+		WriteRow(values []string) error
+		Close() error
+	}
+
+	csvSpreadsheetWriter struct {
+		w *csv.Writer
+	}
+
+	// xlsxSpreadsheetWriter write the rows with the excelize stream writer which keeps
+	// them out of memory until the file is written on Close
+	xlsxSpreadsheetWriter struct {
+		out    io.Writer
+		file   *excelize.File
+		stream *excelize.StreamWriter
+		row    int
+	}
+)
+
+// SpreadsheetFormat return the format of the file by its extension
+func SpreadsheetFormat(name string) (string, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	if _, ok := spreadsheetContentTypes[format]; !ok {
+		return "", ErrUnsupportedSpreadsheet
+	}
+	return format, nil
+}
+
+// readSpreadsheet read every row of a CSV file or of the first sheet of a XLSX file, it
+// stops with an error past maxSpreadsheetRows rows
+func readSpreadsheet(r io.Reader, format string) ([][]string, error) {
+	switch format {
+	case SpreadsheetCSV:
+		reader := csv.NewReader(r)
+		reader.TrimLeadingSpace = true
+		reader.FieldsPerRecord = -1
+		var rows [][]string
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return rows, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if len(rows) == maxSpreadsheetRows {
+				return nil, errTooManyRows
+			}
+			rows = append(rows, record)
+		}
+	case SpreadsheetXLSX:
+		f, err := excelize.OpenReader(r, excelize.Options{UnzipSizeLimit: maxSpreadsheetUnzipSize})
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readSheetRows(f, f.GetSheetName(0))
+	}
+	return nil, ErrUnsupportedSpreadsheet
+}
+
+// readSheetRows read the rows of the sheet like GetRows, the blank rows are only kept
+// before a row with values so that the trailing blank rows do not count toward the limit
+func readSheetRows(f *excelize.File, sheet string) ([][]string, error) {
+	it, err := f.Rows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var (
+		rows  [][]string
+		blank int
+	)
+	for it.Next() {
+		row, err := it.Columns()
+		if err != nil {
+			return nil, err
+		}
+		if len(row) == 0 {
+			blank++
+			continue
+		}
+		if len(rows)+blank >= maxSpreadsheetRows {
+			return nil, errTooManyRows
+		}
+		rows = append(rows, make([][]string, blank)...)
+		rows = append(rows, row)
+		blank = 0
+	}
+	return rows, it.Error()
+}
+
+// escapeSpreadsheetCell prefix the values a spreadsheet application would evaluate as a
+// formula with a quote, it is applied to the user supplied values of the exports
+func escapeSpreadsheetCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func newSpreadsheetWriter(w io.Writer, format string) (spreadsheetWriter, error) {
+	switch format {
+	case SpreadsheetCSV:
+		return &csvSpreadsheetWriter{w: csv.NewWriter(w)}, nil
+	case SpreadsheetXLSX:
+		file := excelize.NewFile()
+		stream, err := file.NewStreamWriter(file.GetSheetName(0))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &xlsxSpreadsheetWriter{out: w, file: file, stream: stream}, nil
+	}
+	return nil, ErrUnsupportedSpreadsheet
+}
+
+func (w *csvSpreadsheetWriter) WriteRow(values []string) error {
+	return w.w.Write(values)
+}
+
+func (w *csvSpreadsheetWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *xlsxSpreadsheetWriter) WriteRow(values []string) error {
+	w.row++
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+	row := make([]interface{}, len(values))
+	for i, value := range values {
+		row[i] = value
+	}
+	return w.stream.SetRow(cell, row)
+}
+
+func (w *xlsxSpreadsheetWriter) Close() error {
+	defer w.file.Close()
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := w.file.WriteTo(w.out)
+	return err
+}
+
+// sheetRow read the columns of a spreadsheet row by header name
+type sheetRow struct {
+	columns map[string]int
+	record  []string
+}
+
+// sheetColumns index the header of a spreadsheet by lower case column name
+func sheetColumns(header []string) map[string]int {
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return columns
+}
+
+func (r sheetRow) has(column string) bool {
+	_, ok := r.columns[column]
+	return ok
+}
+
+func (r sheetRow) get(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+// empty report whether every cell of the row is blank
+func (r sheetRow) empty() bool {
+	for _, value := range r.record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (r sheetRow) int(column string) (int, error) {
+	value, err := strconv.Atoi(r.get(column))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", column, r.get(column))
+	}
+	return value, nil
+}
+
+// bool parse the column, an empty value is false
+func (r sheetRow) bool(column string) (bool, error) {
+	if r.get(column) == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(r.get(column))
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q", column, r.get(column))
+	}
+	return value, nil
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+	tokenTypeSeat    = "seat"
+
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+type (
+	tokenClaims struct {
+		TokenType string `json:"typ"`
+		Role      string `json:"role,omitempty"`
+		jwt.RegisteredClaims
+	}
+
+	// tokenSubject is the identity carried by a verified token
+	tokenSubject struct {
+		UserID uint
+		Role   string
+	}
+)
+
+// issueToken sign a token of the given type for the user
+func issueToken(secret string, user *User, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := tokenClaims{
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	// the role is only trusted from access tokens, refresh always reloads it from the user
+	if tokenType == tokenTypeAccess {
+		claims.Role = user.Role
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// issueTokenPair sign an access token and a refresh token for the user
+func issueTokenPair(secret string, user *User) (accessToken, refreshToken string, err error) {
+	accessToken, err = issueToken(secret, user, tokenTypeAccess, accessTokenTTL)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err = issueToken(secret, user, tokenTypeRefresh, refreshTokenTTL)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// parseToken verify the token signature, expiry and type then return its subject
+func parseToken(secret, tokenString, tokenType string) (tokenSubject, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return tokenSubject{}, ErrInvalidToken
+	}
+	if claims.TokenType != tokenType {
+		return tokenSubject{}, ErrInvalidToken
+	}
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || userID == 0 {
+		return tokenSubject{}, ErrInvalidToken
+	}
+	return tokenSubject{UserID: uint(userID), Role: claims.Role}, nil
+}
+
+// issueSeatToken sign the token of a seat's QR code. It does not expire so printed codes
+// keep working, changing jwt_secret invalidates every one of them
+func issueSeatToken(secret string, seatID uint) (string, error) {
+	claims := tokenClaims{
+		TokenType: tokenTypeSeat,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:  strconv.FormatUint(uint64(seatID), 10),
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// parseSeatToken verify the signature and type of a seat token then return its seat id
+func parseSeatToken(secret, tokenString string) (uint, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || claims.TokenType != tokenTypeSeat {
+		return 0, ErrInvalidToken
+	}
+	seatID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || seatID == 0 {
+		return 0, ErrInvalidToken
+	}
+	return uint(seatID), nil
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNoWaitlistOffer      = errors.New("no waitlist offer to claim")
+	ErrWaitlistOfferExpired = errors.New("waitlist offer expired")
+)
+
+type (
+	// WaitlistConfig is the `waitlist` section of config.yaml
+	WaitlistConfig struct {
+		// ClaimWindow is how long an offered seat is held before it moves to the next user in line
+		ClaimWindow time.Duration `mapstructure:"claim_window"`
+	}
+
+	// Waitlist offer the seats freed by cancellations, check-outs and releases to the users
+	// waiting for them, first come first served. Offers not claimed in time are expired by
+	// the release loop and their seat passed on
+	Waitlist struct {
+		seats    SeatRepository
+		entries  WaitlistRepository
+		config   WaitlistConfig
+		notifier *Notifier
+	}
+)
+
+// DefaultWaitlistConfig hold offered seats for 15 minutes
+func DefaultWaitlistConfig() WaitlistConfig {
+	return WaitlistConfig{ClaimWindow: 15 * time.Minute}
+}
+
+// Validate check the durations of the config
+func (c WaitlistConfig) Validate() error {
+	if c.ClaimWindow <= 0 {
+		return errors.New("waitlist.claim_window must be positive")
+	}
+	return nil
+}
+
+func NewWaitlist(seats SeatRepository, entries WaitlistRepository, config WaitlistConfig, notifier *Notifier) *Waitlist {
+	return &Waitlist{
+		seats:    seats,
+		entries:  entries,
+		config:   config,
+		notifier: notifier,
+	}
+}
+
+// PassOn offer the seat of an offer which has expired or been declined to the next users in line
+func (w *Waitlist) PassOn(entry WaitlistEntry, now time.Time) {
+	if entry.OfferedSeatID != nil {
+		w.SeatFreed(*entry.OfferedSeatID, entry.StartTime, entry.EndTime, now)
+	}
+}
+
+// ExpireOffers expire the offers not claimed before now and pass their seats on
+func (w *Waitlist) ExpireOffers(now time.Time) {
+	expired, err := w.entries.ExpireWaitlistOffers(now)
+	if err != nil {
+		log.WithError(err).Error("expire waitlist offers fail")
+		return
+	}
+	for _, entry := range expired {
+		w.PassOn(entry, now)
+	}
+}
+
+// SeatFreed offer the seat, no longer held in [fromTime, toTime], to the users waiting
+// for it from now on
+func (w *Waitlist) SeatFreed(seatID uint, fromTime, toTime, now time.Time) {
+	if fromTime.Before(now) {
+		fromTime = now
+	}
+	if !fromTime.Before(toTime) {
+		return
+	}
+
+	seat, err := w.seats.GetSeatByID(seatID, false)
+	if err != nil {
+		// a deleted seat is not offered
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.WithError(err).WithField("seat_id", seatID).Error("waitlist offer fail")
+		}
+		return
+	}
+
+	offered, err := w.entries.OfferWaitlistSeat(seat, fromTime, toTime, now, now.Add(w.config.ClaimWindow))
+	if err != nil {
+		log.WithError(err).WithField("seat_id", seatID).Error("waitlist offer fail")
+		return
+	}
+	for _, entry := range offered {
+		log.WithFields(log.Fields{"seat_id": seatID, "user_id": entry.UserID, "entry_id": entry.ID}).Info("waitlist seat offered")
+		w.notifier.WaitlistOffered(entry)
+	}
+}
+
+// booking return the booking claiming seatID for the rest of the entry's time range at now
+func (e *WaitlistEntry) booking(seatID uint, now time.Time) *Booking {
+	booking := &Booking{
+		UserID:    e.UserID,
+		SeatID:    seatID,
+		StartTime: e.StartTime,
+		EndTime:   e.EndTime,
+	}
+	if booking.StartTime.Before(now) {
+		booking.StartTime = now
+	}
+	return booking
+}
+
+// claimable return why the entry's offer cannot be claimed at now, or nil
+func (e *WaitlistEntry) claimable(now time.Time) error {
+	if e.Status != WaitlistStatusOffered || e.OfferedSeatID == nil {
+		return ErrNoWaitlistOffer
+	}
+	if !e.OfferExpiresAt.After(now) || !e.EndTime.After(now) {
+		return ErrWaitlistOfferExpired
+	}
+	return nil
+}
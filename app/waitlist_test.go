@@ -0,0 +1,331 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitlist(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		_, err := Seed(s, Fixtures{
+			Buildings: []BuildingFixture{{
+				Name:   "HQ",
+				Floors: []FloorFixture{{Name: "Ground", Level: 0, Zones: []string{"North"}}},
+			}},
+			Seats: []SeatFixture{{Number: "A1", Building: "HQ", Floor: "Ground", Zone: "North"}},
+		}, false)
+		require.NoError(t, err)
+		seat, err := s.GetSeatByNumber("A1")
+		require.NoError(t, err)
+		users := make([]*User, 4)
+		for i := range users {
+			users[i] = &User{Name: fmt.Sprintf("user %d", i), Email: fmt.Sprintf("user%d@example.com", i), Role: RoleEmployee}
+			require.NoError(t, s.Create(users[i]))
+		}
+
+		var (
+			config   = DefaultWaitlistConfig()
+			waitlist = NewWaitlist(s, s, config, NewNotifier(s, s, DefaultNotificationConfig()))
+			now      = time.Now()
+			from     = now.Add(time.Hour)
+			to       = now.Add(2 * time.Hour)
+		)
+		booking := &Booking{UserID: users[0].ID, SeatID: seat.ID, StartTime: from, EndTime: to}
+		require.NoError(t, s.CreateBookingIfAvailable(booking))
+		entries := []*WaitlistEntry{
+			{UserID: users[1].ID, SeatID: &seat.ID, StartTime: from, EndTime: to},
+			{UserID: users[2].ID, ZoneID: seat.ZoneID, StartTime: from, EndTime: to},
+			{UserID: users[3].ID, SeatID: &seat.ID, StartTime: from, EndTime: to},
+		}
+		for _, entry := range entries {
+			require.NoError(t, s.CreateWaitlistEntry(entry))
+		}
+		status := func(entry *WaitlistEntry) string {
+			stored, err := s.GetWaitlistEntryByID(entry.ID)
+			require.NoError(t, err)
+			return stored.Status
+		}
+
+		// nothing is offered while the seat is booked
+		waitlist.SeatFreed(seat.ID, from, to, now)
+		assert.Equal(t, WaitlistStatusWaiting, status(entries[0]))
+
+		require.NoError(t, s.UpdateBookingStatus(booking, BookingStatusCancelled))
+		waitlist.SeatFreed(seat.ID, from, to, now)
+		assert.Equal(t, WaitlistStatusOffered, status(entries[0]))
+		assert.Equal(t, WaitlistStatusWaiting, status(entries[1]))
+		assert.Equal(t, WaitlistStatusWaiting, status(entries[2]))
+
+		// the offer holds the seat for its user only
+		err = s.CreateBookingIfAvailable(&Booking{UserID: users[3].ID, SeatID: seat.ID, StartTime: from, EndTime: to})
+		assert.ErrorIs(t, err, ErrSeatAlreadyBooked)
+
+		// the offer is not claimed in time and moves to the next user in line
+		later := now.Add(config.ClaimWindow + time.Minute)
+		waitlist.ExpireOffers(later)
+		assert.Equal(t, WaitlistStatusExpired, status(entries[0]))
+		assert.Equal(t, WaitlistStatusOffered, status(entries[1]))
+		assert.Equal(t, WaitlistStatusWaiting, status(entries[2]))
+
+		_, err = s.ClaimWaitlistOffer(entries[0], later)
+		assert.ErrorIs(t, err, ErrNoWaitlistOffer)
+		_, err = s.ClaimWaitlistOffer(entries[1], later.Add(config.ClaimWindow))
+		assert.ErrorIs(t, err, ErrWaitlistOfferExpired)
+
+		claimed, err := s.ClaimWaitlistOffer(entries[1], later)
+		require.NoError(t, err)
+		assert.Equal(t, users[2].ID, claimed.UserID)
+		assert.Equal(t, seat.ID, claimed.SeatID)
+		assert.True(t, claimed.StartTime.Equal(from))
+		assert.Equal(t, WaitlistStatusClaimed, entries[1].Status)
+		require.NotNil(t, entries[1].BookingID)
+		assert.Equal(t, claimed.ID, *entries[1].BookingID)
+
+		waiting, err := s.FindWaitlistEntriesByUserID(users[3].ID, later)
+		require.NoError(t, err)
+		require.Len(t, waiting, 1)
+		assert.Equal(t, WaitlistStatusWaiting, waiting[0].Status)
+		waiting, err = s.FindWaitlistEntriesByUserID(users[2].ID, later)
+		require.NoError(t, err)
+		assert.Empty(t, waiting)
+	})
+}
+
+func TestOfferWaitlistSeat_OneOfferPerUser(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		seats := []*Seat{{Number: "A1"}, {Number: "A2"}}
+		for _, seat := range seats {
+			require.NoError(t, s.CreateSeat(seat))
+		}
+		user := &User{Name: "Jane", Email: "jane@example.com", Role: RoleEmployee}
+		require.NoError(t, s.Create(user))
+
+		now := time.Now()
+		from, to := now.Add(time.Hour), now.Add(2*time.Hour)
+		for _, seat := range seats {
+			require.NoError(t, s.CreateWaitlistEntry(&WaitlistEntry{UserID: user.ID, SeatID: &seat.ID, StartTime: from, EndTime: to}))
+		}
+
+		offered, err := s.OfferWaitlistSeat(seats[0], from, to, now, now.Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, offered, 1)
+		offered, err = s.OfferWaitlistSeat(seats[1], from, to, now, now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Empty(t, offered, "the user already has an offer over that time range")
+	})
+}
+
+func TestWaitlistHandlers(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		seedTestLocations(t, s)
+		_, err := Seed(s, Fixtures{Seats: []SeatFixture{{Number: "A1", Building: "HQ", Floor: "Ground", Zone: "North"}}}, false)
+		require.NoError(t, err)
+		require.NoError(t, s.CreateSeat(&Seat{Number: "B1"}))
+		seat, err := s.GetSeatByNumber("A1")
+		require.NoError(t, err)
+		zone := *seat.ZoneID
+
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 3)
+			from  = time.Now().In(dateutil.LocVN).Add(time.Hour).Truncate(time.Minute)
+			to    = from.Add(time.Hour)
+		)
+		require.Equal(t, http.StatusOK, bookSeat(r, users[0], "A1", from, to))
+		join := func(seatNumber string, zoneID *uint) gin.H {
+			return gin.H{"seat_number": seatNumber, "zone_id": zoneID, "from_time": from.Format(timeFormat), "to_time": to.Format(timeFormat)}
+		}
+
+		tests := []struct {
+			name      string
+			token     string
+			body      gin.H
+			want      int
+			wantError string
+		}{
+			{
+				name:      "seat and zone",
+				token:     users[1],
+				body:      join("A1", &zone),
+				want:      http.StatusBadRequest,
+				wantError: "Either seat_number or zone_id is required",
+			},
+			{
+				name:      "unknown seat",
+				token:     users[1],
+				body:      join("Z9", nil),
+				want:      http.StatusNotFound,
+				wantError: "Seat not found",
+			},
+			{
+				name:      "available seat",
+				token:     users[1],
+				body:      join("B1", nil),
+				want:      http.StatusBadRequest,
+				wantError: "Seat is available",
+			},
+			{
+				name:  "booked seat",
+				token: users[1],
+				body:  join("A1", nil),
+				want:  http.StatusCreated,
+			},
+			{
+				name:      "twice",
+				token:     users[1],
+				body:      join("A1", nil),
+				want:      http.StatusBadRequest,
+				wantError: "Already on the waitlist",
+			},
+			{
+				name:  "fully booked zone",
+				token: users[2],
+				body:  join("", &zone),
+				want:  http.StatusCreated,
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveJSON(r, http.MethodPost, "/waitlist", tt.token, tt.body)
+				require.Equal(t, tt.want, w.Code, w.Body.String())
+				if tt.wantError != "" {
+					assert.Equal(t, tt.wantError, decodeError(t, w))
+				}
+			})
+		}
+
+		myWaitlist := func(token string) []WaitlistEntry {
+			w := serveJSON(r, http.MethodGet, "/me/waitlist", token, nil)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var body struct {
+				Entries []WaitlistEntry `json:"entries"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			return body.Entries
+		}
+		myBookings := func(token string) []BookingView {
+			w := serveJSON(r, http.MethodGet, "/me/bookings", token, nil)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var body struct {
+				Bookings []BookingView `json:"bookings"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			return body.Bookings
+		}
+		entries := myWaitlist(users[1])
+		require.Len(t, entries, 1)
+		entry := entries[0]
+		assert.Equal(t, WaitlistStatusWaiting, entry.Status)
+		claimPath := fmt.Sprintf("/waitlist/%d/claim", entry.ID)
+
+		w := serveJSON(r, http.MethodPost, claimPath, users[1], nil)
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.Equal(t, "No offer to claim", decodeError(t, w))
+
+		// cancelling the booking offers the seat to the first user in line
+		booked := myBookings(users[0])
+		require.Len(t, booked, 1)
+		w = serveJSON(r, http.MethodDelete, fmt.Sprintf("/bookings/%d", booked[0].ID), users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		entries = myWaitlist(users[1])
+		require.Len(t, entries, 1)
+		assert.Equal(t, WaitlistStatusOffered, entries[0].Status)
+		assert.NotNil(t, entries[0].OfferExpiresAt)
+		assert.Equal(t, WaitlistStatusWaiting, myWaitlist(users[2])[0].Status)
+
+		require.Equal(t, http.StatusBadRequest, bookSeat(r, users[2], "A1", from, to), "the seat is held for the offer")
+		w = serveJSON(r, http.MethodPost, claimPath, users[2], nil)
+		require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+		// declining the offer passes the seat on
+		w = serveJSON(r, http.MethodDelete, fmt.Sprintf("/waitlist/%d", entry.ID), users[1], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Empty(t, myWaitlist(users[1]))
+		w = serveJSON(r, http.MethodDelete, fmt.Sprintf("/waitlist/%d", entry.ID), users[1], nil)
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.Equal(t, "Waitlist entry is cancelled", decodeError(t, w))
+
+		entries = myWaitlist(users[2])
+		require.Len(t, entries, 1)
+		require.Equal(t, WaitlistStatusOffered, entries[0].Status)
+		w = serveJSON(r, http.MethodPost, fmt.Sprintf("/waitlist/%d/claim", entries[0].ID), users[2], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Empty(t, myWaitlist(users[2]))
+		assert.Len(t, myBookings(users[2]), 1)
+	})
+}
+
+func TestWaitlist_SeatFreedBySeriesAndMove(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage) {
+		require.NoError(t, s.CreateSeat(&Seat{Number: "A1"}))
+		require.NoError(t, s.CreateSeat(&Seat{Number: "B1"}))
+		var (
+			r     = newTestRouter(s)
+			users = createTestUsers(t, s, 3)
+			from  = time.Now().In(dateutil.LocVN).Add(24 * time.Hour).Truncate(time.Hour)
+			to    = from.Add(time.Hour)
+		)
+		join := func(token string, from time.Time) {
+			body := gin.H{"seat_number": "A1", "from_time": from.Format(timeFormat), "to_time": from.Add(time.Hour).Format(timeFormat)}
+			w := serveJSON(r, http.MethodPost, "/waitlist", token, body)
+			require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		}
+		waitlistStatus := func(token string) string {
+			w := serveJSON(r, http.MethodGet, "/me/waitlist", token, nil)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var body struct {
+				Entries []WaitlistEntry `json:"entries"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			require.Len(t, body.Entries, 1)
+			return body.Entries[0].Status
+		}
+
+		// moving a booking to another seat offers the seat it left
+		require.Equal(t, http.StatusOK, bookSeat(r, users[0], "A1", from, to))
+		join(users[1], from)
+		w := serveJSON(r, http.MethodGet, "/me/bookings", users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var bookings struct {
+			Bookings []BookingView `json:"bookings"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &bookings))
+		require.Len(t, bookings.Bookings, 1)
+		w = serveJSON(r, http.MethodPatch, fmt.Sprintf("/bookings/%d", bookings.Bookings[0].ID), users[0], gin.H{"seat_number": "B1"})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, WaitlistStatusOffered, waitlistStatus(users[1]))
+
+		// cancelling a series offers the seat of every cancelled occurrence
+		request := gin.H{
+			"seat_number": "A1",
+			"from_time":   from.AddDate(0, 0, 1).Format(timeFormat),
+			"to_time":     to.AddDate(0, 0, 1).Format(timeFormat),
+			"rrule":       "FREQ=DAILY;COUNT=2",
+		}
+		w = serveJSON(r, http.MethodPost, "/book-seat/recurring", users[0], request)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var series struct {
+			SeriesID uint `json:"series_id"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+		join(users[2], from.AddDate(0, 0, 2))
+
+		w = serveJSON(r, http.MethodDelete, fmt.Sprintf("/booking-series/%d", series.SeriesID), users[0], nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var cancelled struct {
+			Cancelled int `json:"cancelled"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cancelled))
+		assert.Equal(t, 2, cancelled.Cancelled)
+		assert.Equal(t, WaitlistStatusOffered, waitlistStatus(users[2]))
+	})
+}
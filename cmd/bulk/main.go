@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"code-challenge-backend/app"
+
+	"github.com/spf13/viper"
+)
+
+const usage = `usage: bulk <command> [flags]
+
+commands:
+  import-seats [-dry-run] FILE
+        upsert the seats of a CSV or XLSX file with the columns of fixtures/seats.csv
+  export-bookings [-format csv|xlsx] [-from DATE] [-to DATE] [-user ID] [-seat NUMBER] [-o FILE]
+        write the bookings to FILE or stdout, dates are YYYY-MM-DD and -to is inclusive
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "import-seats":
+		importSeats(os.Args[2:])
+	case "export-bookings":
+		exportBookings(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func importSeats(args []string) {
+	flags := flag.NewFlagSet("import-seats", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "print the changes without writing them")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	path := flags.Arg(0)
+	format, err := app.SpreadsheetFormat(path)
+	if err != nil {
+		log.Fatalf("%s: %v", path, err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("failed to open file: %v", err)
+	}
+	defer f.Close()
+
+	ds := openDataStorage()
+	defer ds.Close()
+
+	result, err := app.ImportSeats(ds, f, format, *dryRun)
+	if err != nil {
+		log.Fatalf("failed to import seats: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		for _, rowErr := range result.Errors {
+			fmt.Fprintf(os.Stderr, "row %d: %s\n", rowErr.Row, rowErr.Error)
+		}
+		log.Fatalf("%d invalid rows, nothing was written", len(result.Errors))
+	}
+
+	for _, change := range result.Changes {
+		fmt.Println(change)
+	}
+	if *dryRun {
+		fmt.Printf("%d created, %d updated, dry run so nothing was written\n", result.Created, result.Updated)
+	} else {
+		fmt.Printf("%d created, %d updated\n", result.Created, result.Updated)
+	}
+}
+
+func exportBookings(args []string) {
+	flags := flag.NewFlagSet("export-bookings", flag.ExitOnError)
+	var (
+		format = flags.String("format", "", "csv or xlsx, by default the extension of -o or csv")
+		from   = flags.String("from", "", "first date of the bookings, YYYY-MM-DD")
+		to     = flags.String("to", "", "last date of the bookings, YYYY-MM-DD")
+		user   = flags.String("user", "", "id of the user")
+		seat   = flags.String("seat", "", "number of the seat")
+		output = flags.String("o", "", "output file, stdout by default")
+	)
+	_ = flags.Parse(args)
+
+	if *format == "" {
+		*format = app.SpreadsheetCSV
+		if *output != "" {
+			detected, err := app.SpreadsheetFormat(*output)
+			if err != nil {
+				log.Fatalf("%s: %v", *output, err)
+			}
+			*format = detected
+		}
+	}
+
+	filter, err := app.ParseBookingExportFilter(*from, *to, *user, *seat)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("failed to create file: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	ds := openDataStorage()
+	defer ds.Close()
+
+	if err := app.ExportBookings(ds, w, *format, filter); err != nil {
+		log.Fatalf("failed to export bookings: %v", err)
+	}
+}
+
+func openDataStorage() *app.DataStorage {
+	viper.SetConfigName("config")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath(".")
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("Error reading config file, %s", err)
+	}
+
+	var database app.DatabaseConfig
+	if err := viper.UnmarshalKey("database", &database); err != nil {
+		log.Fatalf("Error reading database config, %s", err)
+	}
+	return app.NewDataStorage(database)
+}
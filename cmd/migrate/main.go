@@ -1,24 +1,115 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"time"
 
 	"code-challenge-backend/app"
+	"code-challenge-backend/migrations"
+	"code-challenge-backend/pkg/migrate"
 
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
+	"github.com/spf13/viper"
 )
 
+const usage = `usage: migrate <command>
+
+commands:
+  up           apply every pending migration
+  down N       revert the N most recently applied migrations
+  status       list the migrations and when they were applied
+  create NAME  write an empty migration into the migrations directory
+`
+
+// migrationsDir is where `create` writes new migrations, relative to the repository root
+const migrationsDir = "migrations"
+
 func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+	if command == "create" {
+		if len(args) != 1 {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		path, err := migrate.Create(migrationsDir, args[0], time.Now())
+		if err != nil {
+			log.Fatalf("failed to create migration: %v", err)
+		}
+		fmt.Println("created", path)
+		return
+	}
+
+	viper.SetConfigName("config")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath(".")
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("Error reading config file, %s", err)
+	}
+
+	var database app.DatabaseConfig
+	if err := viper.UnmarshalKey("database", &database); err != nil {
+		log.Fatalf("Error reading database config, %s", err)
+	}
+
 	// Initialize database
-	db, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	db, err := app.OpenDatabase(database)
 	if err != nil {
 		log.Fatalf("failed to connect database: %v", err)
 	}
 
-	// Migrate the schema
-	err = db.AutoMigrate(&app.User{}, &app.Seat{}, &app.Booking{})
+	migrator, err := migrate.New(db, migrations.All())
 	if err != nil {
-		log.Fatalf("failed to migrate database: %v", err)
+		log.Fatalf("invalid migrations: %v", err)
+	}
+
+	switch command {
+	case "up":
+		done, err := migrator.Up()
+		for _, m := range done {
+			fmt.Printf("applied %d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("failed to migrate database: %v", err)
+		}
+		if len(done) == 0 {
+			fmt.Println("database is up to date")
+		}
+	case "down":
+		n := 1
+		if len(args) > 0 {
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				log.Fatalf("invalid number of migrations %q", args[0])
+			}
+		}
+		done, err := migrator.Down(n)
+		for _, m := range done {
+			fmt.Printf("reverted %d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("failed to revert database: %v", err)
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("failed to read migrations: %v", err)
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d  %-40s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
 }
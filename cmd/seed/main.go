@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"code-challenge-backend/app"
+
+	"github.com/spf13/viper"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "print the changes without writing them")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: seed [-dry-run] FILE...")
+		fmt.Fprintln(os.Stderr, "\nFILE is a YAML or CSV fixture file, see fixtures/ for examples")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	fixtures, err := app.LoadFixtures(flag.Args()...)
+	if err != nil {
+		log.Fatalf("failed to load fixtures: %v", err)
+	}
+
+	viper.SetConfigName("config")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath(".")
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatalf("Error reading config file, %s", err)
+	}
+
+	var database app.DatabaseConfig
+	if err := viper.UnmarshalKey("database", &database); err != nil {
+		log.Fatalf("Error reading database config, %s", err)
+	}
+
+	ds := app.NewDataStorage(database)
+	defer ds.Close()
+
+	changes, err := app.Seed(ds, fixtures, *dryRun)
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	if err != nil {
+		log.Fatalf("failed to seed database: %v", err)
+	}
+
+	switch {
+	case len(changes) == 0:
+		fmt.Println("database is up to date")
+	case *dryRun:
+		fmt.Printf("%d changes, dry run so nothing was written\n", len(changes))
+	default:
+		fmt.Printf("%d changes written\n", len(changes))
+	}
+}
@@ -1,17 +1,39 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"code-challenge-backend/app"
 
-	"github.cgin-contrib/cors"
+	"github.com/gin-contrib/cors"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 )
 
+// minJWTSecretLength is the shortest jwt_secret accepted, an HS256 key should be at
+// least as long as the hash
+const minJWTSecretLength = 32
+
+type serverConfig struct {
+	Addr            string        `mapstructure:"addr"`
+	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+}
+
 func main() {
+	viper.SetDefault("server.addr", ":8080")
+	viper.SetDefault("server.read_timeout", 15*time.Second)
+	viper.SetDefault("server.write_timeout", 15*time.Second)
+	viper.SetDefault("server.shutdown_timeout", 30*time.Second)
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(".")
@@ -19,21 +41,179 @@ func main() {
 		log.Fatalf("Error reading config file, %s", err)
 	}
 
+	jwtSecret := viper.GetString("jwt_secret")
+	if len(jwtSecret) < minJWTSecretLength {
+		log.Fatalf("jwt_secret must be configured with at least %d bytes", minJWTSecretLength)
+	}
+
+	var database app.DatabaseConfig
+	if err := viper.UnmarshalKey("database", &database); err != nil {
+		log.Fatalf("Error reading database config, %s", err)
+	}
+
+	var server serverConfig
+	if err := viper.UnmarshalKey("server", &server); err != nil {
+		log.Fatalf("Error reading server config, %s", err)
+	}
+
+	release := app.DefaultReleaseConfig()
+	if err := viper.UnmarshalKey("release", &release); err != nil {
+		log.Fatalf("Error reading release config, %s", err)
+	}
+	if err := release.Validate(); err != nil {
+		log.Fatalf("Invalid release config, %s", err)
+	}
+
+	waitlistConfig := app.DefaultWaitlistConfig()
+	if err := viper.UnmarshalKey("waitlist", &waitlistConfig); err != nil {
+		log.Fatalf("Error reading waitlist config, %s", err)
+	}
+	if err := waitlistConfig.Validate(); err != nil {
+		log.Fatalf("Invalid waitlist config, %s", err)
+	}
+
+	notifications := app.DefaultNotificationConfig()
+	if err := viper.UnmarshalKey("notifications", &notifications); err != nil {
+		log.Fatalf("Error reading notifications config, %s", err)
+	}
+	if err := notifications.Validate(); err != nil {
+		log.Fatalf("Invalid notifications config, %s", err)
+	}
+
+	reminders := app.DefaultReminderConfig()
+	if err := viper.UnmarshalKey("reminders", &reminders); err != nil {
+		log.Fatalf("Error reading reminders config, %s", err)
+	}
+	if err := reminders.Validate(); err != nil {
+		log.Fatalf("Invalid reminders config, %s", err)
+	}
+
+	jobs := app.DefaultJobConfig()
+	if err := viper.UnmarshalKey("jobs", &jobs); err != nil {
+		log.Fatalf("Error reading jobs config, %s", err)
+	}
+	if err := jobs.Validate(); err != nil {
+		log.Fatalf("Invalid jobs config, %s", err)
+	}
+	if jobs.LeaseTTL <= release.PollInterval {
+		log.Fatal("jobs.lease_ttl must be longer than release.poll_interval")
+	}
+
 	var (
-		r       = gin.Default()
-		ds      = app.NewDataStorage(viper.GetString("db"))
-		h       = app.NewHandler(ds)
-		checkin = app.NewCheckInService(ds, viper.GetString("jwt_secret"))
+		r          = gin.Default()
+		ds         = app.NewDataStorage(database)
+		events     = app.NewSeatEvents(ds, ds)
+		notifier   = app.NewNotifier(ds, ds, notifications)
+		dispatcher = app.NewNotificationDispatcher(ds, ds, notifications)
+		waitlist   = app.NewWaitlist(ds, ds, waitlistConfig, notifier)
+		runner     = app.NewJobRunner(ds, jobs)
+		h          = app.NewHandler(ds, jwtSecret, events, waitlist, notifier)
+		m          = app.NewMiddleware(jwtSecret, ds)
+		checkin    = app.NewCheckInService(ds, jwtSecret, release, events, waitlist, notifier, runner)
+		scheduler  = app.NewReminderScheduler(ds, ds, notifier, release, reminders)
 	)
-	go checkin.ReleaseBooking()
 	r.Use(cors.Default())
 	r.Use(gin.Recovery())
 	r.Use(gin.Logger())
-	r.POST("/checkin", checkin.CheckIn)
 
 	r.GET("/seats", h.ListAvailableSeats)
-	r.POST("/book-seat", h.BookSeat)
+	r.GET("/seats/availability", h.SeatAvailability)
+	r.GET("/seats/events", h.SeatEvents)
+	r.GET("/locations", h.ListLocations)
+	r.POST("/register", h.Register)
 	r.POST("/login", h.Login)
+	r.POST("/refresh", h.RefreshToken)
+
+	auth := r.Group("/", m.Authenticate())
+	auth.POST("/checkin", checkin.CheckIn)
+	auth.POST("/checkin/qr", checkin.CheckInQR)
+	auth.POST("/book-seat", h.BookSeat)
+	auth.POST("/book-seat/recurring", h.BookRecurringSeat)
+	auth.GET("/me/bookings", h.MyBookings)
+	auth.PATCH("/bookings/:id", h.UpdateBooking)
+	auth.DELETE("/bookings/:id", h.CancelBooking)
+	auth.GET("/bookings/:id/history", h.BookingHistory)
+	auth.POST("/bookings/:id/checkout", h.CheckOutBooking)
+	auth.DELETE("/booking-series/:id", h.CancelBookingSeries)
+	auth.DELETE("/booking-series/:id/bookings/:booking_id", h.CancelBookingOccurrence)
+	auth.POST("/waitlist", h.JoinWaitlist)
+	auth.GET("/me/waitlist", h.MyWaitlist)
+	auth.DELETE("/waitlist/:id", h.LeaveWaitlist)
+	auth.POST("/waitlist/:id/claim", h.ClaimWaitlistOffer)
+	auth.GET("/me/notifications", h.MyNotifications)
+	auth.POST("/me/notifications/:id/read", h.ReadNotification)
+
+	admin := auth.Group("/admin", m.RequireRole(app.RoleFacilityAdmin, app.RoleSuperAdmin))
+	admin.GET("/seats", h.ListSeats)
+	admin.POST("/seats", h.CreateSeat)
+	admin.PUT("/seats/:id", h.UpdateSeat)
+	admin.DELETE("/seats/:id", h.DeleteSeat)
+	admin.POST("/seats/:id/restore", h.RestoreSeat)
+	admin.POST("/seats/import", h.ImportSeats)
+	admin.GET("/seats/:id/qr", h.SeatQRCode)
+	admin.GET("/bookings/export", h.ExportBookings)
+	admin.GET("/reports/utilization", h.UtilizationReport)
+	admin.GET("/reports/seats", h.SeatUtilizationReport)
+	admin.GET("/reports/zones", h.ZoneUtilizationReport)
+	admin.GET("/reports/peak-hours", h.PeakHoursReport)
+	admin.POST("/buildings", h.CreateBuilding)
+	admin.POST("/floors", h.CreateFloor)
+	admin.POST("/zones", h.CreateZone)
+	admin.GET("/jobs", h.ListJobs)
+
+	superAdmin := auth.Group("/admin", m.RequireRole(app.RoleSuperAdmin))
+	superAdmin.PUT("/users/:id/role", h.UpdateUserRole)
 
-	log.Fatal(r.Run(":8080"))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var workers sync.WaitGroup
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		checkin.ReleaseBooking(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		dispatcher.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		scheduler.Run(ctx)
+	}()
+
+	srv := &http.Server{
+		Addr:         server.Addr,
+		Handler:      r,
+		ReadTimeout:  server.ReadTimeout,
+		WriteTimeout: server.WriteTimeout,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Listening on %s", server.Addr)
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		log.Printf("Server stopped, %s", err)
+	case <-ctx.Done():
+		log.Printf("Shutting down")
+	}
+	// stop the workers too when the server failed on its own
+	stop()
+	// end the event streams, Shutdown would wait for them until it times out
+	events.Close()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Error shutting down server, %s", err)
+	}
+	workers.Wait()
+
+	if err := ds.Close(); err != nil {
+		log.Printf("Error closing database, %s", err)
+	}
+	log.Printf("Shutdown complete")
 }
@@ -0,0 +1,96 @@
+package migrations
+
+import (
+	"time"
+
+	"code-challenge-backend/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// the structs are a snapshot of the tables when migrations were introduced so later
+// changes of the app models do not change this migration, AutoMigrate adopts the
+// databases created before migrations as they are
+func init() {
+	type (
+		User struct {
+			gorm.Model
+			Name                string
+			Email               string `gorm:"size:255;unique"`
+			Role                string `gorm:"size:32;default:employee"`
+			PasswordHash        string
+			FailedLoginAttempts int
+			LockedUntil         *time.Time
+		}
+
+		Building struct {
+			gorm.Model
+			Name string
+		}
+
+		Floor struct {
+			gorm.Model
+			BuildingID uint
+			Name       string
+			Level      int
+		}
+
+		Zone struct {
+			gorm.Model
+			FloorID uint
+			Name    string
+		}
+
+		Seat struct {
+			gorm.Model
+			Number      string
+			ZoneID      *uint
+			Type        string `gorm:"size:32;default:desk"`
+			DualMonitor bool
+			NearWindow  bool
+			Accessible  bool
+		}
+
+		BookingSeries struct {
+			ID        uint
+			UserID    uint
+			SeatID    uint
+			Rule      string
+			CreatedAt time.Time
+		}
+
+		Booking struct {
+			ID        int
+			UserID    uint
+			SeatID    uint
+			SeriesID  *uint
+			StartTime time.Time
+			EndTime   time.Time
+			CheckedIn bool
+			Status    string `gorm:"size:32;default:booked;index"`
+			CreatedAt time.Time
+		}
+
+		BookingStatusHistory struct {
+			ID        uint
+			BookingID int    `gorm:"index"`
+			Status    string `gorm:"size:32"`
+			SeatID    uint
+			StartTime time.Time
+			EndTime   time.Time
+			CreatedAt time.Time
+		}
+	)
+
+	tables := []interface{}{&User{}, &Building{}, &Floor{}, &Zone{}, &Seat{}, &BookingSeries{}, &Booking{}, &BookingStatusHistory{}}
+
+	register(migrate.Migration{
+		Version: 20261018090000,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(tables...)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(tables...)
+		},
+	})
+}
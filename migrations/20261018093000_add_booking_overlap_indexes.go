@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"code-challenge-backend/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// the overlap checks look up the bookings of a seat or a user around a time range
+func init() {
+	register(migrate.Migration{
+		Version: 20261018093000,
+		Name:    "add_booking_overlap_indexes",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("CREATE INDEX idx_bookings_seat_start ON bookings (seat_id, start_time)").Error; err != nil {
+				return err
+			}
+			return tx.Exec("CREATE INDEX idx_bookings_user_start ON bookings (user_id, start_time)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex("bookings", "idx_bookings_user_start"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropIndex("bookings", "idx_bookings_seat_start")
+		},
+	})
+}
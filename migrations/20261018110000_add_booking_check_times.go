@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"code-challenge-backend/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// check-in and check-out times, the check-out shortens the booking to the time it happens
+func init() {
+	type Booking struct {
+		CheckedInAt  *time.Time
+		CheckedOutAt *time.Time
+	}
+
+	register(migrate.Migration{
+		Version: 20261018110000,
+		Name:    "add_booking_check_times",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&Booking{}, "CheckedInAt"); err != nil {
+				return err
+			}
+			return tx.Migrator().AddColumn(&Booking{}, "CheckedOutAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&Booking{}, "CheckedOutAt"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&Booking{}, "CheckedInAt")
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"time"
+
+	"code-challenge-backend/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// users waiting for a seat, or any seat of a zone, to free up over a time range
+func init() {
+	type WaitlistEntry struct {
+		ID             uint
+		UserID         uint `gorm:"index"`
+		SeatID         *uint
+		ZoneID         *uint
+		StartTime      time.Time
+		EndTime        time.Time
+		Status         string `gorm:"size:32;default:waiting;index"`
+		OfferedSeatID  *uint
+		OfferExpiresAt *time.Time
+		BookingID      *int
+		CreatedAt      time.Time
+	}
+
+	register(migrate.Migration{
+		Version: 20261018120000,
+		Name:    "create_waitlist_entries",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&WaitlistEntry{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&WaitlistEntry{})
+		},
+	})
+}
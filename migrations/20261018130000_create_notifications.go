@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"time"
+
+	"code-challenge-backend/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// the notification outbox, every row is delivered by one channel and also serves as the
+// in-app inbox
+func init() {
+	type Notification struct {
+		ID            uint
+		UserID        uint   `gorm:"index"`
+		Channel       string `gorm:"size:32"`
+		Kind          string `gorm:"size:64"`
+		Subject       string
+		Body          string
+		BookingID     *int
+		Status        string `gorm:"size:32;default:pending;index"`
+		Attempts      int
+		NextAttemptAt time.Time `gorm:"index"`
+		LastError     string
+		SentAt        *time.Time
+		ReadAt        *time.Time
+		CreatedAt     time.Time
+	}
+
+	register(migrate.Migration{
+		Version: 20261018130000,
+		Name:    "create_notifications",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&Notification{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&Notification{})
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"code-challenge-backend/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// the reminder jobs of the bookings, unique per booking, kind and start time so that
+// concurrent schedulers plan them once
+func init() {
+	type ReminderJob struct {
+		ID        uint
+		BookingID int       `gorm:"uniqueIndex:idx_reminder_jobs_booking"`
+		Kind      string    `gorm:"size:32;uniqueIndex:idx_reminder_jobs_booking"`
+		StartTime time.Time `gorm:"uniqueIndex:idx_reminder_jobs_booking"`
+		ReleaseAt time.Time
+		RunAt     time.Time `gorm:"index:idx_reminder_jobs_due,priority:2"`
+		Status    string    `gorm:"size:32;default:pending;index:idx_reminder_jobs_due,priority:1"`
+		CreatedAt time.Time
+	}
+
+	register(migrate.Migration{
+		Version: 20261018140000,
+		Name:    "create_reminder_jobs",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&ReminderJob{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&ReminderJob{})
+		},
+	})
+}
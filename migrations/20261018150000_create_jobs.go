@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"code-challenge-backend/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// the leases of the background jobs, run by one instance at a time, and their metrics
+func init() {
+	type Job struct {
+		Name           string `gorm:"primaryKey;size:64"`
+		Holder         string `gorm:"size:128"`
+		LeaseExpiresAt time.Time
+		LastRunAt      *time.Time
+		LastDurationMs int64
+		LastProcessed  int
+		LastError      string
+		Runs           int64
+		Failures       int64
+		TotalProcessed int64
+	}
+
+	register(migrate.Migration{
+		Version: 20261018150000,
+		Name:    "create_jobs",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&Job{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&Job{})
+		},
+	})
+}
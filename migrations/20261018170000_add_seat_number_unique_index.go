@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"code-challenge-backend/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// seat numbers are unique among the seats not deleted, a deleted seat keeps its number
+// and is only restored while no other seat took it. MySQL has no partial index, the
+// index is on a generated column which is NULL for the deleted seats. Seats created before
+// may share a number, the migration fails with those numbers so that an admin renames or
+// deletes the duplicates rather than the migration picking which seat keeps its number
+func init() {
+	register(migrate.Migration{
+		Version: 20261018170000,
+		Name:    "add_seat_number_unique_index",
+		Up: func(tx *gorm.DB) error {
+			var duplicates []string
+			err := tx.Raw("SELECT number FROM seats WHERE deleted_at IS NULL " +
+				"GROUP BY number HAVING COUNT(*) > 1 ORDER BY number").Scan(&duplicates).Error
+			if err != nil {
+				return err
+			}
+			if len(duplicates) > 0 {
+				return fmt.Errorf("seat numbers used by more than one seat: %s, rename or delete the duplicates then migrate again",
+					strings.Join(duplicates, ", "))
+			}
+
+			if tx.Dialector.Name() == "mysql" {
+				err := tx.Exec("ALTER TABLE seats ADD COLUMN active_number VARCHAR(255) " +
+					"GENERATED ALWAYS AS (IF(deleted_at IS NULL, number, NULL)) VIRTUAL").Error
+				if err != nil {
+					return err
+				}
+				return tx.Exec("CREATE UNIQUE INDEX idx_seats_number ON seats (active_number)").Error
+			}
+			return tx.Exec("CREATE UNIQUE INDEX idx_seats_number ON seats (number) WHERE deleted_at IS NULL").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex("seats", "idx_seats_number"); err != nil {
+				return err
+			}
+			if tx.Dialector.Name() == "mysql" {
+				return tx.Exec("ALTER TABLE seats DROP COLUMN active_number").Error
+			}
+			return nil
+		},
+	})
+}
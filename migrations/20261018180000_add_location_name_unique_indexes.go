@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"code-challenge-backend/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// locationNameIndexes make the name of a building unique, and the name of a floor or a
+// zone unique in its parent, the seeder and the imports find locations by their names
+var locationNameIndexes = []struct {
+	table, index, parent string
+}{
+	{table: "buildings", index: "idx_buildings_name"},
+	{table: "floors", index: "idx_floors_building_name", parent: "building_id"},
+	{table: "zones", index: "idx_zones_floor_name", parent: "floor_id"},
+}
+
+// like the seat numbers, locations created before may share a name and the migration fails
+// with those names for an admin to rename them. MySQL only indexes a prefix of a text column
+func init() {
+	register(migrate.Migration{
+		Version: 20261018180000,
+		Name:    "add_location_name_unique_indexes",
+		Up: func(tx *gorm.DB) error {
+			for _, idx := range locationNameIndexes {
+				columns, indexed := "name", "name"
+				if tx.Dialector.Name() == "mysql" {
+					indexed = "name(191)"
+				}
+				if idx.parent != "" {
+					columns, indexed = idx.parent+", "+columns, idx.parent+", "+indexed
+				}
+
+				var duplicates []string
+				err := tx.Raw("SELECT name FROM " + idx.table + " GROUP BY " + columns +
+					" HAVING COUNT(*) > 1 ORDER BY name").Scan(&duplicates).Error
+				if err != nil {
+					return err
+				}
+				if len(duplicates) > 0 {
+					return fmt.Errorf("%s names used more than once in the same parent: %s, rename the duplicates then migrate again",
+						idx.table, strings.Join(duplicates, ", "))
+				}
+
+				if err := tx.Exec("CREATE UNIQUE INDEX " + idx.index + " ON " + idx.table + " (" + indexed + ")").Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for i := len(locationNameIndexes) - 1; i >= 0; i-- {
+				idx := locationNameIndexes[i]
+				if err := tx.Migrator().DropIndex(idx.table, idx.index); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,18 @@
+// Package migrations hold the versioned schema of the booking database, every
+// file registers one migration, new ones are created with `migrate create NAME`
+package migrations
+
+import "code-challenge-backend/pkg/migrate"
+
+var all []migrate.Migration
+
+func register(m migrate.Migration) {
+	all = append(all, m)
+}
+
+// All return every registered migration
+func All() []migrate.Migration {
+	migrations := make([]migrate.Migration, len(all))
+	copy(migrations, all)
+	return migrations
+}
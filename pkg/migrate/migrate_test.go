@@ -0,0 +1,210 @@
+package migrate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDatabase(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	return db
+}
+
+// createTable return a migration creating an empty table
+func createTable(version int64, table string) Migration {
+	return Migration{
+		Version: version,
+		Name:    "create_" + table,
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE " + table + " (id INTEGER PRIMARY KEY)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE " + table).Error
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	type args struct {
+		migrations []Migration
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []int64
+		wantErr error
+	}{
+		{
+			name: "sorted by version",
+			args: args{
+				migrations: []Migration{createTable(3, "c"), createTable(1, "a"), createTable(2, "b")},
+			},
+			want: []int64{1, 2, 3},
+		},
+		{
+			name: "duplicate version",
+			args: args{
+				migrations: []Migration{createTable(1, "a"), createTable(1, "b")},
+			},
+			wantErr: ErrDuplicateVersion,
+		},
+		{
+			name: "missing version",
+			args: args{
+				migrations: []Migration{createTable(0, "a")},
+			},
+			wantErr: ErrNoVersion,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(nil, tt.args.migrations)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			var got []int64
+			for _, migration := range m.migrations {
+				got = append(got, migration.Version)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	db := openTestDatabase(t)
+	migrations := []Migration{createTable(1, "a"), createTable(2, "b"), createTable(3, "c")}
+
+	m, err := New(db, migrations[:2])
+	require.NoError(t, err)
+	done, err := m.Up()
+	require.NoError(t, err)
+	assert.Len(t, done, 2)
+	assert.True(t, db.Migrator().HasTable("a"))
+	assert.True(t, db.Migrator().HasTable("b"))
+
+	// a newer binary only applies the new migration
+	m, err = New(db, migrations)
+	require.NoError(t, err)
+	done, err = m.Up()
+	require.NoError(t, err)
+	require.Len(t, done, 1)
+	assert.Equal(t, int64(3), done[0].Version)
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	for _, s := range statuses {
+		assert.NotNil(t, s.AppliedAt, "migration %d", s.Version)
+	}
+
+	done, err = m.Down(2)
+	require.NoError(t, err)
+	require.Len(t, done, 2)
+	assert.Equal(t, int64(3), done[0].Version)
+	assert.Equal(t, int64(2), done[1].Version)
+	assert.True(t, db.Migrator().HasTable("a"))
+	assert.False(t, db.Migrator().HasTable("b"))
+	assert.False(t, db.Migrator().HasTable("c"))
+
+	statuses, err = m.Status()
+	require.NoError(t, err)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+	assert.Nil(t, statuses[2].AppliedAt)
+
+	// an older binary must not run against a database it does not know
+	done, err = m.Up()
+	require.NoError(t, err)
+	assert.Len(t, done, 2)
+	m, err = New(db, migrations[:1])
+	require.NoError(t, err)
+	_, err = m.Status()
+	assert.True(t, errors.Is(err, ErrUnknownVersion), "Status() error = %v", err)
+}
+
+func TestMigrator_UpRollbackOnError(t *testing.T) {
+	db := openTestDatabase(t)
+	failing := Migration{
+		Version: 2,
+		Name:    "failing",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("CREATE TABLE b (id INTEGER PRIMARY KEY)").Error; err != nil {
+				return err
+			}
+			return errors.New("boom")
+		},
+		Down: func(tx *gorm.DB) error { return nil },
+	}
+
+	m, err := New(db, []Migration{createTable(1, "a"), failing})
+	require.NoError(t, err)
+	done, err := m.Up()
+	require.Error(t, err)
+	assert.Len(t, done, 1)
+	assert.False(t, db.Migrator().HasTable("b"))
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+}
+
+func TestCreate(t *testing.T) {
+	now := time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)
+	type args struct {
+		name string
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantFile string
+		wantErr  bool
+	}{
+		{
+			name:     "snake case name",
+			args:     args{name: "add_seat_notes"},
+			wantFile: "20240305093000_add_seat_notes.go",
+		},
+		{
+			name:    "invalid name",
+			args:    args{name: "add seat notes"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "migrations")
+			require.NoError(t, os.Mkdir(dir, 0o755))
+
+			path, err := Create(dir, tt.args.name, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(dir, tt.wantFile), path)
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Contains(t, string(content), "package migrations")
+			assert.Contains(t, string(content), "Version: 20240305093000")
+
+			_, err = Create(dir, tt.args.name, now)
+			assert.Error(t, err, "Create() must not overwrite a migration")
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+)
+
+// Frequency of a rule
+const (
+	FreqDaily  = "DAILY"
+	FreqWeekly = "WEEKLY"
+)
+
+// MaxOccurrences is the upper bound of occurrences a rule can expand to
+const MaxOccurrences = 366
+
+// MaxDailyInterval and MaxWeeklyInterval bound INTERVAL to a year
+const (
+	MaxDailyInterval  = 366
+	MaxWeeklyInterval = 52
+)
+
+var (
+	ErrInvalidRule = errors.New("invalid recurrence rule")
+
+	weekdayCodes = map[string]time.Weekday{
+		"MO": time.Monday,
+		"TU": time.Tuesday,
+		"WE": time.Wednesday,
+		"TH": time.Thursday,
+		"FR": time.Friday,
+		"SA": time.Saturday,
+		"SU": time.Sunday,
+	}
+	weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+)
+
+// Rule is a subset of the iCalendar RRULE: FREQ, INTERVAL, BYDAY, UNTIL and COUNT
+type Rule struct {
+	Freq     string
+	Interval int
+	// ByDay is only used by weekly rules, default to the weekday of the first occurrence
+	ByDay []time.Weekday
+	// Until is inclusive, occurrences starting after it are not generated
+	Until *time.Time
+	Count int
+}
+
+// Parse parse a rule like "FREQ=WEEKLY;BYDAY=TU,TH;UNTIL=20240331", UNTIL is
+// read as the end of that day in loc
+func Parse(s string, loc *time.Location) (Rule, error) {
+	rule := Rule{Interval: 1}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "RRULE:"), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("%w: %q", ErrInvalidRule, part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil {
+				return Rule{}, fmt.Errorf("%w: interval %q", ErrInvalidRule, value)
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				weekday, ok := weekdayCodes[strings.ToUpper(code)]
+				if !ok {
+					return Rule{}, fmt.Errorf("%w: weekday %q", ErrInvalidRule, code)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "UNTIL":
+			until, err := time.ParseInLocation(dateutil.FormatYYYYMMDDNoSlash, value, loc)
+			if err != nil {
+				return Rule{}, fmt.Errorf("%w: until %q", ErrInvalidRule, value)
+			}
+			until = until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			rule.Until = &until
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				return Rule{}, fmt.Errorf("%w: count %q", ErrInvalidRule, value)
+			}
+			// a zero Count means no COUNT was given, an explicit one must be positive
+			if count < 1 {
+				return Rule{}, fmt.Errorf("%w: count must be between 1 and %d", ErrInvalidRule, MaxOccurrences)
+			}
+			rule.Count = count
+		default:
+			return Rule{}, fmt.Errorf("%w: unsupported part %q", ErrInvalidRule, key)
+		}
+	}
+	return rule, rule.Validate()
+}
+
+// Validate check the rule is complete and bounded
+func (r Rule) Validate() error {
+	if r.Freq != FreqDaily && r.Freq != FreqWeekly {
+		return fmt.Errorf("%w: frequency must be DAILY or WEEKLY", ErrInvalidRule)
+	}
+	if r.Interval < 1 {
+		return fmt.Errorf("%w: interval must be positive", ErrInvalidRule)
+	}
+	if r.Freq == FreqDaily && r.Interval > MaxDailyInterval {
+		return fmt.Errorf("%w: a daily interval must be at most %d", ErrInvalidRule, MaxDailyInterval)
+	}
+	if r.Freq == FreqWeekly && r.Interval > MaxWeeklyInterval {
+		return fmt.Errorf("%w: a weekly interval must be at most %d", ErrInvalidRule, MaxWeeklyInterval)
+	}
+	if r.Freq == FreqDaily && len(r.ByDay) > 0 {
+		return fmt.Errorf("%w: BYDAY is only supported by WEEKLY", ErrInvalidRule)
+	}
+	if r.Count < 0 || r.Count > MaxOccurrences {
+		return fmt.Errorf("%w: count must be between 1 and %d", ErrInvalidRule, MaxOccurrences)
+	}
+	if r.Until == nil && r.Count == 0 {
+		return fmt.Errorf("%w: either UNTIL or COUNT is required", ErrInvalidRule)
+	}
+	return nil
+}
+
+// String format the rule back to RRULE syntax
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, weekday := range r.ByDay {
+			codes = append(codes, weekdayNames[weekday])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format(dateutil.FormatYYYYMMDDNoSlash))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences return the start time of every occurrence beginning with start,
+// the wall clock time of start is kept across daylight saving changes
+func (r Rule) Occurrences(start time.Time) ([]time.Time, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	var (
+		occurrences []time.Time
+		limit       = MaxOccurrences
+	)
+	if r.Count > 0 {
+		limit = r.Count
+	}
+	done := func(t time.Time) bool {
+		return len(occurrences) >= limit || (r.Until != nil && t.After(*r.Until))
+	}
+
+	if r.Freq == FreqDaily {
+		for t := start; !done(t); t = t.AddDate(0, 0, r.Interval) {
+			occurrences = append(occurrences, t)
+		}
+		return occurrences, nil
+	}
+
+	byDay := make(map[time.Weekday]bool, len(r.ByDay))
+	for _, weekday := range r.ByDay {
+		byDay[weekday] = true
+	}
+	if len(byDay) == 0 {
+		byDay[start.Weekday()] = true
+	}
+
+	// weeks start on Monday. Every week but the first has an occurrence, the weeks past
+	// horizon can only be reached by a rule Validate should have refused
+	weekStart := start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	horizon := start.AddDate(0, 0, (MaxOccurrences+1)*r.Interval*7)
+	for week := 0; !weekStart.AddDate(0, 0, week*7).After(horizon); week += r.Interval {
+		for day := 0; day < 7; day++ {
+			t := weekStart.AddDate(0, 0, week*7+day)
+			if t.Before(start) || !byDay[t.Weekday()] {
+				continue
+			}
+			if done(t) {
+				return occurrences, nil
+			}
+			occurrences = append(occurrences, t)
+		}
+	}
+	return occurrences, nil
+}
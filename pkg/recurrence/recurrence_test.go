@@ -0,0 +1,219 @@
+package recurrence
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"code-challenge-backend/pkg/dateutil"
+)
+
+func TestParse(t *testing.T) {
+	until := time.Date(2024, 3, 31, 23, 59, 59, 999999999, dateutil.LocVN)
+	type args struct {
+		s string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    Rule
+		wantErr error
+	}{
+		{
+			name: "weekly by day until date",
+			args: args{
+				s: "FREQ=WEEKLY;BYDAY=TU,TH;UNTIL=20240331",
+			},
+			want: Rule{
+				Freq:     FreqWeekly,
+				Interval: 1,
+				ByDay:    []time.Weekday{time.Tuesday, time.Thursday},
+				Until:    &until,
+			},
+		},
+		{
+			name: "daily with interval and count and RRULE prefix",
+			args: args{
+				s: "RRULE:freq=daily;interval=2;count=5",
+			},
+			want: Rule{
+				Freq:     FreqDaily,
+				Interval: 2,
+				Count:    5,
+			},
+		},
+		{
+			name: "unbounded rule",
+			args: args{
+				s: "FREQ=DAILY",
+			},
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name: "unsupported frequency",
+			args: args{
+				s: "FREQ=MONTHLY;COUNT=3",
+			},
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name: "unknown weekday",
+			args: args{
+				s: "FREQ=WEEKLY;BYDAY=XX;COUNT=3",
+			},
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name: "count over limit",
+			args: args{
+				s: "FREQ=DAILY;COUNT=1000",
+			},
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name: "zero count with until",
+			args: args{
+				s: "FREQ=DAILY;UNTIL=20261031;COUNT=0",
+			},
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name: "weekly interval over a year",
+			args: args{
+				s: "FREQ=WEEKLY;INTERVAL=4611686018427387904;BYDAY=MO;COUNT=2",
+			},
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name: "daily interval over a year",
+			args: args{
+				s: "FREQ=DAILY;INTERVAL=367;COUNT=2",
+			},
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name: "malformed part",
+			args: args{
+				s: "FREQ=DAILY;COUNT",
+			},
+			wantErr: ErrInvalidRule,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.args.s, dateutil.LocVN)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRule_String(t *testing.T) {
+	until := time.Date(2024, 3, 31, 23, 59, 59, 0, dateutil.LocVN)
+	tests := []struct {
+		name string
+		rule Rule
+		want string
+	}{
+		{
+			name: "weekly",
+			rule: Rule{Freq: FreqWeekly, Interval: 2, ByDay: []time.Weekday{time.Tuesday, time.Thursday}, Until: &until},
+			want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;UNTIL=20240331",
+		},
+		{
+			name: "daily",
+			rule: Rule{Freq: FreqDaily, Interval: 1, Count: 3},
+			want: "FREQ=DAILY;COUNT=3",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.String(); got != tt.want {
+				t.Errorf("Rule.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRule_Occurrences(t *testing.T) {
+	// 2024-03-05 is a Tuesday
+	start := time.Date(2024, 3, 5, 9, 0, 0, 0, dateutil.LocVN)
+	day := func(d int) time.Time {
+		return time.Date(2024, 3, d, 9, 0, 0, 0, dateutil.LocVN)
+	}
+	until := time.Date(2024, 3, 14, 23, 59, 59, 0, dateutil.LocVN)
+	tests := []struct {
+		name    string
+		rule    Rule
+		want    []time.Time
+		wantErr bool
+	}{
+		{
+			name: "daily count",
+			rule: Rule{Freq: FreqDaily, Interval: 1, Count: 3},
+			want: []time.Time{day(5), day(6), day(7)},
+		},
+		{
+			name: "daily every other day until",
+			rule: Rule{Freq: FreqDaily, Interval: 2, Until: &until},
+			want: []time.Time{day(5), day(7), day(9), day(11), day(13)},
+		},
+		{
+			name: "weekly on tuesday and thursday until",
+			rule: Rule{Freq: FreqWeekly, Interval: 1, ByDay: []time.Weekday{time.Tuesday, time.Thursday}, Until: &until},
+			want: []time.Time{day(5), day(7), day(12), day(14)},
+		},
+		{
+			name: "weekly skips days before start",
+			rule: Rule{Freq: FreqWeekly, Interval: 1, ByDay: []time.Weekday{time.Monday, time.Wednesday}, Count: 3},
+			want: []time.Time{day(6), day(11), day(13)},
+		},
+		{
+			name: "weekly default to start weekday",
+			rule: Rule{Freq: FreqWeekly, Interval: 2, Count: 2},
+			want: []time.Time{day(5), day(19)},
+		},
+		{
+			name: "weekly longest interval skips the week of start",
+			rule: Rule{Freq: FreqWeekly, Interval: MaxWeeklyInterval, ByDay: []time.Weekday{time.Monday}, Count: 2},
+			want: []time.Time{
+				time.Date(2025, 3, 3, 9, 0, 0, 0, dateutil.LocVN),
+				time.Date(2026, 3, 2, 9, 0, 0, 0, dateutil.LocVN),
+			},
+		},
+		{
+			name:    "weekly interval overflowing the dates",
+			rule:    Rule{Freq: FreqWeekly, Interval: 1 << 62, ByDay: []time.Weekday{time.Monday}, Count: 2},
+			wantErr: true,
+		},
+		{
+			name:    "invalid rule",
+			rule:    Rule{Freq: FreqWeekly, Interval: 1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rule.Occurrences(start)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Rule.Occurrences() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rule.Occurrences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}